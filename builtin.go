@@ -0,0 +1,197 @@
+package stele
+
+import (
+	"go/constant"
+	"slices"
+
+	"deedles.dev/stele/scanner"
+)
+
+// self is the self parameter of the predeclared types. It allows their
+// methods to refer to whatever type is being used as them, so that,
+// for example, adding two values of a type that embeds int results in
+// that type rather than in an int.
+var self = TypeParam{Name: "T"}.Ref()
+
+var (
+	// String is the type of strings.
+	String = builtinType("string", nil,
+		operator("add", self, self),
+		operator("eq", Bool, self),
+		operator("lt", Bool, self),
+		operator("le", Bool, self),
+		operator("len", Int),
+		operator("get", Byte, Int),
+	)
+
+	// Array is the generic type of arrays. It must be instantiated
+	// with the type of the array's elements.
+	Array = builtinType("array", []TypeParam{arrayElem},
+		operator("len", Int),
+		operator("get", arrayElem.Ref(), Int),
+		Feature{Type: FuncFeature, Name: "set", Args: []Type{Int, arrayElem.Ref()}, Return: Unit, MutRecv: true},
+		Feature{Type: FuncFeature, Name: "append", Args: []Type{arrayElem.Ref()}, Return: Unit, MutRecv: true},
+	)
+
+	// Error is the type of run-time errors.
+	Error = Type{Name: "error", Features: []Feature{operator("error", String)}}
+
+	// Result is the generic type of the result of something that may
+	// fail. It is either a value of its type argument or an error.
+	Result = Type{
+		Name:       "result",
+		TypeParams: []TypeParam{{Name: "T"}, resultVal},
+		Oneof:      []Type{resultVal.Ref(), Error},
+	}
+
+	// Opt is the generic type of an optional value. It is either a
+	// value of its type argument or unit.
+	Opt = Type{
+		Name:       "opt",
+		TypeParams: []TypeParam{{Name: "T"}, optVal},
+		Oneof:      []Type{optVal.Ref(), Unit},
+	}
+)
+
+var (
+	arrayElem = TypeParam{Name: "E", Constraint: Any}
+	resultVal = TypeParam{Name: "R", Constraint: Any}
+	optVal    = TypeParam{Name: "V", Constraint: Any}
+)
+
+// Fallible returns true if t is a oneof type that errors may be values
+// of, such as an instance of result.
+func (t Type) Fallible() bool {
+	return slices.ContainsFunc(t.Members(), Error.Satisfies)
+}
+
+// ArrayOf returns the type of arrays with elements of type elem.
+func ArrayOf(elem Type) Type {
+	t, err := Array.Instantiate(elem)
+	if err != nil {
+		// Array's element parameter is unconstrained.
+		panic(err)
+	}
+	return t
+}
+
+// Elem returns the type of the elements of t if t is an array type or
+// embeds one.
+func (t Type) Elem() (Type, bool) {
+	f, ok := t.Feature(MemLayoutFeature, "array")
+	if !ok || (len(f.Args) != 1) {
+		return Type{}, false
+	}
+	return f.Args[0], true
+}
+
+// predeclared is the contents of the root scope.
+var predeclared = map[string]Declaration{
+	"int":      TypeDecl{Name: "int", T: Int},
+	"uint":     TypeDecl{Name: "uint", T: Uint},
+	"byte":     TypeDecl{Name: "byte", T: Byte},
+	"float":    TypeDecl{Name: "float", T: Float},
+	"bigint":   TypeDecl{Name: "bigint", T: BigInt},
+	"bigfloat": TypeDecl{Name: "bigfloat", T: BigFloat},
+	"anyint":   TypeDecl{Name: "anyint", T: AnyInt},
+	"anyfloat": TypeDecl{Name: "anyfloat", T: AnyFloat},
+	"numeric":  TypeDecl{Name: "numeric", T: Numeric},
+	"string":   TypeDecl{Name: "string", T: String},
+	"array":    TypeDecl{Name: "array", T: Array},
+	"bool":     TypeDecl{Name: "bool", T: Bool},
+	"true":     boolConst("true", true),
+	"false":    boolConst("false", false),
+	"any":      TypeDecl{Name: "any", T: Any},
+	"unit":     TypeDecl{Name: "unit", T: Unit},
+	"result":   TypeDecl{Name: "result", T: Result},
+	"error":    TypeDecl{Name: "error", T: Error},
+	"opt":      TypeDecl{Name: "opt", T: Opt},
+}
+
+func boolConst(name string, v bool) Let {
+	return Let{
+		Name:   name + "!",
+		T:      Bool,
+		Assign: &Assign{ID: name, Val: Const{Val: constant.MakeBool(v), T: Bool}},
+	}
+}
+
+// layoutType returns a named type with a single memory layout of the
+// same name.
+func layoutType(name string) Type {
+	return Type{Name: name, Features: []Feature{{Type: MemLayoutFeature, Name: name}}}
+}
+
+// builtinType returns a predeclared type with a memory layout of the
+// same name and the given methods. The layout's arguments are
+// references to params, which follow the type's self parameter.
+func builtinType(name string, params []TypeParam, methods ...Feature) Type {
+	layout := Feature{Type: MemLayoutFeature, Name: name}
+	for _, p := range params {
+		layout.Args = append(layout.Args, p.Ref())
+	}
+
+	return Type{
+		Name:       name,
+		TypeParams: append([]TypeParam{{Name: self.Name}}, params...),
+		Features:   append([]Feature{layout}, methods...),
+	}
+}
+
+// operator returns the signature of a pure method with an immutable
+// receiver, such as those that operators map to.
+func operator(name string, ret Type, args ...Type) Feature {
+	return Feature{Type: FuncFeature, Name: name, Args: args, Return: ret}
+}
+
+// binaryOperators and unaryOperators map operators to the methods that
+// they are defined by. Comparison operators that are not listed are
+// defined in terms of eq, lt and le. Not being less than something is
+// not the same as being greater than or equal to it, as NaN is
+// neither, so each needs its own method.
+var (
+	binaryOperators = map[scanner.Type]string{
+		scanner.PLUS:   "add",
+		scanner.MINUS:  "sub",
+		scanner.MULT:   "mul",
+		scanner.DIV:    "div",
+		scanner.MOD:    "mod",
+		scanner.BITAND: "and",
+		scanner.BITOR:  "or",
+		scanner.BITNOT: "xor",
+		scanner.LSHIFT: "shl",
+		scanner.RSHIFT: "shr",
+		scanner.EQUAL:  "eq",
+		scanner.LT:     "lt",
+		scanner.LE:     "le",
+	}
+
+	unaryOperators = map[scanner.Type]string{
+		scanner.MINUS:  "neg",
+		scanner.BITNOT: "compl",
+		scanner.NOT:    "not",
+	}
+)
+
+// OperatorMethod returns the name of the method that the operator op
+// maps to. If unary is true, op is a unary operator. For comparisons
+// that are defined in terms of other methods, the method that is used
+// is returned, and swap and negate are set if the operands must be
+// swapped or the result negated, respectively.
+func OperatorMethod(op scanner.Type, unary bool) (name string, swap, negate, ok bool) {
+	if unary {
+		name, ok = unaryOperators[op]
+		return name, false, false, ok
+	}
+
+	switch op {
+	case scanner.NOTEQUAL:
+		return "eq", false, true, true
+	case scanner.GT:
+		return "lt", true, false, true
+	case scanner.GE:
+		return "le", true, false, true
+	}
+	name, ok = binaryOperators[op]
+	return name, false, false, ok
+}
@@ -0,0 +1,104 @@
+package stele
+
+import (
+	"testing"
+
+	"deedles.dev/stele/scanner"
+)
+
+func TestPredeclared(t *testing.T) {
+	names := []string{
+		"int", "uint", "byte", "float", "bigint", "bigfloat",
+		"anyint", "anyfloat", "numeric", "string", "array", "bool",
+		"true", "false", "any", "unit", "result", "error", "opt",
+	}
+	for _, name := range names {
+		if RootScope().Get(name) == nil {
+			t.Errorf("%v is not predeclared", name)
+		}
+	}
+
+	shadow := RootScope().Add(Let{Name: "int", T: String})
+	if !RootScope().Predeclared("int") || shadow.Predeclared("int") {
+		t.Fatal("int is not shadowed")
+	}
+	if !shadow.Predeclared("bool") {
+		t.Fatal("bool is shadowed")
+	}
+}
+
+func TestBuiltinMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      Type
+		other  Type
+		method string
+		want   string
+	}{
+		{name: "Int", t: Int, other: Numeric, method: "add", want: "-> (int) int"},
+		{name: "Float", t: Float, other: AnyFloat, method: "lt", want: "-> (float) bool"},
+		{
+			name:   "Embedded",
+			t:      Type{Name: "example", Features: []Feature{{Type: EmbedFeature, Return: Int}}},
+			other:  AnyInt,
+			method: "shl",
+			want:   "-> (example) example",
+		},
+		{name: "String", t: String, other: Any, method: "len", want: "-> () int"},
+		{name: "Bool", t: Bool, other: Any, method: "not", want: "-> () bool"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if !test.t.Satisfies(test.other) {
+				t.Fatalf("%v does not satisfy %v: %v", test.t, test.other, test.t.Explain(test.other))
+			}
+			f, ok := test.t.Feature(FuncFeature, test.method)
+			if !ok {
+				t.Fatalf("%v has no method %v", test.t, test.method)
+			}
+			if s := FuncOf(f).String(); s != test.want {
+				t.Fatalf("unexpected method: %v", s)
+			}
+		})
+	}
+
+	if Float.Satisfies(AnyInt) {
+		t.Fatal("float satisfies anyint")
+	}
+	if _, ok := Float.Feature(FuncFeature, "mod"); ok {
+		t.Fatal("float has a mod method")
+	}
+}
+
+func TestOperatorMethod(t *testing.T) {
+	tests := []struct {
+		op     scanner.Type
+		unary  bool
+		name   string
+		swap   bool
+		negate bool
+	}{
+		{op: scanner.PLUS, name: "add"},
+		{op: scanner.MINUS, unary: true, name: "neg"},
+		{op: scanner.NOT, unary: true, name: "not"},
+		{op: scanner.NOTEQUAL, name: "eq", negate: true},
+		{op: scanner.GT, name: "lt", swap: true},
+		{op: scanner.LE, name: "le"},
+		{op: scanner.GE, name: "le", swap: true},
+	}
+
+	for _, test := range tests {
+		name, swap, negate, ok := OperatorMethod(test.op, test.unary)
+		if !ok || (name != test.name) || (swap != test.swap) || (negate != test.negate) {
+			t.Errorf("unexpected method for %v: %v, %v, %v, %v", test.op, name, swap, negate, ok)
+		}
+	}
+
+	if _, _, _, ok := OperatorMethod(scanner.AND, false); ok {
+		t.Error("&& maps to a method")
+	}
+}
@@ -0,0 +1,616 @@
+// Package check resolves and checks the syntax tree of Stele source
+// code, lowering it into a runnable [stele.Script].
+//
+// Checking is a separate stage from parsing so that tools that only
+// need the syntax, such as formatters, can work with source that does
+// not check.
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+// Config configures the checker.
+type Config struct {
+	// Importer resolves the packages imported by the checked source.
+	// If it is nil, imports are not resolved and imported modules
+	// have no members.
+	Importer stele.Importer
+
+	// Warn, if not nil, is called with each warning found while
+	// checking. Warnings do not cause checking to fail.
+	Warn func(*Error)
+}
+
+// File checks a single parsed file and lowers it into a Script. If
+// checking fails, the returned error is an ErrorList.
+func File(file *ast.File) (stele.Script, error) {
+	return new(Config).File(file)
+}
+
+// File checks a single parsed file and lowers it into a Script. If
+// checking fails, the returned error is an ErrorList.
+func (conf *Config) File(file *ast.File) (stele.Script, error) {
+	return conf.Package([]*ast.File{file})
+}
+
+// Package checks the files of a single package together, lowering
+// them into one Script. If checking fails, the returned error is an
+// ErrorList.
+func (conf *Config) Package(files []*ast.File) (stele.Script, error) {
+	c := checker{conf: conf, descs: stele.NewDescs()}
+	script := c.pkg(files)
+	return script, c.errs.Err()
+}
+
+type checker struct {
+	conf *Config
+	errs ErrorList
+
+	// scope is the scope of the code currently being checked, and
+	// pkgScope is the scope of the package's top-level declarations.
+	scope    stele.Scope
+	pkgScope stele.Scope
+
+	types map[string]*typeInfo
+	funcs map[string]*funcInfo
+
+	// methods holds the position of each method that has been
+	// declared, keyed by its receiver type and name.
+	methods map[methodKey]scanner.Pos
+
+	// methodDecls holds the package's method declarations by name,
+	// so that methods can be attached to the types of receivers before
+	// they have been checked.
+	methodDecls map[string][]*funcInfo
+
+	// descs is the table that the types of the script are interned in.
+	descs *stele.Descs
+
+	// ret is the return type of the function currently being checked,
+	// and mut is true if that function is mutable.
+	ret stele.Type
+	mut bool
+
+	// locals is the set of variables declared by the function currently
+	// being checked, including its parameters. It is nil outside of
+	// functions.
+	locals map[string]struct{}
+
+	// loops is the number of loops that the code currently being
+	// checked is in, within the function that it belongs to.
+	loops int
+
+	delayed []func()
+}
+
+// lookup finds the declaration of id in c.scope. Package-level types
+// and functions are only added to it once they have been checked, but
+// they shadow predeclared identifiers even before then, so lookup
+// returns nil for a predeclared identifier that the package declares
+// one of. The caller is then expected to look for it in c.types or
+// c.funcs.
+func (c *checker) lookup(id string) stele.Declaration {
+	if c.scope.Predeclared(id) {
+		if _, ok := c.types[id]; ok {
+			return nil
+		}
+		if _, ok := c.funcs[id]; ok {
+			return nil
+		}
+	}
+	return c.scope.Get(id)
+}
+
+// declare adds d, declared at pos, to c.scope. It reports an error if
+// d redeclares something in the same block.
+func (c *checker) declare(d stele.Declaration, pos scanner.Pos) bool {
+	scope, err := c.scope.Declare(d, pos)
+	if err != nil {
+		c.errorf(pos, "%v", err)
+		return false
+	}
+	c.scope = scope
+	return true
+}
+
+// ref returns where the variable id, as it is seen from c.scope, is
+// kept at run time. Anything that is declared in a block inside of the
+// package's is local. Everything else is global.
+func (c *checker) ref(id string) stele.Ref {
+	depth, slot, ok := c.scope.Resolve(id)
+	if !ok || (c.scope.Depth()-depth <= c.pkgScope.Depth()) {
+		return stele.Ref{}
+	}
+	return stele.Ref{Local: true, Depth: depth, Slot: slot}
+}
+
+// resolve fills in where the variables that a is assigned to are kept
+// at run time.
+func (c *checker) resolve(a *stele.Assign) {
+	switch {
+	case len(a.IDs) > 0:
+		a.Refs = make([]stele.Ref, 0, len(a.IDs))
+		for _, id := range a.IDs {
+			a.Refs = append(a.Refs, c.ref(id))
+		}
+	case a.Recv != "":
+		a.Ref = c.ref(a.Recv)
+	case a.Struct != nil:
+	default:
+		a.Ref = c.ref(a.ID)
+	}
+}
+
+func (c *checker) warnf(pos scanner.Pos, format string, args ...any) {
+	if c.conf.Warn != nil {
+		c.conf.Warn(&Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+	}
+}
+
+// later delays f until everything else in the package has been
+// checked.
+func (c *checker) later(f func()) {
+	c.delayed = append(c.delayed, f)
+}
+
+func (c *checker) errorf(pos scanner.Pos, format string, args ...any) {
+	c.errs = append(c.errs, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) pkg(files []*ast.File) stele.Script {
+	c.types = make(map[string]*typeInfo)
+	c.funcs = make(map[string]*funcInfo)
+	c.methods = make(map[methodKey]scanner.Pos)
+	c.methodDecls = make(map[string][]*funcInfo)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			// If something is redeclared, the first declaration is the
+			// one that is used. The others are reported once they are
+			// reached in order.
+			switch decl := decl.(type) {
+			case *ast.TypeDecl:
+				if _, ok := c.types[decl.Name.Name]; !ok {
+					c.types[decl.Name.Name] = &typeInfo{decl: decl}
+				}
+			case *ast.Func:
+				if decl.Recv != nil {
+					c.methodDecls[decl.Name.Name] = append(c.methodDecls[decl.Name.Name], &funcInfo{decl: decl})
+					break
+				}
+				if _, ok := c.funcs[decl.Name.Name]; !ok {
+					c.funcs[decl.Name.Name] = &funcInfo{decl: decl}
+				}
+			}
+		}
+	}
+
+	var decls []stele.Declaration
+	for _, file := range files {
+		decls = append(decls, c.file(file)...)
+	}
+	for i := 0; i < len(c.delayed); i++ {
+		c.delayed[i]()
+	}
+
+	// Methods are found through their receivers rather than by name,
+	// so they are not in the package's scope.
+	named := make([]stele.Declaration, 0, len(decls))
+	for _, d := range decls {
+		if f, ok := d.(stele.Func); ok && (f.Recv != nil) {
+			continue
+		}
+		named = append(named, d)
+	}
+
+	var script stele.Script
+	script.Scope = script.Scope.AddAll(named)
+	script.Decls = decls
+	script.Descs = c.descs
+	return script
+}
+
+func (c *checker) file(file *ast.File) []stele.Declaration {
+	var decls []stele.Declaration
+	add := func(d stele.Declaration, pos scanner.Pos) {
+		c.scope = c.pkgScope
+		if c.declare(d, pos) {
+			decls = append(decls, d)
+			c.pkgScope = c.scope
+		}
+	}
+
+	imports := make(map[string]*ast.Import)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.Import:
+			imp, ok := c.importDecl(decl, imports)
+			if !ok {
+				continue
+			}
+			// Imports belong to the file that they are in, so each of a
+			// package's files may import the same package by the same
+			// name.
+			if prev, ok := c.pkgScope.Get(imp.Name).(stele.Import); ok && (prev.Path == imp.Path) {
+				continue
+			}
+			add(imp, decl.Pos())
+
+		case *ast.TypeDecl:
+			info := c.types[decl.Name.Name]
+			if info.decl != decl {
+				info = &typeInfo{decl: decl}
+			}
+			if t, ok := c.typeDecl(info); ok {
+				add(t, decl.Name.Pos())
+			}
+
+		case *ast.Let:
+			lets, _ := c.letDecl(decl)
+			for i, let := range lets {
+				add(let, decl.Names[i].Pos())
+				if let.Assign != nil {
+					c.resolve(let.Assign)
+				}
+			}
+
+		case *ast.Func:
+			f, ok := c.funcDecl(decl)
+			if !ok {
+				continue
+			}
+			if f.Recv != nil {
+				c.attach(&f, decl)
+				if c.method(f, decl.Name.Pos()) {
+					decls = append(decls, f)
+				}
+				continue
+			}
+			add(f, decl.Name.Pos())
+
+		default:
+			c.errorf(decl.Pos(), "%v is not supported yet", describe(decl))
+		}
+	}
+	return decls
+}
+
+type methodKey struct {
+	recv string
+	name string
+}
+
+// method checks that a method, declared at pos, is not a redeclaration
+// of another method of the same receiver type. Methods are found
+// through their receivers, so methods of different types may have the
+// same name.
+func (c *checker) method(f stele.Func, pos scanner.Pos) bool {
+	if f.Recv.T.Param {
+		// Methods with generic receivers are checked for conflicts
+		// when they are attached.
+		return true
+	}
+	key := methodKey{recv: f.Recv.T.String(), name: f.Name}
+	if prev, ok := c.methods[key]; ok {
+		c.errorf(pos, "method %v.%v redeclared; previous declaration at %v", key.recv, key.name, prev)
+		return false
+	}
+	c.methods[key] = pos
+	return true
+}
+
+// importDecl checks an import and resolves the imported package.
+// imports holds the imports seen so far in the file, by name.
+func (c *checker) importDecl(decl *ast.Import, imports map[string]*ast.Import) (stele.Import, bool) {
+	p := decl.Path.Value.(string)
+	if !validImportPath(p) {
+		c.errorf(decl.Path.Pos(), "invalid import path %q", p)
+		return stele.Import{}, false
+	}
+
+	var name string
+	switch {
+	case decl.Name != nil:
+		name = decl.Name.Name
+		if !scanner.IsIdentifier(name) {
+			c.errorf(decl.Name.Pos(), "invalid import name %v", name)
+			return stele.Import{}, false
+		}
+
+	default:
+		// Without an explicit name, an import is named by the last
+		// element of its path.
+		name = path.Base(p)
+		if !scanner.IsIdentifier(name) {
+			c.errorf(decl.Path.Pos(), "import path %q does not end in a valid identifier; name it with as", p)
+			return stele.Import{}, false
+		}
+	}
+
+	if prev, ok := imports[name]; ok {
+		c.errorf(decl.Pos(), "%v redeclared in this file; previous import at %v", name, prev.Pos())
+		return stele.Import{}, false
+	}
+	imports[name] = decl
+
+	imp := stele.Import{Name: name, Path: p}
+	if c.conf.Importer == nil {
+		return imp, true
+	}
+
+	decls, err := c.conf.Importer.Import(p)
+	if err != nil {
+		if errors.Is(err, stele.ErrNotFound) {
+			c.errorf(decl.Path.Pos(), "package %q not found", p)
+			return stele.Import{}, false
+		}
+		c.errorf(decl.Path.Pos(), "could not import %q: %v", p, err)
+		return stele.Import{}, false
+	}
+	imp.Decls = decls
+	return imp, true
+}
+
+// validImportPath returns true if p is a valid import path. Import
+// paths are slash-separated and may not be empty, rooted, or contain
+// empty, . or .. elements.
+func validImportPath(p string) bool {
+	return fs.ValidPath(p) && (p != ".") && !strings.ContainsAny(p, "\\:")
+}
+
+// letDecl checks a variable declaration. A declaration of more than
+// one variable destructures a tuple, in which case only the first of
+// the returned variables has an Assign, which assigns all of them. If
+// the declaration fails to check, its variables are still returned,
+// but with an invalid type.
+func (c *checker) letDecl(decl *ast.Let) ([]stele.Let, bool) {
+	if (decl.Type == nil) && (decl.Value == nil) {
+		c.errorf(decl.Pos(), "variable %v has neither a type nor a value", decl.Names[0].Name)
+		return invalidLets(decl), false
+	}
+
+	var t stele.Type
+	if decl.Type != nil {
+		var ok bool
+		t, ok = c.typeExpr(decl.Type)
+		if !ok {
+			return invalidLets(decl), false
+		}
+	}
+
+	lets := make([]stele.Let, 0, len(decl.Names))
+	for _, name := range decl.Names {
+		lets = append(lets, stele.Let{Name: name.Name, T: t})
+	}
+	if decl.Value == nil {
+		for i, let := range lets {
+			if !let.Mutable() {
+				c.errorf(decl.Names[i].Pos(), "cannot declare immutable %v without a value", let.ID())
+				return invalidLets(decl), false
+			}
+		}
+		if !stele.HasZero(t) {
+			c.errorf(decl.Type.Pos(), "cannot declare %v without a value: %v has no zero value", decl.Names[0].ID(), t)
+			return invalidLets(decl), false
+		}
+		for i, name := range decl.Names {
+			lets[i].Assign = &stele.Assign{ID: name.ID(), Val: stele.Zero{T: t}}
+		}
+		return lets, true
+	}
+
+	rhs := c.expr(decl.Value)
+	if rhs == nil {
+		return invalidLets(decl), false
+	}
+
+	if len(lets) == 1 {
+		if decl.Type == nil {
+			if c.untyped(decl.Value.Pos(), rhs, decl.Names[0].Name) {
+				return invalidLets(decl), false
+			}
+			lets[0].T = rhs.Type()
+		} else {
+			var ok bool
+			if rhs, ok = c.convert(decl.Value.Pos(), rhs, t); !ok {
+				return invalidLets(decl), false
+			}
+		}
+		lets[0].Assign = &stele.Assign{ID: decl.Names[0].ID(), Val: c.copied(rhs, lets[0].Mutable())}
+		return lets, true
+	}
+
+	elems, ok := c.destructure(decl.Value.Pos(), rhs, len(lets))
+	if !ok {
+		return invalidLets(decl), false
+	}
+	to := make([]stele.Type, 0, len(lets))
+	names := make([]string, 0, len(lets))
+	pos := make([]scanner.Pos, 0, len(lets))
+	for _, name := range decl.Names {
+		to = append(to, t)
+		names = append(names, name.Name)
+		pos = append(pos, name.Pos())
+	}
+	rhs, ok = c.assignElems(rhs, elems, to, names, pos)
+	if !ok {
+		return invalidLets(decl), false
+	}
+
+	elems, _ = rhs.Type().Tuple()
+	ids := make([]string, 0, len(lets))
+	mut := make([]bool, 0, len(lets))
+	for i, name := range decl.Names {
+		ids = append(ids, name.ID())
+		mut = append(mut, lets[i].Mutable())
+		if decl.Type == nil {
+			lets[i].T = elems[i]
+		}
+	}
+	lets[0].Assign = &stele.Assign{IDs: ids, Val: c.copiedElems(rhs, mut)}
+	return lets, true
+}
+
+// invalidLets returns the variables declared by decl with an invalid
+// type. Declaring them anyway keeps the uses of variables whose
+// declarations failed to check from being reported as undefined.
+func invalidLets(decl *ast.Let) []stele.Let {
+	lets := make([]stele.Let, 0, len(decl.Names))
+	for _, name := range decl.Names {
+		lets = append(lets, stele.Let{Name: name.Name})
+	}
+	return lets
+}
+
+// destructure checks that x is a tuple with n elements and returns
+// the types of those elements.
+func (c *checker) destructure(pos scanner.Pos, x stele.Expr, n int) ([]stele.Type, bool) {
+	elems, ok := x.Type().Tuple()
+	if !ok {
+		c.errorf(pos, "cannot destructure %v into %v variables: not a tuple", x.Type(), n)
+		return nil, false
+	}
+	if len(elems) != n {
+		c.errorf(pos, "assignment mismatch: %v variables but %v has %v elements", n, x.Type(), len(elems))
+		return nil, false
+	}
+	return elems, true
+}
+
+// assignElems checks that the elements of the tuple x, which are of
+// the types in elems, can be assigned to variables of the types in to.
+// An invalid type in to is a variable whose type is inferred from its
+// element. names and pos hold the name and position of each variable.
+// If x is a tuple literal, any untyped constants in it are converted.
+func (c *checker) assignElems(x stele.Expr, elems, to []stele.Type, names []string, pos []scanner.Pos) (stele.Expr, bool) {
+	lit, isLit := x.(stele.Tuple)
+	if !isLit {
+		ok := true
+		for i := range elems {
+			ok = c.assignable(pos[i], elems[i], to[i]) && ok
+		}
+		return x, ok
+	}
+
+	ok := true
+	lit.Elems = slices.Clone(lit.Elems)
+	types := make([]stele.Type, 0, len(lit.Elems))
+	for i, e := range lit.Elems {
+		if to[i].Valid() {
+			ce, eok := c.convert(pos[i], e, to[i])
+			if eok {
+				lit.Elems[i] = ce
+			}
+			ok = ok && eok
+		} else {
+			ok = !c.untyped(pos[i], e, names[i]) && ok
+		}
+		types = append(types, lit.Elems[i].Type())
+	}
+	lit.T = stele.TupleType(types...)
+	lit.Desc = nil
+	if ok {
+		lit.Desc = c.descs.Intern(lit.T)
+	}
+	return lit, ok
+}
+
+func (c *checker) expr(expr ast.Expr) stele.Expr {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		return c.basicLit(expr)
+
+	case *ast.Unary:
+		return c.unary(expr)
+
+	case *ast.Binary:
+		if expr.Op == scanner.PIPE {
+			return c.pipe(expr)
+		}
+		return c.binary(expr)
+
+	case *ast.Paren:
+		return c.expr(expr.X)
+
+	case *ast.Ident:
+		return c.ident(expr)
+
+	case *ast.Selector:
+		return c.selector(expr)
+
+	case *ast.If:
+		return c.ifExpr(expr)
+
+	case *ast.Switch:
+		return c.switchExpr(expr)
+
+	case *ast.Block:
+		return c.block(expr)
+
+	case *ast.TypeAssert:
+		return c.typeAssert(expr)
+
+	case *ast.Try:
+		return c.try(expr)
+
+	case *ast.Call:
+		return c.call(expr)
+
+	case *ast.TupleLit:
+		return c.tupleLit(expr)
+
+	case *ast.ArrayLit:
+		return c.arrayLit(expr)
+
+	case *ast.StructLit:
+		return c.structLit(expr)
+
+	case *ast.FuncLit:
+		return c.funcLit(expr)
+
+	case *ast.Index:
+		x := c.expr(expr.X)
+		if x == nil {
+			return nil
+		}
+		if sig, ok := x.Type().Func(); ok && (len(sig.TypeParams) > 0) {
+			return c.instantiate(expr, x, sig)
+		}
+		if elems, ok := x.Type().Tuple(); ok {
+			return c.tupleIndex(expr, x, elems)
+		}
+		if m, ok := x.Type().Feature(stele.FuncFeature, "get"); ok && (len(m.Args) == 1) {
+			return c.index(expr, x, m)
+		}
+	}
+
+	c.errorf(expr.Pos(), "%v is not supported yet", describe(expr))
+	return nil
+}
+
+// describe returns a short, human-readable description of a node for
+// use in error messages.
+func describe(node ast.Node) string {
+	name := fmt.Sprintf("%T", node)
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	var words []string
+	start := 0
+	for i, c := range name {
+		if (i > 0) && (c >= 'A') && (c <= 'Z') {
+			words = append(words, name[start:i])
+			start = i
+		}
+	}
+	words = append(words, name[start:])
+	return strings.ToLower(strings.Join(words, " "))
+}
@@ -0,0 +1,1961 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser"
+)
+
+func TestFile(t *testing.T) {
+	const src = `import "test"
+import "something/else" as something
+
+let v! int = 3`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(script.Decls) != 3 {
+		t.Fatalf("expected 3 declarations but got %v", len(script.Decls))
+	}
+	if d, ok := script.Scope.Get("something").(stele.Import); !ok || (d.Path != "something/else") {
+		t.Fatalf("unexpected declaration for something: %#v", d)
+	}
+	if d := script.Scope.Get("v"); (d == nil) || d.Mutable() {
+		t.Fatalf("unexpected declaration for v: %#v", d)
+	}
+}
+
+func TestFileUnsupported(t *testing.T) {
+	const src = `let v int = 3
+let u = v |> f()
+let w int = 4`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	var list ErrorList
+	if !errors.As(err, &list) || (len(list) != 1) {
+		t.Fatalf("expected a single error but got %v", err)
+	}
+	if list[0].Pos.Line != 2 {
+		t.Fatalf("error reported at wrong position: %v", list[0])
+	}
+	if script.Scope.Get("w") == nil {
+		t.Fatal("declarations after the error were not checked")
+	}
+}
+
+func TestImports(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{name: "Valid", src: "import \"a/b\"\nimport \"a/c\" as d"},
+		{name: "Duplicate", src: "import \"a/b\"\nimport \"c/b\"", err: "(2:1) b redeclared in this file; previous import at 1:1"},
+		{name: "Alias", src: "import \"a/b\"\nimport \"c/b\" as c"},
+		{name: "InvalidDerived", src: `import "a/b-c"`, err: `(1:8) import path "a/b-c" does not end in a valid identifier; name it with as`},
+		{name: "Keyword", src: `import "a/func"`, err: `(1:8) import path "a/func" does not end in a valid identifier; name it with as`},
+		{name: "InvalidAlias", src: `import "a/b" as b!`, err: "(1:17) invalid import name b!"},
+		{name: "Rooted", src: `import "/a"`, err: `(1:8) invalid import path "/a"`},
+		{name: "Dots", src: `import "a/../b"`, err: `(1:8) invalid import path "a/../b"`},
+		{name: "Missing", src: `import "missing"`, err: `(1:8) package "missing" not found`},
+	}
+
+	imp := importerFunc(func(path string) ([]stele.Declaration, error) {
+		if path == "missing" {
+			return nil, stele.ErrNotFound
+		}
+		return []stele.Declaration{stele.Let{Name: "x"}}, nil
+	})
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			file, err := parser.Parse(strings.NewReader(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			conf := Config{Importer: imp}
+			script, err := conf.File(file)
+			if test.err != "" {
+				if (err == nil) || (err.Error() != test.err) {
+					t.Fatalf("expected error %q but got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, ok := script.Decls[0].(stele.Import)
+			if !ok || (len(d.Type().Features) != 1) || (d.Type().Features[0].Name != "x") {
+				t.Fatalf("unexpected import: %#v", script.Decls[0])
+			}
+		})
+	}
+}
+
+type importerFunc func(string) ([]stele.Declaration, error)
+
+func (f importerFunc) Import(path string) ([]stele.Declaration, error) { return f(path) }
+
+func TestAssignable(t *testing.T) {
+	const src = `type text {
+	func len() text
+}
+
+type reader {
+	func read(text) text
+}
+
+type file {
+	reader
+	func close()
+}
+
+type number oneof {
+	file
+	reader
+}
+
+let f file = &disk{size = 0}
+let r reader = f
+let bad file = r
+let n number = f
+
+type disk {
+	let size int
+	func read(text) text
+	func close()
+}
+
+func (d disk) read(t text) text { t }
+func (d disk) close() {}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	var list ErrorList
+	if !errors.As(err, &list) || (len(list) != 3) {
+		t.Fatalf("expected three errors but got %v", err)
+	}
+	if msg := list[0].Error(); msg != "(21:16) cannot use reader as file: missing method close" {
+		t.Fatalf("unexpected first error: %v", msg)
+	}
+	if msg := list[1].Error(); msg != "(22:16) cannot use file as number: satisfies more than one of file, reader" {
+		t.Fatalf("unexpected second error: %v", msg)
+	}
+	if msg := list[2].Error(); msg != "(16:2) oneof members file and reader overlap: file satisfies reader" {
+		t.Fatalf("unexpected third error: %v", msg)
+	}
+
+	if d, ok := script.Scope.Get("r").(stele.Let); !ok || (d.Type().Name != "reader") {
+		t.Fatalf("unexpected declaration for r: %#v", script.Scope.Get("r"))
+	}
+}
+
+func TestRecursiveType(t *testing.T) {
+	const src = `type a { b }
+type b { a }`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	if (err == nil) || !strings.Contains(err.Error(), "invalid recursive type") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	const src = `type text {
+	func len() text
+}
+
+type [T] adder {
+	func add(T) T
+}
+
+type num {
+	func add(num) num
+}
+
+type [T, E any] box {
+	let val E
+}
+
+type [T, E any] list {
+	let next array[list[T, E]]
+	let val E
+}
+
+type [T, E adder] holder {
+	let val E
+}
+
+func [T any] id(v T) T { v }
+
+func [T adder, E any] pick(a T, b E) E { b }
+
+let n num = &count{v = 1}
+let t text = &count{v = 1}
+let a = id(n)
+let b num = id[num](n)
+let c box[num] = &box[num]{val = n}
+let l list[num] = &list[num]{val = n}
+let l2 list[num] = l
+let p = pick(n, t)
+let h holder[num] = &holder[num]{val = n}
+
+let e1 = id[text](n)
+let e2 box[text] = c
+let e3 holder[text]
+let e4 = pick(t, n)
+let e5 = id()
+let e6 list[text] = l
+let e7 box
+
+type count {
+	let v int
+	func add(num) num
+	func len() text
+}
+
+func (c count) add(o num) num { c }
+func (c count) len() text { c }
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(40:19) cannot use num as text: missing method len",
+		"(41:20) cannot use box[num] as box[text]: field val has type num, but text is required",
+		"(42:14) cannot instantiate holder: text does not satisfy adder (missing method add)",
+		"(43:14) cannot instantiate pick: text does not satisfy adder (missing method add)",
+		"(44:12) wrong number of arguments in call: have 0, want 1",
+		"(45:21) cannot use list[num] as list[text]: field next has type array[list[list[num], num]], but array[list[list[num], text]] is required; field val has type num, but text is required",
+		"(46:8) generic type box must be instantiated",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+
+	types := map[string]string{
+		"id": "[T any] -> (T) T",
+		"a":  "num",
+		"b":  "num",
+		"p":  "text",
+		"l2": "list[num]",
+	}
+	for id, name := range types {
+		d := script.Scope.Get(id)
+		if (d == nil) || (d.Type().String() != name) {
+			t.Errorf("unexpected declaration for %v: %#v", id, d)
+		}
+	}
+}
+
+func TestOneof(t *testing.T) {
+	const src = `type text {
+	func len() text
+}
+
+type a {
+	func a() text
+	func b() text
+}
+
+type b {
+	func a() text
+	func b()
+	func c()
+}
+
+type e oneof {
+	a
+	b
+}
+
+type overlap oneof {
+	a
+	type { func a() text }
+}
+
+let ok bool
+let x e = &impl{v = 0}
+
+func common() text { x.a() }
+func partial() { x.b() }
+func missing() { x.c() }
+
+func cond(v a, w b) e {
+	if ok { v } else { w }
+}
+
+func branches(v a) {
+	if ok { v }
+}
+
+func exhaustive(v a, w b) e {
+	switch x {
+	.(a) { v }
+	.(b) { w }
+	}
+}
+
+func incomplete(v a) {
+	switch x {
+	.(a) { v }
+	}
+}
+
+type impl {
+	let v int
+	func a() text
+	func b() text
+	func len() text
+}
+
+func (i impl) a() text { i }
+func (i impl) b() text { i }
+func (i impl) len() text { i }
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []string
+	conf := Config{Warn: func(err *Error) { warnings = append(warnings, err.Error()) }}
+	_, err = conf.File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(30:20) x.b undefined (type e has no field or method b)",
+		"(31:20) x.c undefined (type e has no field or method c)",
+		"(23:2) oneof members a and type { func a() text } overlap: a satisfies type { func a() text }",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+
+	wantWarnings := []string{"(49:2) switch on e is not exhaustive: missing b"}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Fatalf("unexpected warnings:\n%v", strings.Join(warnings, "\n"))
+	}
+}
+
+func TestNarrow(t *testing.T) {
+	const src = `func narrowed(v! any) int {
+	if v.(int) { v + 1 } else { v.len() }
+}
+
+func mutable(v any) int {
+	if v.(int) { v + 1 } else { 0 }
+}
+
+func cases(v! oneof { int; string }) {
+	switch v {
+	.(int) { v.len() }
+	.(string) { v.len() }
+	}
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(2:32) v.len undefined (type any has no field or method len)",
+		"(6:17) operator + not defined on v (type any has no method add)",
+		"(11:13) v.len undefined (type int has no field or method len)",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestMethodScope(t *testing.T) {
+	const src = `type example int
+type label string
+
+func (e example) double() int { e + e }
+func [T int] (v T) triple() int { v * 3 }
+func [T any] (v T) triple() int { 0 }
+
+func main() {
+	let v int = 3
+	v.double()
+	example(v).double()
+	label(v)
+	example()
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(6:20) method triple is ambiguous for example: it and the method declared at 5:20 both have generic receivers that example satisfies",
+		"(10:4) v.double undefined (type int has no field or method double)",
+		"(12:8) cannot use int as label: missing underlying string",
+		"(13:10) missing argument in conversion to example",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestTuples(t *testing.T) {
+	const src = `type text {
+	func len() text
+}
+
+type num {
+	func add(num) num
+}
+
+type pair (text, num)
+
+type named {
+	(text, num)
+	let name text
+}
+
+type twice {
+	(text, num)
+	(num, text)
+}
+
+let p pair = (&both{v = 0}, &both{v = 0})
+func second(n named) num { n[1] }
+let s text = p[0]
+let i num = p[1]
+let a, b = p
+let c, d text = p
+let t = (s, i)
+
+func swap(x text, y num) {
+	let u text = x
+	let v num = y
+	u, v = (x, y)
+	v, u = t
+}
+
+let e1 = p[2]
+let e2, e3, e4 = p
+let e5, e6 = s
+let e7 num = p[0]
+
+type both {
+	let v int
+	func len() text
+	func add(num) num
+}
+
+func (b both) len() text { b }
+func (b both) add(o num) num { b }
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(18:2) type already has a tuple at 17:2",
+		"(26:8) cannot use num as text: missing method len",
+		"(33:2) cannot use text as num: missing method add",
+		"(33:5) cannot use num as text: missing method len",
+		"(36:12) index 2 out of range for pair with 2 elements",
+		"(37:18) assignment mismatch: 3 variables but pair has 2 elements",
+		"(38:14) cannot destructure text into 2 variables: not a tuple",
+		"(39:14) cannot use text as num: missing method add",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+
+	types := map[string]string{
+		"s": "text",
+		"i": "num",
+		"a": "text",
+		"b": "num",
+		"t": "(text, num)",
+	}
+	for id, name := range types {
+		d := script.Scope.Get(id)
+		if (d == nil) || (d.Type().String() != name) {
+			t.Errorf("unexpected declaration for %v: %#v", id, d)
+		}
+	}
+}
+
+func TestFuncTypes(t *testing.T) {
+	const src = `type num {
+	func add(num) num
+}
+
+type logger {
+	func log(num) mut
+}
+
+func double(n num) num { n.add(n) }
+func print(n num) mut { }
+
+let f -> (num) num = double
+let g -> (num) mut num = double
+let h -> (num) = print
+let k -> (num) mut = print
+
+func pure(l logger, n num) {
+	print(n)
+	l.log(n)
+	k(n)
+}
+
+func impure(l logger, n num) mut {
+	print(n)
+	l.log(n)
+	k(n)
+	f(n)
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(14:18) cannot use -> (num) mut unit as -> (num) unit: function is mutable",
+		"(18:2) cannot call mutable function print from a pure context",
+		"(19:2) cannot call mutable function l.log from a pure context",
+		"(20:2) cannot call mutable function k from a pure context",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+
+	if d := script.Scope.Get("g"); (d == nil) || (d.Type().String() != "-> (num) mut num") {
+		t.Fatalf("unexpected declaration for g: %#v", d)
+	}
+}
+
+func TestConstants(t *testing.T) {
+	const src = `let a int = 3
+let b byte = 'a'
+let c float = 1.5
+let d bigint = 1 << 100
+let e int = (1 << 100) >> 98
+let f (int, float) = (1, 2)
+let g, h int = (1, 2)
+
+func example(v numeric) numeric { v * 2 }
+func [T numeric] generic(v T) T { v * 2 }
+func five() int { 5 }
+
+let i = example(3)
+let j = generic(a)
+let k = 'a' < 'b'
+
+let e1 = 3
+let e2 byte = 256
+let e3 int = 1.5
+let e4 int = 1 << 100
+let e5 = generic(3)
+let e6 byte = 'あ'
+let e7 int = 1 / 0
+let e8, e9 = (1, 2)
+
+type either oneof {
+	int
+	string
+}
+
+func pick(b bool) int { if b { 1 } else { 2 } }
+func sign(x int) int {
+	switch x {
+		< 0 { 0 }
+		else { x }
+	}
+}
+func kind(v! either) int {
+	switch v {
+		.(int) { 1 }
+		.(string) { 2 }
+	}
+}
+let l int = if k { 1 } else { 2 }
+let e10 = if k { 1 } else { 2 }
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(17:10) cannot infer type of e1 from untyped int constant 3",
+		"(18:15) cannot use 256 as byte: constant 256 overflows byte",
+		"(19:14) cannot use 1.5 as int: constant 1.5 truncated to int",
+		"(20:14) cannot use 1267650600228229401496703205376 as int: constant 1267650600228229401496703205376 overflows int",
+		"(21:17) cannot infer T in call to generic: untyped constant 3 could be of more than one type",
+		"(22:15) cannot use 12354 as byte: constant 12354 overflows byte",
+		"(23:16) division by zero",
+		"(24:5) cannot infer type of e8 from untyped int constant 1",
+		"(24:9) cannot infer type of e9 from untyped int constant 2",
+		"(45:11) cannot infer type of e10 from untyped int constant 1",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+
+	types := map[string]string{
+		"a": "int",
+		"d": "bigint",
+		"f": "(int, float)",
+		"h": "int",
+		"i": "numeric",
+		"j": "int",
+		"k": "bool",
+		"l": "int",
+	}
+	for id, name := range types {
+		d := script.Scope.Get(id)
+		if (d == nil) || (d.Type().String() != name) {
+			t.Errorf("unexpected declaration for %v: %#v", id, d)
+		}
+	}
+}
+
+func TestPurity(t *testing.T) {
+	const src = `type counter {
+	let n int
+	func mut inc()
+	func get() int
+}
+
+let global int = 1
+let frozen! int = 2
+let shared counter
+
+func pure(c counter, d! counter, n int) {
+	let local int = 3
+	local = 4
+	c.n = 5
+	c.get()
+
+	global = 5
+	frozen = 6
+	n = 7
+	d.n = 8
+	c.inc()
+	shared.n = 9
+	shared.inc()
+	c = shared
+}
+
+func impure(c counter, d! counter) mut {
+	let local counter
+	local.inc()
+	global = 5
+	shared.inc()
+	c.inc()
+
+	frozen = 6
+	d.inc()
+}
+
+func closures() mut {
+	let n int = 0
+	let pure = -> () { n = 1 }
+	let impure = -> () mut { n = 2 }
+	let reads = -> () int { n }
+}
+
+type holder {
+	let c counter
+}
+
+let outer holder = &holder{c = &counter{n = 0}}
+
+func fields(h holder, i! holder) {
+	h.c.inc()
+	i.c.inc()
+	outer.c.inc()
+	let l holder = &holder{c = &counter{n = 0}}
+	l.c.inc()
+}
+
+func uninitialized() mut {
+	let x! int
+	let y! int = 1
+	let a, b! int
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(17:2) cannot assign to global from a pure context: it is declared outside of the function",
+		"(18:2) cannot assign to frozen: it is immutable",
+		"(19:2) cannot assign to parameter n",
+		"(20:2) cannot assign to field n of immutable d",
+		"(21:2) cannot call mutable method inc of c from a pure context",
+		"(22:2) cannot assign to field n of shared from a pure context: it is declared outside of the function",
+		"(23:2) cannot call mutable method inc of shared from a pure context",
+		"(24:2) cannot assign to parameter c",
+		"(34:2) cannot assign to frozen: it is immutable",
+		"(35:2) cannot call mutable method inc on immutable d",
+		"(40:21) cannot assign to n from a pure context: it is declared outside of the function",
+		"(52:2) cannot call mutable method inc of h from a pure context",
+		"(53:2) cannot call mutable method inc on immutable i",
+		"(54:2) cannot call mutable method inc of outer from a pure context",
+		"(60:6) cannot declare immutable x without a value",
+		"(62:9) cannot declare immutable b without a value",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestPredeclared(t *testing.T) {
+	const src = `type celsius int
+
+let a int = 1
+let b int = a + 2
+let c float = 1.5
+let d = a < b
+let e = true && !d
+let f = -a
+let g celsius = 3
+let h = g * g
+let i string = "a"
+let j = i + "b"
+let k array[int]
+let l = k.len()
+let m result[int] = a
+
+func shadow() {
+	let true int = 1
+	let n int = true + 1
+}
+
+let e1 = a + c
+let e2 = i - i
+let e3 = c % c
+let e4 = !a
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(22:14) cannot use float as int: missing underlying int",
+		"(23:12) operator - not defined on i (type string has no method sub)",
+		"(24:12) operator % not defined on c (type float has no method mod)",
+		"(25:10) operator ! not defined on a (type int has no method not)",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+
+	types := map[string]string{
+		"b": "int",
+		"d": "bool",
+		"e": "bool",
+		"f": "int",
+		"h": "celsius",
+		"j": "string",
+		"l": "int",
+		"m": "result[int]",
+	}
+	for id, name := range types {
+		d := script.Scope.Get(id)
+		if (d == nil) || (d.Type().String() != name) {
+			t.Errorf("unexpected declaration for %v: %#v", id, d)
+		}
+	}
+}
+
+func TestShadowPredeclared(t *testing.T) {
+	const src = `let a int
+let b = bool(1)
+
+type int {
+	let n unit
+}
+
+func bool(v float) string { "" }
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := script.Scope.Get("a"); (d == nil) || (len(d.Type().Features) != 1) {
+		t.Fatalf("unexpected declaration for a: %#v", d)
+	}
+	if d := script.Scope.Get("b"); (d == nil) || (d.Type().String() != "string") {
+		t.Fatalf("unexpected declaration for b: %#v", d)
+	}
+}
+
+func TestRedeclared(t *testing.T) {
+	const src = `import "a/lib"
+
+type point {
+	let x int
+}
+
+type other {
+	let x int
+}
+
+func (p point) x() int { 1 }
+func (o other) x() int { 2 }
+
+let a int
+let b int
+
+func f(v int) {
+	let v int = 1
+}
+
+func locals() {
+	let c int = 2
+	if true {
+		let c int = 3
+	}
+	let c int = 4
+}
+
+func b() {}
+func f() {}
+type point {}
+let b int
+func (p point) x() int { 3 }
+func g(a int, a int) {}
+func [T any, T any] h() {}
+let lib int
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(26:6) c redeclared in this block; previous declaration at 22:6",
+		"(29:6) b redeclared in this block; previous declaration at 15:5",
+		"(30:6) f redeclared in this block; previous declaration at 17:6",
+		"(31:6) point redeclared in this block; previous declaration at 3:6",
+		"(32:5) b redeclared in this block; previous declaration at 15:5",
+		"(33:16) method point.x redeclared; previous declaration at 11:16",
+		"(34:15) a redeclared in this block; previous declaration at 34:8",
+		"(35:14) T redeclared in this block; previous declaration at 35:7",
+		"(36:5) lib redeclared in this block; previous declaration at 1:1",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	const src = `let count int
+let x int
+
+func example() {
+	let total int = cuont + 1
+	let y int = z
+	let b boool
+	let p = pirnt
+	let s = strin
+	let c cuont
+}
+
+func print() {}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(5:18) undefined: cuont (did you mean count?)",
+		"(6:14) undefined: z",
+		"(7:8) undefined: boool (did you mean bool?)",
+		"(8:10) undefined: pirnt (did you mean print?)",
+		"(9:10) undefined: strin",
+		"(10:8) undefined: cuont",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestFailedDeclaration(t *testing.T) {
+	const src = `type point {
+	let x, y int
+}
+
+let g int = "global"
+
+func example() int {
+	let a int = "a"
+	let b, c = 1
+	let p point = 3
+	let d = a + b
+	p.x = c
+	a = 2
+	g + d + undefined
+}
+
+func global() int { g }
+
+func local() int {
+	let a int = "a"
+	a
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(5:13) cannot use string as int: missing underlying int",
+		"(8:14) cannot use string as int: missing underlying int",
+		"(9:13) cannot destructure untyped int into 2 variables: not a tuple",
+		"(10:16) cannot use untyped int constant 3 as point",
+		"(14:10) undefined: undefined",
+		"(20:14) cannot use string as int: missing underlying int",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	const src = `type node {
+	let v int
+	let next node
+}
+
+type tree {
+	let v int
+	let children array[tree]
+	let parent -> () tree
+}
+
+type writer {
+	func write(string)
+}
+
+type [T, E any] box {
+	let val E
+}
+
+type [T, E any] chain {
+	let next chain[box[E]]
+}
+
+func main() mut {
+	let n node
+	let t tree
+	let w writer
+	let r result[int]
+	let o opt[int]
+	let b box[box[int]]
+	let bw box[writer]
+	let c chain[int]
+	let x = &node{v = 1}
+	let y = &tree{v = 1}
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(25:8) cannot declare n without a value: node has no zero value",
+		"(27:8) cannot declare w without a value: writer has no zero value",
+		"(28:8) cannot declare r without a value: result[int] has no zero value",
+		"(29:8) cannot declare o without a value: opt[int] has no zero value",
+		"(31:9) cannot declare bw without a value: box[writer] has no zero value",
+		"(32:8) cannot declare c without a value: chain[int] has no zero value",
+		"(33:10) missing field next in struct literal: node has no zero value",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestGenericZero(t *testing.T) {
+	const src = `type [T, E any] box {
+	let val E
+}
+
+func [T numeric] sum(a array[T]) T {
+	let s T
+	let i int = 0
+	for i < a.len() {
+		s = s + a[i]
+		i += 1
+	}
+	s
+}
+
+func [T any] wrap(v T) box[T] {
+	let b box[T]
+	let w T
+	b
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(6:8) cannot declare s without a value: T has no zero value",
+		"(16:8) cannot declare b without a value: box[T] has no zero value",
+		"(17:8) cannot declare w without a value: T has no zero value",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestRun(t *testing.T) {
+	const src = `type point {
+	let x, y int
+}
+
+let a int = 1
+let b, c int = (a, 2)
+let p point
+let q (int, bool) = (c, true)
+
+func example(n int) mut int {
+	let m int = n
+	let i, j = q
+	a = m
+	p.x = i
+	p.y = b
+	m = 5
+	m
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := stele.NewState()
+	script.Run(state)
+
+	var example stele.Func
+	for _, d := range script.Decls {
+		if f, ok := d.(stele.Func); ok && (f.Name == "example") {
+			example = f
+		}
+	}
+	frame := stele.NewFrame(nil, example.Slots)
+	frame.Slots[example.Slots-1] = stele.MakeInt(state.Descs.Intern(stele.Int), 3)
+	state.Call(example.Name, frame)
+	r := example.Body.Eval(state)
+	state.Return()
+
+	if r.Interface() != int64(5) {
+		t.Fatalf("unexpected result: %#v", r)
+	}
+	want := map[string]any{
+		"a": int64(3),
+		"b": int64(1),
+		"c": int64(2),
+	}
+	for id, v := range want {
+		if got := state.Globals[id]; got.Interface() != v {
+			t.Errorf("unexpected value of %v: %#v", id, got)
+		}
+	}
+	p := state.Globals["p"].Interface().(*stele.Struct)
+	x, _ := p.Field("x")
+	y, _ := p.Field("y")
+	if (x.Interface() != int64(2)) || (y.Interface() != int64(1)) {
+		t.Errorf("unexpected value of p: %#v", p)
+	}
+}
+
+func TestResolvedDescs(t *testing.T) {
+	const src = `type named {
+	func name() string
+}
+
+type point {
+	let x, y int
+	func name() string
+}
+
+func (p point) name() string { "point" }
+
+func parse(n int) result[int] { n }
+
+func total(v! any) result[int] {
+	let n = parse(2)?
+	let p = &point{x = n, y = 3}
+	let a array[int] = [p.x, p.y]
+	let t = (a[0], p.name)
+	let name = t[1]
+	let k int = switch v {
+		.(int) { v }
+		else { 0 }
+	}
+	let q! any = p
+	if q.(named) { k += q.name().len() }
+	t[0] + a[1] + k + name().len()
+}
+
+func main() int {
+	let r! = total(4)
+	switch r {
+		.(int) { r }
+		else { 0 }
+	}
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a table to intern types in, evaluating anything that the
+	// checker did not resolve the type of fails.
+	script.Descs = nil
+	state := stele.NewState()
+	state.Descs = nil
+	if err := script.Run(state); err != nil {
+		t.Fatal(err)
+	}
+	r, err := script.Call(state, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Interface() != int64(19) {
+		t.Fatalf("unexpected result: %#v", r)
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want any
+	}{
+		{
+			name: "Arithmetic",
+			src:  `func main() int { (7 + 3) * 2 - 10 / 3 % 2 }`,
+			want: int64(19),
+		},
+		{
+			name: "Float",
+			src:  `func main() float { let x float = 1.5; -x * 2.0 }`,
+			want: -3.0,
+		},
+		{
+			name: "BigInt",
+			src:  `func main() bigint { let x bigint = 1 << 70; x + 1 }`,
+			want: "1180591620717411303425",
+		},
+		{
+			name: "Comparison",
+			src:  `func main() bool { (1 < 2) && (2 >= 2) && !(3 <= 2) && (1 != 2) }`,
+			want: true,
+		},
+		{
+			name: "String",
+			src:  `func main() string { let s string = "ab" + "cd"; s + "e" }`,
+			want: "abcde",
+		},
+		{
+			name: "Switch",
+			src: `func classify(n int) string {
+	switch n {
+		< 0 { "negative" }
+		0 { "zero" }
+		else { "positive" }
+	}
+}
+func main() string { classify(-2) + classify(0) + classify(5) }`,
+			want: "negativezeropositive",
+		},
+		{
+			name: "Return",
+			src: `func first(n int) int {
+	let i int = 0
+	for {
+		if i * i > n { return i }
+		i += 1
+	}
+	0
+}
+func main() int { first(20) }`,
+			want: int64(5),
+		},
+		{
+			name: "Continue",
+			src: `func main() int {
+	let sum int = 0
+	let i int = 0
+	for i < 10 {
+		i += 1
+		if i % 2 == 0 { continue }
+		sum += i
+	}
+	sum
+}`,
+			want: int64(25),
+		},
+		{
+			name: "Closure",
+			src: `func apply(x int, f -> (int) int) int { f(x) }
+func main() int {
+	let n int = 10
+	let add = -> (x int) int { x + n }
+	3 |> apply(add)
+}`,
+			want: int64(13),
+		},
+		{
+			name: "Recursion",
+			src: `func fib(n int) int {
+	if n < 2 { n } else { fib(n - 1) + fib(n - 2) }
+}
+func main() int { fib(15) }`,
+			want: int64(610),
+		},
+		{
+			name: "Struct",
+			src: `type point {
+	let x, y int
+	func sum() int
+}
+func (p point) sum() int { p.x + p.y }
+func main() int {
+	let p = &point{x = 3}
+	p.y = 4
+	p.sum() + (p.x, p.y)[0]
+}`,
+			want: int64(10),
+		},
+		{
+			name: "Array",
+			src: `func main() int {
+	let a array[int] = [1, 2, 3]
+	a[1] += 10
+	a.append(4)
+	a[1] + a.len()
+}`,
+			want: int64(16),
+		},
+		{
+			name: "Assert",
+			src: `type named {
+	func name() string
+}
+
+type point {
+	let x, y int
+	func name() string
+}
+
+func (p point) name() string { "point" }
+
+func describe(v! any) string {
+	if v.(named) { v.name() } else { "unknown" }
+}
+
+let none int = 0
+
+func size(v! any) int {
+	switch v {
+		.(int) { v + 1 }
+		.(string) { v.len() }
+		else { none }
+	}
+}
+
+func main() string {
+	let p! point = &point{x = 1}
+	let n! named = p
+	let a! any = n
+	let s int = size(41) + size("abc") + size(p)
+	describe(a) + describe(3) + if s == 45 { "45" } else { "?" }
+}`,
+			want: "pointunknown45",
+		},
+		{
+			name: "MethodScope",
+			src: `type example int
+type other int
+
+func (e example) double() int { e + e }
+func [T int] (v T) double() int { v * 3 }
+
+func main() int {
+	let v int = 3
+	let o other = 4
+	let f = example(v).double
+	f() * 100 + o.double() + other(v).double()
+}`,
+			want: int64(621),
+		},
+		{
+			name: "CopyOnAssign",
+			src: `type point {
+	let x, y int
+}
+
+func bump(p point) { p.x = p.x + 1 }
+
+func alias(a array[int], b! array[int]) mut int {
+	a[0] = 9
+	b[0]
+}
+
+func main() mut int {
+	let frozen! = &point{x = 1}
+	let m = frozen
+	m.x = 10
+	bump(m)
+	bump(frozen)
+
+	let a! = [frozen]
+	let b = a
+	let e = b[0]
+	e.x = 100
+
+	let arr array[int] = [1, 2]
+	let fixed! = arr
+	arr[0] = 5
+	arr.append(3)
+	let before = alias(arr, arr)
+
+	frozen.x + m.x * 10 + a[0].x * 1000 + b[0].x * 10000 + fixed[0] * 10000000 + fixed.len() * 100000000 + before * 1000000000 + arr[0] * 10000000000
+}`,
+			want: int64(95211001111),
+		},
+		{
+			name: "Closures",
+			src: `func counter() -> () mut int {
+	let n int = 0
+	-> () mut int { n += 1; n }
+}
+
+func apply(f -> (int) int, x int) int { f(x) }
+
+func main() mut int {
+	let base int = 10
+	let add = -> (x int) int { x + base }
+	let c = counter()
+	c()
+	c()
+	let d = counter()
+	d()
+	base = 100
+
+	let fs array[-> () int]
+	let i int = 0
+	for i < 3 {
+		let x int = i
+		fs.append(-> () int { x + i })
+		i += 1
+	}
+
+	c() * 10000 + d() * 1000 + apply(add, 1) + fs[0]() * 100 + fs[1]() * 10 + fs[2]()
+}`,
+			want: int64(32446),
+		},
+		{
+			name: "LoopClosures",
+			src: `func main() mut int {
+	let fs array[-> () int]
+	let i int = 0
+	for i < 6 {
+		let x int = i * 2
+		i += 1
+		if i == 2 { continue }
+		let inc = -> () mut int { x += 1; x }
+		inc()
+		fs.append(-> () int { x })
+		if i == 5 { break }
+	}
+
+	let gs array[-> () int]
+	let j int = 0
+	for j < 2 {
+		let k int = 0
+		for k < 2 {
+			let y int = j * 2 + k
+			gs.append(-> () int { y })
+			k += 1
+		}
+		j += 1
+	}
+
+	i * 100000000 + fs[0]() * 10000000 + fs[1]() * 1000000 + fs[2]() * 100000 + fs[3]() * 10000 +
+		gs[0]() * 1000 + gs[1]() * 100 + gs[2]() * 10 + gs[3]()
+}`,
+			want: int64(515790123),
+		},
+		{
+			name: "RecursiveZero",
+			src: `type tree {
+	let v int
+	let children array[tree]
+	let parent -> () tree
+}
+
+type [T, E any] box {
+	let val E
+}
+
+func main() mut int {
+	let t tree
+	let c array[tree] = t.children
+	c.append(&tree{v = 2})
+	let p tree = t.parent()
+	let b box[box[int]]
+	(b.val.val + 3) * 1000 + c.len() * 10 + c[0].v * 100 + t.v + p.v + p.children.len() + 1
+}`,
+			want: int64(3211),
+		},
+		{
+			name: "GenericLocal",
+			src: `func [T numeric] sum(a array[T]) T {
+	let s T = a[0]
+	let i int = 1
+	for i < a.len() {
+		s = s + a[i]
+		i += 1
+	}
+	s
+}
+
+func main() mut float {
+	let a array[float]
+	a.append(1.5)
+	a.append(2.25)
+	let b array[int]
+	b.append(3)
+	b.append(4)
+	let n float = if sum(b) == 7 { 7.0 } else { 0.0 }
+	sum(a) * 100.0 + n
+}`,
+			want: 382.0,
+		},
+		{
+			name: "NumericConstraints",
+			src: `func double(v numeric) numeric { v * 2 }
+func [T numeric] triple(v T) T { v * 3 }
+func [T anyint] half(v T) T { v / 2 }
+
+func main() float {
+	let f float = 1.5
+	let r! = double(f)
+	let d float = if r.(float) { r } else { 0.0 }
+	let n bigint = triple(bigint(7))
+	let i int = 9
+	d * 100.0 + triple(f) + if n == 21 { 1000.0 } else { 0.0 } + if half(i) == 4 { 10000.0 } else { 0.0 }
+}`,
+			want: 11304.5,
+		},
+		{
+			name: "CompoundIndex",
+			src: `type inner {
+	let x int
+}
+
+type outer {
+	let in inner
+	let a array[int]
+}
+
+let calls int = 0
+
+func idx() mut int {
+	calls += 1
+	1
+}
+
+func arr() mut array[int] {
+	calls += 10
+	[1, 2, 3]
+}
+
+func main() mut int {
+	let o outer
+	o.a = [1, 2, 3]
+	o.a[idx()] += 5
+	arr()[idx()] *= 2
+	o.in.x = 5
+	o.in.x += 2
+	o.a[1] * 1000 + calls * 10 + o.in.x
+}`,
+			want: int64(7127),
+		},
+		{
+			name: "StackOverflow",
+			src: `func down(n int) result[int] {
+	let r int = down(n + 1)?
+	r
+}
+
+func main() string {
+	let r! result[int] = down(0)
+	switch r {
+		.(int) { "no error" }
+		.(error) { r.error() }
+	}
+}`,
+			want: "stack overflow: more than 10000 calls",
+		},
+		{
+			name: "NaN",
+			src: `func bit(b bool, n int) int { if b { n } else { 0 } }
+
+func main() int {
+	let zero float = 0.0
+	let one float = 1.0
+	let nan float = zero / zero
+	let i int = 3
+	bit(nan >= one, 1) + bit(nan <= one, 2) + bit(nan < one, 4) + bit(nan > one, 8) + bit(nan == nan, 16) + bit(nan != nan, 32) + bit(one <= one, 64) + bit(one >= zero, 128) + bit(i <= 3, 256) + bit(i >= 4, 512)
+}`,
+			want: int64(480),
+		},
+		{
+			name: "Errors",
+			src: `type failure {
+	let msg string
+	func error() string
+}
+
+func (f failure) error() string { f.msg }
+
+func get(a array[int], i int) result[int] { a[i] }
+
+func at(a array[int], i int) int { a[i] }
+
+func check(n int) result[int] {
+	if n > 2 {
+		return &failure{msg = "too big"}
+	}
+	n
+}
+
+func sum(a array[int]) result[int] {
+	let total int = get(a, 0)? + at(a, 1)
+	check(total)? * 10
+}
+
+func describe(r! result[int]) string {
+	switch r {
+		.(int) { "ok" }
+		.(failure) { "failure: " + r.error() }
+		.(error) { r.error() }
+	}
+}
+
+func main() string {
+	let a array[int] = [1, 2]
+	let b array[int] = [1]
+	let c array[int] = [1, 1]
+	describe(sum(a)) + ";" + describe(sum(b)) + ";" + describe(sum(c))
+}`,
+			want: "failure: too big;index 1 out of range with length 1;ok",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := run(t, test.src)
+			got := r.Interface()
+			if s, ok := got.(fmt.Stringer); ok {
+				got = s.String()
+			}
+			if got != test.want {
+				t.Fatalf("unexpected result: %#v", r.Interface())
+			}
+		})
+	}
+}
+
+func TestStatementErrors(t *testing.T) {
+	const src = `type point {
+	let x, y int
+}
+
+func example(a! array[int]) {
+	break
+	let p = &point{x = 1, z = 2, x = 3}
+	let e = [1, "a"]
+	let f = []
+	a[0] = 1
+	let g = -> (x) { x }
+	for 1 { continue }
+	let h = parse()?
+}
+func parse() result[int] { 1 }
+func bad(n int) result[int] { n? }
+let v = parse()?
+
+type line {
+	let start point
+}
+
+func fields(l! line) {
+	l.start.x = 1
+	parse().x = 1
+	l.start.z = 2
+}
+`
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = File(file)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected errors but got %v", err)
+	}
+
+	want := []string{
+		"(6:2) break is not in a loop",
+		"(7:24) unknown field z in struct literal of type point",
+		"(7:31) duplicate field x in struct literal",
+		"(8:10) cannot use untyped int constant 1 as string",
+		"(9:10) cannot infer element type of empty array literal",
+		"(10:2) cannot call mutable method set on immutable a",
+		"(11:14) missing type for parameter",
+		"(12:6) cannot use untyped int as bool: missing underlying bool",
+		"(13:17) cannot use ? in a function that returns unit: it is not a result",
+		"(16:32) cannot use ? on int: it is not a result",
+		"(17:16) cannot use ? outside of a function",
+		"(24:2) cannot assign to field start.x of immutable l",
+		"(25:2) cannot assign to field x of call: only fields of variables can be assigned to",
+		"(26:10) l.start.z undefined (type point has no field z)",
+	}
+	var got []string
+	for _, err := range list {
+		got = append(got, err.Error())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected errors:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	file, err := parser.Parse(strings.NewReader(`func main() int {
+	let a array[int] = [1]
+	a[3]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := stele.NewState()
+	if err := script.Run(state); err != nil {
+		t.Fatal(err)
+	}
+	_, err = script.Call(state, "main")
+	var rerr *stele.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a runtime error but got %v", err)
+	}
+	if (rerr.Pos.Line != 3) || !strings.Contains(rerr.Msg, "out of range") {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+	if len(state.Stack) != 0 {
+		t.Fatalf("call stack was not unwound: %v", state.Stack)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	file, err := parser.Parse(strings.NewReader(`func down(n int) int {
+	down(n + 1) + 1
+}
+func main() int { down(0) }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := stele.NewState()
+	if err := script.Run(state); err != nil {
+		t.Fatal(err)
+	}
+	_, err = script.Call(state, "main")
+	var rerr *stele.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a runtime error but got %v", err)
+	}
+	if (rerr.Pos.Line != 2) || !strings.Contains(rerr.Msg, "stack overflow") {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+	if len(state.Stack) != 0 {
+		t.Fatalf("call stack was not unwound: %v", state.Stack)
+	}
+}
+
+func TestRot13(t *testing.T) {
+	src, err := os.ReadFile("../testdata/rot13.stele")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := run(t, string(src))
+	var out []byte
+	for _, v := range r.Interface().(*stele.Slice).Elems {
+		out = append(out, v.Byte())
+	}
+	if string(out) != "Guvf vf na rknzcyr." {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+// run checks and runs src and returns the result of calling its main
+// function.
+func run(t *testing.T, src string) stele.Value {
+	t.Helper()
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := stele.NewState()
+	if err := script.Run(state); err != nil {
+		t.Fatal(err)
+	}
+	r, err := script.Call(state, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
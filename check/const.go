@@ -0,0 +1,307 @@
+package check
+
+import (
+	"go/constant"
+	"go/token"
+	"math/big"
+	"slices"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+// constOps maps Stele's operators to the go/constant equivalents that
+// are used to fold them.
+var constOps = map[scanner.Type]token.Token{
+	scanner.PLUS:     token.ADD,
+	scanner.MINUS:    token.SUB,
+	scanner.MULT:     token.MUL,
+	scanner.DIV:      token.QUO,
+	scanner.MOD:      token.REM,
+	scanner.BITAND:   token.AND,
+	scanner.BITOR:    token.OR,
+	scanner.BITNOT:   token.XOR,
+	scanner.LSHIFT:   token.SHL,
+	scanner.RSHIFT:   token.SHR,
+	scanner.EQUAL:    token.EQL,
+	scanner.NOTEQUAL: token.NEQ,
+	scanner.LT:       token.LSS,
+	scanner.LE:       token.LEQ,
+	scanner.GT:       token.GTR,
+	scanner.GE:       token.GEQ,
+	scanner.NOT:      token.NOT,
+	scanner.AND:      token.LAND,
+	scanner.OR:       token.LOR,
+}
+
+func (c *checker) basicLit(lit *ast.BasicLit) stele.Expr {
+	var v constant.Value
+	switch val := lit.Value.(type) {
+	case int64:
+		v = constant.MakeInt64(val)
+	case rune:
+		v = constant.MakeInt64(int64(val))
+	case float64:
+		v = constant.MakeFloat64(val)
+	case *big.Int, *big.Float:
+		v = constant.Make(val)
+	case string:
+		// Strings only have the one type, so their constants are never
+		// untyped.
+		return c.constant(stele.Const{Val: constant.MakeString(val), T: stele.String})
+	default:
+		c.errorf(lit.Pos(), "%v literals are not supported yet", lit.Kind)
+		return nil
+	}
+	return stele.UntypedConst(v)
+}
+
+// constant returns x, resolved so that it does not have to be when it
+// is evaluated if it has been given a type.
+func (c *checker) constant(x stele.Const) stele.Const {
+	if x.T.Untyped() {
+		return x
+	}
+	return x.Resolve(c.descs)
+}
+
+// foldUnary folds a unary operation on a constant.
+func (c *checker) foldUnary(expr *ast.Unary, x stele.Const, op token.Token) stele.Expr {
+	r, err := stele.FoldUnary(op, x)
+	if err != nil {
+		c.errorf(expr.OpPos, "%v", err)
+		return nil
+	}
+	return c.constant(r)
+}
+
+// foldBinary folds a binary operation on a pair of constants.
+func (c *checker) foldBinary(expr *ast.Binary, x stele.Const, op token.Token, y stele.Const) stele.Expr {
+	r, err := stele.Fold(x, op, y)
+	if err != nil {
+		c.errorf(expr.OpPos, "%v", err)
+		return nil
+	}
+	return c.constant(r)
+}
+
+// convert checks that x can be used as a value of type to. Untyped
+// constants are given a type, including those inside of tuple
+// literals and those that are the results of the branches of an if,
+// switch or block, and are checked to make sure that they fit in it.
+// Other
+// expressions that are not already of type to are converted to it at
+// run time, which adds it to the chain of types that their value has
+// had.
+func (c *checker) convert(pos scanner.Pos, x stele.Expr, to stele.Type) (stele.Expr, bool) {
+	if !to.Valid() {
+		return x, true
+	}
+
+	switch x := x.(type) {
+	case stele.Const:
+		if !x.T.Untyped() {
+			break
+		}
+		if to.Number() {
+			r, err := x.Convert(to)
+			if err != nil {
+				c.errorf(pos, "cannot use %v as %v: %v", x.Val, to, err)
+				return nil, false
+			}
+			return c.constant(r), true
+		}
+
+		// Any number type that satisfies to will do, so one is picked.
+		if r, ok := x.Default(to); ok {
+			return c.constant(r), true
+		}
+		c.errorf(pos, "cannot use %v constant %v as %v", x.T, x.Val, to)
+		return nil, false
+
+	case stele.Tuple:
+		elems, ok := to.Tuple()
+		if !ok || (len(elems) != len(x.Elems)) {
+			break
+		}
+
+		r := stele.Tuple{Elems: make([]stele.Expr, 0, len(elems)), T: to, Desc: c.descs.Intern(to)}
+		for i, e := range x.Elems {
+			ce, eok := c.convert(pos, e, elems[i])
+			r.Elems = append(r.Elems, ce)
+			ok = ok && eok
+		}
+		return r, ok
+
+	case stele.ArrayLit:
+		elem, ok := to.Elem()
+		if !ok {
+			break
+		}
+
+		r := stele.ArrayLit{Elems: make([]stele.Expr, 0, len(x.Elems)), T: to, Desc: c.descs.Intern(to)}
+		for _, e := range x.Elems {
+			ce, eok := c.convert(pos, e, elem)
+			r.Elems = append(r.Elems, ce)
+			ok = ok && eok
+		}
+		return r, ok
+
+	case stele.If, stele.Switch, stele.Block:
+		if !hasUntyped(x) {
+			break
+		}
+		r, ok := c.convertBranches(pos, x, to)
+		if !ok {
+			return nil, false
+		}
+		return c.convert(pos, r, to)
+	}
+
+	if !c.assignable(pos, x.Type(), to) {
+		return x, false
+	}
+	if to.Param || (x.Type().String() == to.String()) {
+		return x, true
+	}
+	return stele.Convert{X: x, T: to, Desc: c.descs.Intern(to)}, true
+}
+
+// convertBranches converts the results of the branches of x, an if,
+// switch or block, to the type to, and recomputes the type of x from
+// them. Branches that do not result in anything, such as a missing
+// else, still result in unit.
+func (c *checker) convertBranches(pos scanner.Pos, x stele.Expr, to stele.Type) (stele.Expr, bool) {
+	switch x := x.(type) {
+	case stele.Block:
+		last, ok := result(x)
+		if !ok {
+			return x, true
+		}
+		r, ok := c.convert(pos, last, to)
+		if !ok {
+			return nil, false
+		}
+		x.Stmts = slices.Clone(x.Stmts)
+		x.Stmts[len(x.Stmts)-1] = r
+		x.T = r.Type()
+		return x, true
+
+	case stele.If:
+		body, ok := c.convertBranches(pos, x.Body, to)
+		if !ok {
+			return nil, false
+		}
+		x.Body = body.(stele.Block)
+		types := []stele.Type{x.Body.T}
+		if x.Else == nil {
+			types = append(types, stele.Unit)
+		} else {
+			x.Else, ok = c.convertBranches(pos, x.Else, to)
+			if !ok {
+				return nil, false
+			}
+			types = append(types, x.Else.Type())
+		}
+		x.T = stele.Oneof(types...)
+		return x, true
+
+	case stele.Switch:
+		// A switch that is not exhaustive also results in unit if no
+		// case matches, which is already in its type if so.
+		var types []stele.Type
+		if slices.ContainsFunc(append(x.T.Members(), x.T), isUnit) {
+			types = append(types, stele.Unit)
+		}
+		x.Cases = slices.Clone(x.Cases)
+		for i, sc := range x.Cases {
+			body, ok := c.convertBranches(pos, sc.Body, to)
+			if !ok {
+				return nil, false
+			}
+			x.Cases[i].Body = body.(stele.Block)
+			types = append(types, x.Cases[i].Body.T)
+		}
+		x.T = stele.Oneof(types...)
+		return x, true
+	}
+
+	return c.convert(pos, x, to)
+}
+
+// result returns the expression that the block b results in, if it
+// results in anything other than unit.
+func result(b stele.Block) (stele.Expr, bool) {
+	if (len(b.Stmts) == 0) || isUnit(b.T) {
+		return nil, false
+	}
+	x, ok := b.Stmts[len(b.Stmts)-1].(stele.Expr)
+	return x, ok
+}
+
+// isUnit returns true if t is unit.
+func isUnit(t stele.Type) bool {
+	return t.String() == stele.Unit.String()
+}
+
+// hasUntyped returns true if x is an untyped constant, or if it is a
+// literal or a branching expression that one is an element or result
+// of.
+func hasUntyped(x stele.Expr) bool {
+	switch x := x.(type) {
+	case stele.Const:
+		return x.T.Untyped()
+	case stele.Tuple:
+		return slices.ContainsFunc(x.Elems, hasUntyped)
+	case stele.ArrayLit:
+		return slices.ContainsFunc(x.Elems, hasUntyped)
+	case stele.Block:
+		r, ok := result(x)
+		return ok && hasUntyped(r)
+	case stele.If:
+		return hasUntyped(x.Body) || ((x.Else != nil) && hasUntyped(x.Else))
+	case stele.Switch:
+		return slices.ContainsFunc(x.Cases, func(sc stele.Case) bool { return hasUntyped(sc.Body) })
+	}
+	return false
+}
+
+// untyped reports an error if x is, or is a tuple or array literal
+// containing or a branching expression resulting in, an untyped
+// constant, as the type of what is described by what can then not be
+// decided.
+func (c *checker) untyped(pos scanner.Pos, x stele.Expr, what string) bool {
+	switch x := x.(type) {
+	case stele.Const:
+		if x.T.Untyped() {
+			c.errorf(pos, "cannot infer type of %v from %v constant %v", what, x.T, x.Val)
+			return true
+		}
+	case stele.Tuple:
+		for _, e := range x.Elems {
+			if c.untyped(pos, e, what) {
+				return true
+			}
+		}
+	case stele.ArrayLit:
+		for _, e := range x.Elems {
+			if c.untyped(pos, e, what) {
+				return true
+			}
+		}
+	case stele.Block:
+		if r, ok := result(x); ok {
+			return c.untyped(pos, r, what)
+		}
+	case stele.If:
+		return c.untyped(pos, x.Body, what) || ((x.Else != nil) && c.untyped(pos, x.Else, what))
+	case stele.Switch:
+		for _, sc := range x.Cases {
+			if c.untyped(pos, sc.Body, what) {
+				return true
+			}
+		}
+	}
+	return false
+}
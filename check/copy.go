@@ -0,0 +1,166 @@
+package check
+
+import (
+	"slices"
+
+	"deedles.dev/stele"
+)
+
+// Assigning a value from an immutable variable to a mutable one, or
+// the other way round, copies it, so that nothing can be changed
+// through a variable that it is immutable in. Mutable arguments are
+// passed by reference, so arguments from immutable variables are
+// copied in case the parameter is mutable, and functions copy their
+// immutable parameters themselves when they are called. Values that
+// are returned are copied if they are kept in any variable at all, as
+// what they are returned to could be of either kind.
+
+// root returns the variable that x is the value of, or that it is a
+// field or element of.
+func root(x stele.Expr) (stele.Ident, bool) {
+	for {
+		switch e := x.(type) {
+		case stele.Ident:
+			return e, true
+		case stele.Selector:
+			x = e.X
+		case stele.Index:
+			x = e.X
+		case stele.TupleIndex:
+			x = e.X
+		case stele.Narrow:
+			x = e.X
+		case stele.Try:
+			x = e.X
+		case stele.Convert:
+			x = e.X
+		default:
+			return stele.Ident{}, false
+		}
+	}
+}
+
+// copied returns x as it is assigned to a variable that is mutable if
+// mut is true, copying it if it is kept in a variable that is not, or
+// the other way round.
+func (c *checker) copied(x stele.Expr, mut bool) stele.Expr {
+	return copyIf(x, func(x stele.Expr) bool {
+		id, ok := root(x)
+		if !ok {
+			return branches(x)
+		}
+		let, ok := c.scope.Get(id.ID).(stele.Let)
+		return ok && (let.Mutable() != mut)
+	})
+}
+
+// copiedElems returns x, a tuple that is destructured into variables
+// that are mutable if the corresponding elements of mut are true, as
+// it is assigned to them.
+func (c *checker) copiedElems(x stele.Expr, mut []bool) stele.Expr {
+	if lit, ok := x.(stele.Tuple); ok {
+		lit.Elems = slices.Clone(lit.Elems)
+		for i, e := range lit.Elems {
+			lit.Elems[i] = c.copied(e, mut[i])
+		}
+		return lit
+	}
+	for _, m := range mut {
+		if r, ok := c.copied(x, m).(stele.Copy); ok {
+			return r
+		}
+	}
+	return x
+}
+
+// returned returns x as it is returned from a function, copying it if
+// it is kept in a variable. The variables that were declared in the
+// function may no longer be in scope, so any variable will do.
+func (c *checker) returned(x stele.Expr) stele.Expr {
+	return copyIf(x, func(x stele.Expr) bool {
+		_, ok := root(x)
+		return ok || branches(x)
+	})
+}
+
+// receiver returns sel, a method selected from a value, with the value
+// copied if it is kept in an immutable variable and the method may have
+// a mutable receiver. The methods of the predeclared types can not
+// change immutable receivers, so they never need it.
+func (c *checker) receiver(sel stele.Selector) stele.Selector {
+	if sel.Recv != "" {
+		infos := c.attached(sel.X.Type(), sel.Name)
+		if recv := infos[0].decl.Recv; (len(recv.Names) > 0) && recv.Names[0].IsImmutable() {
+			return sel
+		}
+	} else if !c.declared(sel.X.Type()) {
+		return sel
+	}
+	sel.X = c.copied(sel.X, true)
+	return sel
+}
+
+// copyIf returns x wrapped in a Copy if need returns true for it. The
+// elements of literals are checked individually instead, as a literal
+// is always a new value itself.
+func copyIf(x stele.Expr, need func(stele.Expr) bool) stele.Expr {
+	if !copyable(x.Type()) {
+		return x
+	}
+
+	switch e := x.(type) {
+	case stele.Tuple:
+		e.Elems = slices.Clone(e.Elems)
+		for i, elem := range e.Elems {
+			e.Elems[i] = copyIf(elem, need)
+		}
+		return e
+	case stele.ArrayLit:
+		e.Elems = slices.Clone(e.Elems)
+		for i, elem := range e.Elems {
+			e.Elems[i] = copyIf(elem, need)
+		}
+		return e
+	case stele.StructLit:
+		e.Fields = slices.Clone(e.Fields)
+		for i, f := range e.Fields {
+			e.Fields[i].Val = copyIf(f.Val, need)
+		}
+		return e
+	case stele.Convert:
+		e.X = copyIf(e.X, need)
+		return e
+	}
+
+	if need(x) {
+		return stele.Copy{X: x}
+	}
+	return x
+}
+
+// branches returns true if x is one of several expressions, any of
+// which could be kept in a variable, such as an if.
+func branches(x stele.Expr) bool {
+	switch x.(type) {
+	case stele.If, stele.Switch, stele.Block:
+		return true
+	}
+	return false
+}
+
+// copyable returns true if values of type t could need to be copied.
+// Numbers, bools, strings and functions can not be changed in place,
+// so they never do.
+func copyable(t stele.Type) bool {
+	if !t.Valid() || t.Number() {
+		return false
+	}
+	if _, ok := t.Func(); ok {
+		return false
+	}
+	switch t.String() {
+	case stele.Unit.String(), stele.Bool.String(), stele.String.String():
+		return false
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"deedles.dev/stele/scanner"
+)
+
+// Error is an error found while checking.
+type Error struct {
+	Pos scanner.Pos
+	Msg string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("(%v) %v", err.Pos, err.Msg)
+}
+
+// ErrorList is a list of errors found while checking, in the order in
+// which they were found.
+type ErrorList []*Error
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	}
+
+	var sb strings.Builder
+	for i, err := range list {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Err returns list as an error if it is not empty, or nil if it is.
+func (list ErrorList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
@@ -0,0 +1,341 @@
+package check
+
+import (
+	"go/constant"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+func (c *checker) selector(sel *ast.Selector) stele.Expr {
+	x := c.expr(sel.X)
+	if x == nil {
+		return nil
+	}
+
+	t := x.Type()
+	if f, ok := t.Feature(stele.LetFeature, sel.Sel.Name); ok {
+		return stele.Selector{X: x, Name: sel.Sel.Name, T: f.Return}
+	}
+	if m, ok := c.methodSelector(x, sel); ok {
+		if m == nil {
+			return nil
+		}
+		return c.receiver(*m)
+	}
+	if f, ok := t.Feature(stele.FuncFeature, sel.Sel.Name); ok {
+		mt := stele.FuncOf(f)
+		return c.receiver(stele.Selector{X: x, Name: sel.Sel.Name, T: mt, Desc: c.descs.Intern(mt)})
+	}
+
+	c.errorf(sel.Sel.Pos(), "%v.%v undefined (type %v has no field or method %v)", describeFunc(sel.X), sel.Sel.Name, t, sel.Sel.Name)
+	return nil
+}
+
+// cond checks the condition of an if or for, which must be a bool.
+func (c *checker) cond(expr ast.Expr) stele.Expr {
+	x := c.expr(expr)
+	if x == nil {
+		return nil
+	}
+	if !c.assignable(expr.Pos(), x.Type(), stele.Bool) {
+		return nil
+	}
+	return x
+}
+
+func (c *checker) ifExpr(expr *ast.If) stele.Expr {
+	cond := c.cond(expr.Cond)
+	var body stele.Block
+	if a, ok := cond.(stele.TypeAssert); ok {
+		body = c.narrowed(a.X, a.Assert, expr.Cond.Pos(), expr.Body)
+	} else {
+		body = c.block(expr.Body)
+	}
+	if cond == nil {
+		return nil
+	}
+
+	x := stele.If{Cond: cond, Body: body}
+	types := []stele.Type{body.T}
+	switch e := expr.Else.(type) {
+	case nil:
+		// Without an else, nothing is returned if the condition is
+		// false.
+		types = append(types, stele.Unit)
+	default:
+		x.Else = c.expr(e)
+		if x.Else == nil {
+			return nil
+		}
+		types = append(types, x.Else.Type())
+	}
+
+	x.T = stele.Oneof(types...)
+	return x
+}
+
+func (c *checker) switchExpr(expr *ast.Switch) stele.Expr {
+	var x stele.Switch
+	if expr.Tag != nil {
+		x.Tag = c.expr(expr.Tag)
+		if x.Tag == nil {
+			return nil
+		}
+	}
+
+	ok := true
+	var types []stele.Type
+	var asserts []stele.Type
+	var hasElse bool
+	for _, cc := range expr.Cases {
+		sc := stele.Case{Else: cc.Else, Op: cc.Op}
+		switch {
+		case cc.Else:
+			hasElse = true
+
+		case cc.Type != nil:
+			if x.Tag == nil {
+				c.errorf(cc.Type.Pos(), "type case in switch without a tag")
+				ok = false
+				break
+			}
+			t, tok := c.typeExpr(cc.Type)
+			sc.Assert = t
+			asserts = append(asserts, t)
+			ok = ok && tok
+			if tok {
+				sc.Desc = c.descs.Intern(t)
+				sc.Body = c.narrowed(x.Tag, t, cc.Type.Pos(), cc.Body)
+			}
+
+		case x.Tag == nil:
+			sc.Value = c.cond(cc.Value)
+			ok = ok && (sc.Value != nil)
+
+		default:
+			v := c.expr(cc.Value)
+			if (v != nil) && v.Type().Untyped() {
+				v, _ = c.convert(cc.Value.Pos(), v, x.Tag.Type())
+			}
+			sc.Value = v
+			ok = ok && (v != nil)
+		}
+
+		if !sc.Assert.Valid() {
+			sc.Body = c.block(cc.Body)
+		}
+		types = append(types, sc.Body.T)
+		x.Cases = append(x.Cases, sc)
+	}
+	if !ok {
+		return nil
+	}
+
+	if !hasElse && !c.exhaustive(expr, x.Tag, asserts) {
+		// If nothing matches, the switch returns unit.
+		types = append(types, stele.Unit)
+	}
+
+	x.T = stele.Oneof(types...)
+	return x
+}
+
+// narrowed checks the body of an if or switch case that only runs if
+// x can be asserted to t, which it does at pos. If x is an immutable
+// variable, it is declared again in a block around the body as its
+// value asserted to t, so that the body can use it as a t.
+func (c *checker) narrowed(x stele.Expr, t stele.Type, pos scanner.Pos, body *ast.Block) stele.Block {
+	id, ok := x.(stele.Ident)
+	if !ok {
+		return c.block(body)
+	}
+	let, ok := c.lookup(id.ID).(stele.Let)
+	if !ok || let.Mutable() {
+		return c.block(body)
+	}
+
+	scope := c.scope
+	defer func() { c.scope = scope }()
+	c.scope = c.scope.Block()
+
+	// x was resolved outside of the new block, so it is resolved again
+	// from inside of it before it is shadowed.
+	id.Ref = c.ref(id.ID)
+	c.declare(stele.Let{Name: let.Name, T: t}, pos)
+	assign := &stele.Assign{ID: id.ID, Val: stele.Narrow{X: id, T: t, Desc: c.descs.Intern(t)}}
+	c.resolve(assign)
+
+	inner := c.block(body)
+	return stele.Block{
+		Stmts: []stele.Stmt{assign, inner},
+		Pos:   []scanner.Pos{pos, body.Pos()},
+		Slots: c.scope.Len(),
+		T:     inner.T,
+	}
+}
+
+// exhaustive returns true if a switch without an else is known to
+// always match one of its cases. This is only the case for a switch on
+// a value of a oneof type which has a type case for every member. If
+// such a switch is not exhaustive, a warning listing the missing
+// members is reported.
+func (c *checker) exhaustive(expr *ast.Switch, tag stele.Expr, asserts []stele.Type) bool {
+	if (tag == nil) || (len(asserts) == 0) || (len(asserts) != len(expr.Cases)) {
+		return false
+	}
+
+	members := tag.Type().Members()
+	if len(members) == 0 {
+		return false
+	}
+
+	var missing []stele.Type
+	for _, m := range members {
+		if !coveredBy(m, asserts) {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) == 0 {
+		return true
+	}
+
+	c.warnf(expr.Switch, "switch on %v is not exhaustive: missing %v", tag.Type(), stele.Oneof(missing...))
+	return false
+}
+
+func coveredBy(t stele.Type, asserts []stele.Type) bool {
+	for _, a := range asserts {
+		if t.Satisfies(a) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) typeAssert(expr *ast.TypeAssert) stele.Expr {
+	x := c.expr(expr.X)
+	t, ok := c.typeExpr(expr.Type)
+	if (x == nil) || !ok {
+		return nil
+	}
+	return stele.TypeAssert{X: x, Assert: t, Desc: c.descs.Intern(t)}
+}
+
+// try checks a use of the ? operator. The members of X's type that are
+// errors are returned early by the function that it is in, which must
+// return a result that they can be values of, and the rest are its
+// type.
+func (c *checker) try(expr *ast.Try) stele.Expr {
+	x := c.expr(expr.X)
+	if x == nil {
+		return nil
+	}
+
+	var vals, errs []stele.Type
+	for _, m := range x.Type().Members() {
+		if m.Satisfies(stele.Error) {
+			errs = append(errs, m)
+			continue
+		}
+		vals = append(vals, m)
+	}
+	if (len(errs) == 0) || (len(vals) == 0) {
+		c.errorf(expr.Question, "cannot use ? on %v: it is not a result", x.Type())
+		return nil
+	}
+
+	switch {
+	case c.locals == nil:
+		c.errorf(expr.Question, "cannot use ? outside of a function")
+		return nil
+	case !c.ret.Fallible():
+		c.errorf(expr.Question, "cannot use ? in a function that returns %v: it is not a result", c.ret)
+		return nil
+	}
+	for _, e := range errs {
+		if !e.Satisfies(c.ret) {
+			c.errorf(expr.Question, "cannot return %v from a function that returns %v", e, c.ret)
+			return nil
+		}
+	}
+
+	t := stele.Oneof(vals...)
+	return stele.Try{X: x, T: t, Ret: c.ret, Desc: c.descs.Intern(t), RetDesc: c.descs.Intern(c.ret)}
+}
+
+func (c *checker) tupleLit(lit *ast.TupleLit) stele.Expr {
+	ok := true
+	x := stele.Tuple{Elems: make([]stele.Expr, 0, len(lit.Elems))}
+	types := make([]stele.Type, 0, len(lit.Elems))
+	for _, e := range lit.Elems {
+		elem := c.expr(e)
+		if elem == nil {
+			ok = false
+			continue
+		}
+		x.Elems = append(x.Elems, elem)
+		types = append(types, elem.Type())
+	}
+	if !ok {
+		return nil
+	}
+
+	x.T = stele.TupleType(types...)
+	if !hasUntyped(x) {
+		x.Desc = c.descs.Intern(x.T)
+	}
+	return x
+}
+
+// tupleIndex checks the selection of an element of a tuple. The index
+// must be a constant so that the element's type is known.
+func (c *checker) tupleIndex(index *ast.Index, x stele.Expr, elems []stele.Type) stele.Expr {
+	if len(index.Indices) != 1 {
+		c.errorf(index.Lbrack, "tuple index must be a single integer constant")
+		return nil
+	}
+
+	pos := index.Indices[0].Pos()
+	i := c.expr(index.Indices[0])
+	if i == nil {
+		return nil
+	}
+	ic, ok := i.(stele.Const)
+	if !ok || (ic.Val.Kind() != constant.Int) {
+		c.errorf(pos, "tuple index must be an integer constant")
+		return nil
+	}
+
+	n, ok := constant.Int64Val(ic.Val)
+	switch {
+	case constant.Sign(ic.Val) < 0:
+		c.errorf(pos, "invalid tuple index %v (index must not be negative)", ic.Val)
+		return nil
+	case !ok || (n >= int64(len(elems))):
+		c.errorf(pos, "index %v out of range for %v with %v elements", ic.Val, x.Type(), len(elems))
+		return nil
+	}
+
+	return stele.TupleIndex{X: x, Index: int(n), T: elems[n]}
+}
+
+// index checks the selection of an element of something, such as an
+// array, that has a get method, m.
+func (c *checker) index(index *ast.Index, x stele.Expr, m stele.Feature) stele.Expr {
+	if len(index.Indices) != 1 {
+		c.errorf(index.Lbrack, "index of %v must be a single value", x.Type())
+		return nil
+	}
+
+	i := c.expr(index.Indices[0])
+	if i == nil {
+		return nil
+	}
+	i, ok := c.convert(index.Indices[0].Pos(), i, m.Args[0])
+	if !ok {
+		return nil
+	}
+	return stele.Index{X: x, Index: i, T: m.Return}
+}
@@ -0,0 +1,455 @@
+package check
+
+import (
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+)
+
+// funcInfo tracks the signature of a package-level function.
+// Signatures are lowered on demand so that functions may be called
+// before the point at which they are declared.
+type funcInfo struct {
+	decl  *ast.Func
+	state typeState
+	t     stele.Type
+
+	// recv is the type of the receiver of a method.
+	recv stele.Type
+}
+
+func (c *checker) funcSig(info *funcInfo) (stele.Type, bool) {
+	switch info.state {
+	case typeResolved:
+		return info.t, info.t.Valid()
+	case typeResolving:
+		c.errorf(info.decl.Pos(), "invalid recursive signature for %v", info.decl.Name.Name)
+		return stele.Type{}, false
+	}
+
+	info.state = typeResolving
+	defer func() { info.state = typeResolved }()
+
+	scope := c.scope
+	c.scope = c.pkgScope
+	defer func() { c.scope = scope }()
+
+	t, recv, ok := c.signature(info.decl)
+	info.t = t
+	if recv != nil {
+		info.recv = recv.T
+	}
+	return t, ok
+}
+
+// signature lowers the signature of a function declaration. It leaves
+// the function's type parameters, receiver and parameters declared in
+// a new block in c.scope.
+func (c *checker) signature(decl *ast.Func) (stele.Type, *stele.Let, bool) {
+	c.scope = c.scope.Block()
+	params, ok := c.typeParams(decl.TypeParams, false)
+
+	var recv *stele.Let
+	if decl.Recv != nil {
+		t, rok := c.typeExpr(decl.Recv.Type)
+		ok = ok && rok
+		if len(decl.Recv.Names) > 0 {
+			recv = &stele.Let{Name: decl.Recv.Names[0].Name, T: t, Param: true}
+			ok = c.declare(*recv, decl.Recv.Names[0].Pos()) && ok
+		} else {
+			recv = &stele.Let{T: t, Param: true}
+		}
+	}
+
+	var args []stele.Type
+	if decl.Type.Params != nil {
+		for _, field := range decl.Type.Params.List {
+			t, aok := c.typeExpr(field.Type)
+			ok = ok && aok
+			for _, name := range field.Names {
+				args = append(args, t)
+				ok = c.declare(stele.Let{Name: name.Name, T: t, Param: true}, name.Pos()) && ok
+			}
+		}
+	}
+
+	ret := stele.Unit
+	if decl.Type.Result != nil {
+		t, rok := c.typeExpr(decl.Type.Result)
+		ok = ok && rok
+		ret = t
+	}
+
+	return stele.FuncOf(stele.Feature{
+		TypeParams: params,
+		Args:       args,
+		Return:     ret,
+		Mut:        decl.Type.Mut,
+	}), recv, ok
+}
+
+func (c *checker) funcDecl(decl *ast.Func) (stele.Func, bool) {
+	infos := c.methodDecls[decl.Name.Name]
+	if decl.Recv == nil {
+		infos = []*funcInfo{c.funcs[decl.Name.Name]}
+	}
+	for _, info := range infos {
+		if (info != nil) && (info.decl == decl) {
+			// Make sure that errors in the signature are only reported
+			// once if it has already been needed.
+			if _, ok := c.funcSig(info); !ok {
+				return stele.Func{}, false
+			}
+		}
+	}
+
+	return c.function(decl.Name.Name, decl)
+}
+
+// function checks a function named name, which is empty for a
+// function literal. Its signature is declared in a new block inside
+// of c.scope, so its body can see the variables of any block that it
+// is in.
+func (c *checker) function(name string, decl *ast.Func) (stele.Func, bool) {
+	scope := c.scope
+	defer func() { c.scope = scope }()
+
+	t, recv, ok := c.signature(decl)
+	if !ok {
+		return stele.Func{}, false
+	}
+	sig, _ := t.Func()
+
+	f := stele.Func{
+		Name:  name,
+		Recv:  recv,
+		T:     t,
+		Slots: c.scope.Len(),
+		Desc:  c.descs.Intern(t),
+		Unit:  sig.Return.Satisfies(stele.Unit),
+	}
+
+	locals := c.locals
+	c.locals = make(map[string]struct{})
+	defer func() { c.locals = locals }()
+	if recv != nil {
+		c.locals[recv.ID()] = struct{}{}
+	}
+	if decl.Type.Params != nil {
+		for _, field := range decl.Type.Params.List {
+			for _, name := range field.Names {
+				if name.IsImmutable() {
+					f.Immutable = append(f.Immutable, len(f.Params))
+				}
+				f.Params = append(f.Params, name.ID())
+				c.locals[name.ID()] = struct{}{}
+			}
+		}
+	}
+
+	f.Body, ok = c.funcBody(decl.Body, sig)
+	return f, ok
+}
+
+func (c *checker) funcLit(lit *ast.FuncLit) stele.Expr {
+	if lit.Type.Params != nil {
+		for _, field := range lit.Type.Params.List {
+			if field.Type == nil {
+				c.errorf(field.Pos(), "missing type for parameter")
+				return nil
+			}
+		}
+	}
+
+	f, ok := c.function("", &ast.Func{Type: lit.Type, Body: lit.Body})
+	if !ok {
+		return nil
+	}
+	return stele.FuncLit{Func: f}
+}
+
+// funcBody checks the body of a function with the signature sig. If
+// the last statement in the body is an expression, its value is the
+// function's result.
+func (c *checker) funcBody(body *ast.Block, sig stele.Feature) (stele.Block, bool) {
+	ret, mut, loops := c.ret, c.mut, c.loops
+	c.ret, c.mut, c.loops = sig.Return, sig.Mut, 0
+	defer func() { c.ret, c.mut, c.loops = ret, mut, loops }()
+
+	n := len(c.errs)
+	block := c.block(body)
+
+	if len(c.errs) > n {
+		return block, false
+	}
+
+	// A function that returns unit may end with an expression of any
+	// type, as it is not used.
+	if last, ok := lastExpr(body); ok && !sig.Return.Satisfies(stele.Unit) {
+		i := len(block.Stmts) - 1
+		if (i < 0) || (block.Pos[i] != last.Pos()) {
+			// The expression was dropped because something that it
+			// uses failed to check, which has already been reported.
+			return block, false
+		}
+		x, ok := c.convert(last.Pos(), block.Stmts[i].(stele.Expr), sig.Return)
+		if !ok {
+			return block, false
+		}
+		block.Stmts[i], block.T = c.returned(x), x.Type()
+	}
+	return block, true
+}
+
+// typeParams lowers a list of type parameters, declaring each in
+// c.scope as it goes so that constraints may refer to earlier
+// parameters. If self is true, the list belongs to a type and must
+// start with the unconstrained self parameter. Otherwise, every
+// parameter must have a constraint.
+func (c *checker) typeParams(list *ast.TypeParamList, self bool) ([]stele.TypeParam, bool) {
+	if list == nil {
+		return nil, true
+	}
+
+	ok := true
+	params := make([]stele.TypeParam, 0, len(list.List))
+	for i, p := range list.List {
+		param := stele.TypeParam{Name: p.Name.Name}
+		switch {
+		case self && (i == 0):
+			if p.Constraint != nil {
+				c.errorf(p.Constraint.Pos(), "the self type parameter %v may not have a constraint", p.Name.Name)
+				ok = false
+			}
+
+		case p.Constraint == nil:
+			c.errorf(p.Pos(), "missing constraint for type parameter %v", p.Name.Name)
+			ok = false
+
+		default:
+			t, cok := c.typeExpr(p.Constraint)
+			param.Constraint = t
+			ok = ok && cok
+		}
+
+		params = append(params, param)
+		ok = c.declare(stele.TypeDecl{Name: param.Name, T: param.Ref()}, p.Name.Pos()) && ok
+	}
+	return params, ok
+}
+
+func (c *checker) ident(id *ast.Ident) stele.Expr {
+	switch d := c.lookup(id.ID()).(type) {
+	case stele.Let:
+		if !d.T.Valid() {
+			// The declaration failed to check, which has already been
+			// reported.
+			return nil
+		}
+		return stele.Ident{ID: d.ID(), T: d.T, Ref: c.ref(d.ID())}
+	case stele.Func, stele.Import:
+		return stele.Ident{ID: d.ID(), T: d.Type(), Ref: c.ref(d.ID())}
+	case nil:
+		if info, ok := c.funcs[id.Name]; ok {
+			t, ok := c.funcSig(info)
+			if !ok {
+				return nil
+			}
+			return stele.Ident{ID: id.Name, T: t}
+		}
+		c.undefined(id, false)
+		return nil
+	default:
+		c.errorf(id.Pos(), "%v is not a value", id.Name)
+		return nil
+	}
+}
+
+func (c *checker) call(call *ast.Call) stele.Expr {
+	if c.isType(call.Fun) {
+		return c.conversion(call)
+	}
+
+	fun := c.expr(call.Fun)
+	if fun == nil {
+		return nil
+	}
+
+	sig, ok := fun.Type().Func()
+	if !ok {
+		c.errorf(call.Fun.Pos(), "cannot call non-function %v of type %v", describe(call.Fun), fun.Type())
+		return nil
+	}
+	if sig.Mut && !c.mut {
+		c.errorf(call.Fun.Pos(), "cannot call mutable function %v from a pure context", describeFunc(call.Fun))
+		return nil
+	}
+	if sel, ok := fun.(stele.Selector); ok && !c.canCallMethod(call.Fun.Pos(), sel) {
+		return nil
+	}
+
+	args := make([]stele.Expr, 0, len(call.Args))
+	types := make([]stele.Type, 0, len(call.Args))
+	for _, arg := range call.Args {
+		x := c.expr(arg)
+		if x == nil {
+			return nil
+		}
+		args = append(args, x)
+		types = append(types, x.Type())
+	}
+
+	if len(args) != len(sig.Args) {
+		c.errorf(call.Lparen, "wrong number of arguments in call: have %v, want %v", len(args), len(sig.Args))
+		return nil
+	}
+
+	if len(sig.TypeParams) > 0 {
+		// Untyped constants could be any number type, so they can not
+		// be used to infer type arguments.
+		var untyped stele.Expr
+		for i, arg := range args {
+			if arg.Type().Untyped() {
+				types[i] = stele.Type{}
+				untyped = arg
+			}
+		}
+
+		targs, err := sig.Infer(types)
+		if err != nil {
+			if untyped != nil {
+				c.errorf(call.Lparen, "%v in call to %v: untyped constant %v could be of more than one type", err, describeFunc(call.Fun), untyped.(stele.Const).Val)
+				return nil
+			}
+			c.errorf(call.Lparen, "%v in call to %v", err, describeFunc(call.Fun))
+			return nil
+		}
+		sig, err = sig.Instantiate(targs...)
+		if err != nil {
+			c.errorf(call.Lparen, "cannot instantiate %v: %v", describeFunc(call.Fun), err)
+			return nil
+		}
+	}
+
+	ok = true
+	for i, arg := range args {
+		x, aok := c.convert(call.Args[i].Pos(), arg, sig.Args[i])
+		if aok {
+			args[i] = c.copied(x, true)
+		}
+		ok = ok && aok
+	}
+	if !ok {
+		return nil
+	}
+
+	return stele.Call{Func: fun, Args: args, T: sig.Return}
+}
+
+// isType returns true if expr names a type, possibly with type
+// arguments, rather than a value.
+func (c *checker) isType(expr ast.Expr) bool {
+	if index, ok := expr.(*ast.Index); ok {
+		expr = index.X
+	}
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	switch c.lookup(id.Name).(type) {
+	case stele.TypeDecl:
+		return true
+	case nil:
+		_, ok := c.types[id.Name]
+		return ok
+	}
+	return false
+}
+
+// conversion checks a conversion, T(x), which is the same as assigning
+// x to a variable of type T. If there is more than one argument, they
+// are a tuple literal.
+func (c *checker) conversion(call *ast.Call) stele.Expr {
+	t, ok := c.typeExpr(call.Fun)
+	if !ok {
+		return nil
+	}
+
+	var x stele.Expr
+	switch len(call.Args) {
+	case 0:
+		c.errorf(call.Rparen, "missing argument in conversion to %v", t)
+		return nil
+	case 1:
+		x = c.expr(call.Args[0])
+	default:
+		x = c.tupleLit(&ast.TupleLit{Lparen: call.Lparen, Elems: call.Args, Rparen: call.Rparen})
+	}
+	if x == nil {
+		return nil
+	}
+
+	r, ok := c.convert(call.Args[0].Pos(), x, t)
+	if !ok {
+		return nil
+	}
+	if r.Type().String() != t.String() {
+		r = stele.Convert{X: r, T: t, Desc: c.descs.Intern(t)}
+	}
+	return r
+}
+
+// pipe checks a pipe, x |> f(args), which is a call of f with x
+// inserted before the rest of its arguments. If the right-hand side is
+// not a call, it is called with x alone.
+func (c *checker) pipe(expr *ast.Binary) stele.Expr {
+	call, ok := expr.Y.(*ast.Call)
+	if !ok {
+		call = &ast.Call{Fun: expr.Y, Lparen: expr.OpPos, Rparen: expr.OpPos}
+	}
+	return c.call(&ast.Call{
+		Fun:    call.Fun,
+		Lparen: call.Lparen,
+		Args:   append([]ast.Expr{expr.X}, call.Args...),
+		Rparen: call.Rparen,
+	})
+}
+
+// instantiate explicitly instantiates a generic function.
+func (c *checker) instantiate(index *ast.Index, fun stele.Expr, sig stele.Feature) stele.Expr {
+	targs := make([]stele.Type, 0, len(index.Indices))
+	for _, expr := range index.Indices {
+		t, ok := c.typeExpr(expr)
+		if !ok {
+			return nil
+		}
+		targs = append(targs, t)
+	}
+
+	sig, err := sig.Instantiate(targs...)
+	if err != nil {
+		c.errorf(index.Lbrack, "cannot instantiate %v: %v", describeFunc(index.X), err)
+		return nil
+	}
+
+	t := stele.FuncOf(sig)
+	if id, ok := fun.(stele.Ident); ok {
+		id.T = t
+		return id
+	}
+	c.errorf(index.Pos(), "%v is not supported yet", describe(index))
+	return nil
+}
+
+// describeFunc returns a description of a called expression for use
+// in error messages.
+func describeFunc(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.Selector:
+		if _, ok := expr.X.(*ast.Ident); ok {
+			return describeFunc(expr.X) + "." + expr.Sel.Name
+		}
+	}
+	return describe(expr)
+}
@@ -0,0 +1,102 @@
+package check
+
+import (
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+)
+
+// arrayLit checks an array literal. Its element type is that of its
+// first element that is not an untyped constant. If all of them are,
+// they are left untyped, like those of a tuple literal, until the
+// literal is converted to an array type.
+func (c *checker) arrayLit(lit *ast.ArrayLit) stele.Expr {
+	if len(lit.Elems) == 0 {
+		c.errorf(lit.Pos(), "cannot infer element type of empty array literal")
+		return nil
+	}
+
+	ok := true
+	elems := make([]stele.Expr, 0, len(lit.Elems))
+	var elem stele.Type
+	for _, e := range lit.Elems {
+		x := c.expr(e)
+		if x == nil {
+			ok = false
+			continue
+		}
+		elems = append(elems, x)
+		if !elem.Valid() && !x.Type().Untyped() {
+			elem = x.Type()
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	if !elem.Valid() {
+		return stele.ArrayLit{Elems: elems, T: stele.ArrayOf(elems[0].Type())}
+	}
+	x, ok := c.convert(lit.Pos(), stele.ArrayLit{Elems: elems}, stele.ArrayOf(elem))
+	if !ok {
+		return nil
+	}
+	return x
+}
+
+// structLit checks a struct literal. Fields that are not given a value
+// are initialized to their zero value, so they must have one.
+func (c *checker) structLit(lit *ast.StructLit) stele.Expr {
+	t, ok := c.typeExpr(lit.Type)
+	if !ok {
+		return nil
+	}
+
+	x := stele.StructLit{T: t, Desc: c.descs.Intern(t)}
+	seen := make(map[string]struct{}, len(lit.Fields))
+	for _, init := range lit.Fields {
+		name := init.Name.Name
+		f, fok := t.Feature(stele.LetFeature, name)
+		if !fok {
+			c.errorf(init.Name.Pos(), "unknown field %v in struct literal of type %v", name, t)
+			ok = false
+			continue
+		}
+		if _, dup := seen[name]; dup {
+			c.errorf(init.Name.Pos(), "duplicate field %v in struct literal", name)
+			ok = false
+			continue
+		}
+		seen[name] = struct{}{}
+
+		v := c.expr(init.Value)
+		if v == nil {
+			ok = false
+			continue
+		}
+		v, vok := c.convert(init.Value.Pos(), v, f.Return)
+		x.Fields = append(x.Fields, stele.FieldInit{Name: name, Val: v})
+		ok = ok && vok
+	}
+	if !ok {
+		return nil
+	}
+
+	for _, f := range t.FeatureSet() {
+		if f.Type != stele.LetFeature {
+			continue
+		}
+		if _, ok := seen[f.Name]; ok {
+			continue
+		}
+		if !stele.HasZero(f.Return) {
+			c.errorf(lit.Pos(), "missing field %v in struct literal: %v has no zero value", f.Name, f.Return)
+			ok = false
+			continue
+		}
+		x.Fields = append(x.Fields, stele.FieldInit{Name: f.Name, Val: stele.Zero{T: f.Return}})
+	}
+	if !ok {
+		return nil
+	}
+	return x
+}
@@ -0,0 +1,137 @@
+package check
+
+import (
+	"slices"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+)
+
+// Methods are not features of the types that they are declared for.
+// Instead, they are attached to those types by name, and a method is
+// found from the static type of the expression that it is selected
+// from, so converting a value to another type gives it that type's
+// methods. A method with a generic receiver is attached to every type
+// declared in the package that satisfies the receiver's constraint,
+// unless the type has a method of the same name of its own.
+
+// generic returns the constraint of the receiver of a method if the
+// receiver is one of the method's type parameters.
+func (info *funcInfo) generic() (stele.Type, bool) {
+	if !info.recv.Param {
+		return stele.Type{}, false
+	}
+	sig, _ := info.t.Func()
+	for _, p := range sig.TypeParams {
+		if p.Name == info.recv.Name {
+			return p.Constraint, true
+		}
+	}
+	return stele.Type{}, false
+}
+
+// attached returns the methods named name that are attached to t. If
+// there is more than one, they all have generic receivers, and t is
+// ambiguous.
+func (c *checker) attached(t stele.Type, name string) []*funcInfo {
+	if (t.Name == "") || t.Param {
+		return nil
+	}
+
+	var generic []*funcInfo
+	for _, info := range c.methodDecls[name] {
+		if _, ok := c.funcSig(info); !ok {
+			continue
+		}
+		constraint, ok := info.generic()
+		if !ok {
+			if info.recv.String() == t.String() {
+				return []*funcInfo{info}
+			}
+			continue
+		}
+		if c.declared(t) && t.Satisfies(constraint) {
+			generic = append(generic, info)
+		}
+	}
+	return generic
+}
+
+// declared returns true if t is a type declared by the package.
+func (c *checker) declared(t stele.Type) bool {
+	info, ok := c.types[t.Name]
+	if !ok {
+		return false
+	}
+	d, ok := c.typeDecl(info)
+	return ok && (d.T.Name == t.Name)
+}
+
+// methodSelector selects the method attached to the static type of x
+// that sel names, if there is one.
+func (c *checker) methodSelector(x stele.Expr, sel *ast.Selector) (*stele.Selector, bool) {
+	t := x.Type()
+	infos := c.attached(t, sel.Sel.Name)
+	if len(infos) == 0 {
+		return nil, false
+	}
+
+	// If more than one method applies, the ambiguity is reported where
+	// they are declared, so the first one will do here.
+	info := infos[0]
+	sig, _ := info.t.Func()
+	if _, ok := info.generic(); ok {
+		if len(sig.TypeParams) != 1 {
+			c.errorf(sel.Sel.Pos(), "method %v with type parameters other than its receiver's is not supported yet", sel.Sel.Name)
+			return nil, true
+		}
+		var err error
+		sig, err = sig.Instantiate(t)
+		if err != nil {
+			c.errorf(sel.Sel.Pos(), "cannot attach method %v to %v: %v", sel.Sel.Name, t, err)
+			return nil, true
+		}
+	}
+	mt := stele.FuncOf(sig)
+	return &stele.Selector{X: x, Name: sel.Sel.Name, T: mt, Recv: t.String(), Desc: c.descs.Intern(mt)}, true
+}
+
+// attach fills in the types that f, a method with a generic receiver
+// declared by decl, is attached to. If another method of the same
+// name that was declared before it would be attached to any of the
+// same types, the ambiguity is reported.
+func (c *checker) attach(f *stele.Func, decl *ast.Func) {
+	var info *funcInfo
+	for _, m := range c.methodDecls[f.Name] {
+		if m.decl == decl {
+			info = m
+		}
+	}
+	if info == nil {
+		return
+	}
+	if _, ok := info.generic(); !ok {
+		return
+	}
+
+	names := make([]string, 0, len(c.types))
+	for name := range c.types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		t, ok := c.typeDecl(c.types[name])
+		if !ok {
+			continue
+		}
+		infos := c.attached(t.T, f.Name)
+		switch i := slices.Index(infos, info); {
+		case i < 0:
+		case len(infos) == 1:
+			f.Attached = append(f.Attached, t.Name)
+		case i > 0:
+			c.errorf(decl.Name.Pos(), "method %v is ambiguous for %v: it and the method declared at %v both have generic receivers that %v satisfies", f.Name, t.Name, infos[0].decl.Name.Pos(), t.Name)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package check
+
+import (
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+// Operators are not overloadable, but each of them maps to a method of
+// the predeclared types. An operator can be used on any value whose
+// type has that method, and operations on constants are folded.
+
+func (c *checker) unary(expr *ast.Unary) stele.Expr {
+	x := c.expr(expr.X)
+	if x == nil {
+		return nil
+	}
+	if xc, ok := x.(stele.Const); ok {
+		if op, ok := constOps[expr.Op]; ok {
+			return c.foldUnary(expr, xc, op)
+		}
+	}
+
+	name, _, _, ok := stele.OperatorMethod(expr.Op, true)
+	if !ok {
+		c.errorf(expr.OpPos, "unary operator %v is not supported yet", expr.Op.Text())
+		return nil
+	}
+
+	m, ok := x.Type().Feature(stele.FuncFeature, name)
+	if !ok || (len(m.Args) != 0) {
+		c.errorf(expr.OpPos, "operator %v not defined on %v (type %v has no method %v)", expr.Op.Text(), describeFunc(expr.X), x.Type(), name)
+		return nil
+	}
+	return stele.Unary{Op: expr.Op, X: x, Method: name, T: m.Return}
+}
+
+func (c *checker) binary(expr *ast.Binary) stele.Expr {
+	x, y := c.expr(expr.X), c.expr(expr.Y)
+	if (x == nil) || (y == nil) {
+		return nil
+	}
+
+	xc, xok := x.(stele.Const)
+	yc, yok := y.(stele.Const)
+	if op, ok := constOps[expr.Op]; ok && xok && yok {
+		return c.foldBinary(expr, xc, op, yc)
+	}
+
+	// An untyped operand is given the type of the other one, as both
+	// sides of an operator must be of the same type.
+	var ok bool
+	if x.Type().Untyped() {
+		if x, ok = c.convert(expr.X.Pos(), x, y.Type()); !ok {
+			return nil
+		}
+	}
+	if y.Type().Untyped() {
+		if y, ok = c.convert(expr.Y.Pos(), y, x.Type()); !ok {
+			return nil
+		}
+	}
+
+	switch expr.Op {
+	case scanner.AND, scanner.OR:
+		xok := c.assignable(expr.X.Pos(), x.Type(), stele.Bool)
+		yok := c.assignable(expr.Y.Pos(), y.Type(), stele.Bool)
+		if !xok || !yok {
+			return nil
+		}
+		return stele.Binary{Op: expr.Op, X: x, Y: y, T: stele.Bool}
+	}
+
+	name, swap, negate, ok := stele.OperatorMethod(expr.Op, false)
+	if !ok {
+		c.errorf(expr.OpPos, "operator %v is not supported yet", expr.Op.Text())
+		return nil
+	}
+
+	recv, arg, argExpr := x, y, expr.Y
+	if swap {
+		recv, arg, argExpr = y, x, expr.X
+	}
+	m, ok := recv.Type().Feature(stele.FuncFeature, name)
+	if !ok || (len(m.Args) != 1) {
+		c.errorf(expr.OpPos, "operator %v not defined on %v (type %v has no method %v)", expr.Op.Text(), describeFunc(expr.X), recv.Type(), name)
+		return nil
+	}
+	if !c.assignable(argExpr.Pos(), arg.Type(), m.Args[0]) {
+		return nil
+	}
+
+	t := m.Return
+	if negate {
+		t = stele.Bool
+	}
+	return stele.Binary{
+		Op:     expr.Op,
+		X:      x,
+		Y:      y,
+		Method: name,
+		Swap:   swap,
+		Negate: negate,
+		T:      t,
+	}
+}
@@ -0,0 +1,89 @@
+package check
+
+import (
+	"deedles.dev/stele"
+	"deedles.dev/stele/scanner"
+)
+
+// Functions are pure unless they are declared to be mutable. A pure
+// function may only affect state that has been explicitly passed to
+// it, so it may not assign to variables from outside of it, call
+// mutable functions, or call mutable methods of its arguments. The
+// checks in this file enforce that, along with the rules about which
+// variables may be assigned to at all.
+
+// local returns true if the variable id is declared by the function
+// currently being checked.
+func (c *checker) local(id string) bool {
+	_, ok := c.locals[id]
+	return ok
+}
+
+// canAssign reports an error at pos if the variable let may not be
+// assigned to.
+func (c *checker) canAssign(pos scanner.Pos, let stele.Let) bool {
+	switch {
+	case !let.Mutable():
+		c.errorf(pos, "cannot assign to %v: it is immutable", let.ID())
+	case let.Param:
+		c.errorf(pos, "cannot assign to parameter %v", let.ID())
+	case !c.mut && !c.local(let.ID()):
+		c.errorf(pos, "cannot assign to %v from a pure context: it is declared outside of the function", let.ID())
+	default:
+		return true
+	}
+	return false
+}
+
+// canAssignField reports an error at pos if the field name of the
+// variable let may not be assigned to. Unlike the variable itself, the
+// fields of a mutable parameter may be assigned to, even by a pure
+// function.
+func (c *checker) canAssignField(pos scanner.Pos, let stele.Let, name string) bool {
+	switch {
+	case !let.Mutable():
+		c.errorf(pos, "cannot assign to field %v of immutable %v", name, let.ID())
+	case !c.mut && !c.local(let.ID()):
+		c.errorf(pos, "cannot assign to field %v of %v from a pure context: it is declared outside of the function", name, let.ID())
+	default:
+		return true
+	}
+	return false
+}
+
+// canCallMethod reports an error at pos if the method selected by sel
+// requires a mutable receiver that it can not be given. The receiver
+// is checked as the variable that it is kept in, either directly or as
+// a field or element of it. Methods of values that are not stored in a
+// variable may always be called, as nothing else can see the change.
+func (c *checker) canCallMethod(pos scanner.Pos, sel stele.Selector) bool {
+	f, ok := sel.X.Type().Feature(stele.FuncFeature, sel.Name)
+	if sel.Recv != "" {
+		f, ok = sel.T.Func()
+	}
+	if !ok || !f.MutRecv {
+		return true
+	}
+	x := sel.X
+	if cp, ok := x.(stele.Copy); ok {
+		x = cp.X
+	}
+	id, ok := root(x)
+	if !ok {
+		return true
+	}
+	let, ok := c.scope.Get(id.ID).(stele.Let)
+	if !ok {
+		return true
+	}
+
+	switch {
+	case !let.Mutable():
+		c.errorf(pos, "cannot call mutable method %v on immutable %v", sel.Name, let.ID())
+	case !c.mut && (let.Param || !c.local(let.ID())):
+		c.errorf(pos, "cannot call mutable method %v of %v from a pure context", sel.Name, let.ID())
+	default:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,401 @@
+package check
+
+import (
+	"maps"
+	"strings"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+// block checks a block in its own scope. The block's type is the type
+// of its last statement if that is an expression, or unit otherwise.
+func (c *checker) block(body *ast.Block) stele.Block {
+	scope, locals := c.scope, c.locals
+	defer func() { c.scope, c.locals = scope, locals }()
+	c.scope = c.scope.Block()
+	if locals != nil {
+		c.locals = maps.Clone(locals)
+	}
+
+	block := stele.Block{T: stele.Unit}
+	for i, stmt := range body.Stmts {
+		s := c.stmt(stmt)
+		if s == nil {
+			continue
+		}
+		block.Stmts = append(block.Stmts, s)
+		block.Pos = append(block.Pos, stmt.Pos())
+
+		if x, ok := s.(stele.Expr); ok && (i == len(body.Stmts)-1) {
+			if _, ok := stmt.(*ast.ExprStmt); ok {
+				block.T = x.Type()
+			}
+		}
+	}
+	block.Slots = c.scope.Len()
+	return block
+}
+
+// lastExpr returns the expression that a block ends with, if any.
+func lastExpr(body *ast.Block) (ast.Expr, bool) {
+	if len(body.Stmts) == 0 {
+		return nil, false
+	}
+	stmt, ok := body.Stmts[len(body.Stmts)-1].(*ast.ExprStmt)
+	if !ok {
+		return nil, false
+	}
+	return stmt.X, true
+}
+
+// stmt checks a single statement. It returns nil if the statement
+// has no run-time effect or if checking it failed.
+func (c *checker) stmt(stmt ast.Stmt) stele.Stmt {
+	switch stmt := stmt.(type) {
+	case *ast.Let:
+		lets, ok := c.letDecl(stmt)
+		for i, let := range lets {
+			if !c.declare(let, stmt.Names[i].Pos()) {
+				continue
+			}
+			if c.locals != nil {
+				c.locals[let.ID()] = struct{}{}
+			}
+		}
+		if !ok || (lets[0].Assign == nil) {
+			return nil
+		}
+		c.resolve(lets[0].Assign)
+		return lets[0].Assign
+
+	case *ast.Assign:
+		return c.assign(stmt)
+
+	case *ast.Return:
+		var r stele.Return
+		if stmt.Result != nil {
+			r.Val = c.expr(stmt.Result)
+			if r.Val == nil {
+				return nil
+			}
+			val, ok := c.convert(stmt.Result.Pos(), r.Val, c.ret)
+			if !ok {
+				return nil
+			}
+			r.Val = c.returned(val)
+		}
+		return r
+
+	case *ast.ExprStmt:
+		x := c.expr(stmt.X)
+		if x == nil {
+			return nil
+		}
+		return x
+
+	case *ast.Block:
+		return c.block(stmt)
+
+	case *ast.For:
+		return c.forStmt(stmt)
+
+	case *ast.Branch:
+		if c.loops == 0 {
+			c.errorf(stmt.TokPos, "%v is not in a loop", stmt.Tok.Text())
+			return nil
+		}
+		return stele.Branch{Tok: stmt.Tok}
+	}
+
+	c.errorf(stmt.Pos(), "%v is not supported yet", describe(stmt))
+	return nil
+}
+
+func (c *checker) forStmt(stmt *ast.For) stele.Stmt {
+	var loop stele.For
+	ok := true
+	if stmt.Cond != nil {
+		loop.Cond = c.cond(stmt.Cond)
+		ok = loop.Cond != nil
+	}
+
+	c.loops++
+	loop.Body = c.block(stmt.Body)
+	c.loops--
+	if !ok {
+		return nil
+	}
+	return loop
+}
+
+// compoundOps maps the compound assignment operators to the binary
+// operators that they apply.
+var compoundOps = map[scanner.Type]scanner.Type{
+	scanner.PLUSASSIGN:  scanner.PLUS,
+	scanner.MINUSASSIGN: scanner.MINUS,
+	scanner.MULTASSIGN:  scanner.MULT,
+	scanner.DIVASSIGN:   scanner.DIV,
+	scanner.MODASSIGN:   scanner.MOD,
+}
+
+// assign checks an assignment to one or more variables. Assigning to
+// more than one variable destructures a tuple.
+//
+// A compound assignment, such as x += y, is checked as though it was
+// written as x = x + y. A compound assignment to an element is checked
+// by compoundIndex instead.
+func (c *checker) assign(stmt *ast.Assign) stele.Stmt {
+	if op, ok := compoundOps[stmt.Tok]; ok {
+		if len(stmt.Lhs) != 1 {
+			c.errorf(stmt.TokPos, "assignment operator %v requires a single operand", stmt.Tok.Text())
+			return nil
+		}
+		if index, ok := stmt.Lhs[0].(*ast.Index); ok {
+			return c.compoundIndex(stmt, index, op)
+		}
+		stmt = &ast.Assign{
+			Lhs:    stmt.Lhs,
+			TokPos: stmt.TokPos,
+			Tok:    scanner.ASSIGN,
+			Rhs:    &ast.Binary{X: stmt.Lhs[0], OpPos: stmt.TokPos, Op: op, Y: stmt.Rhs},
+		}
+	}
+	if stmt.Tok != scanner.ASSIGN {
+		c.errorf(stmt.TokPos, "%v is not supported yet", stmt.Tok.Text())
+		return nil
+	}
+
+	if len(stmt.Lhs) == 1 {
+		switch lhs := stmt.Lhs[0].(type) {
+		case *ast.Selector:
+			return c.assignField(stmt, lhs)
+		case *ast.Index:
+			return c.assignIndex(stmt, lhs)
+		}
+	}
+
+	ok := true
+	lets := make([]stele.Let, 0, len(stmt.Lhs))
+	for _, lhs := range stmt.Lhs {
+		id, iok := lhs.(*ast.Ident)
+		if !iok {
+			c.errorf(lhs.Pos(), "assignment to %v is not supported yet", describe(lhs))
+			ok = false
+			continue
+		}
+		let, iok := c.scope.Get(id.ID()).(stele.Let)
+		if !iok {
+			c.errorf(id.Pos(), "cannot assign to %v: not a variable", id.Name)
+			ok = false
+			continue
+		}
+		ok = c.canAssign(id.Pos(), let) && ok
+		lets = append(lets, let)
+	}
+
+	rhs := c.expr(stmt.Rhs)
+	if (rhs == nil) || !ok {
+		return nil
+	}
+
+	if len(lets) == 1 {
+		rhs, ok = c.convert(stmt.Rhs.Pos(), rhs, lets[0].T)
+		if !ok {
+			return nil
+		}
+		a := &stele.Assign{ID: lets[0].ID(), Val: c.copied(rhs, true)}
+		c.resolve(a)
+		return a
+	}
+
+	elems, ok := c.destructure(stmt.Rhs.Pos(), rhs, len(lets))
+	if !ok {
+		return nil
+	}
+	ids := make([]string, 0, len(lets))
+	to := make([]stele.Type, 0, len(lets))
+	pos := make([]scanner.Pos, 0, len(lets))
+	mut := make([]bool, 0, len(lets))
+	for i, let := range lets {
+		ids = append(ids, let.ID())
+		to = append(to, let.T)
+		pos = append(pos, stmt.Lhs[i].Pos())
+		mut = append(mut, true)
+	}
+	rhs, ok = c.assignElems(rhs, elems, to, ids, pos)
+	if !ok {
+		return nil
+	}
+	a := &stele.Assign{IDs: ids, Val: c.copiedElems(rhs, mut)}
+	c.resolve(a)
+	return a
+}
+
+// assignField checks an assignment to a field of a variable, or to a
+// field of one of its fields, and so on.
+func (c *checker) assignField(stmt *ast.Assign, sel *ast.Selector) stele.Stmt {
+	id, path, ok := fieldPath(sel)
+	if !ok {
+		c.errorf(sel.Pos(), "cannot assign to field %v of %v: only fields of variables can be assigned to", sel.Sel.Name, describe(sel.X))
+		return nil
+	}
+	let, ok := c.scope.Get(id.ID()).(stele.Let)
+	if !ok {
+		c.errorf(id.Pos(), "cannot assign to %v: %v is not a variable", path, id.Name)
+		return nil
+	}
+	if !let.T.Valid() {
+		return nil
+	}
+
+	var x stele.Expr
+	t := let.T
+	if sel.X != id {
+		x = c.expr(sel.X)
+		if x == nil {
+			return nil
+		}
+		t = x.Type()
+	}
+
+	f, ok := t.Feature(stele.LetFeature, sel.Sel.Name)
+	if !ok {
+		c.errorf(sel.Sel.Pos(), "%v undefined (type %v has no field %v)", path, t, sel.Sel.Name)
+		return nil
+	}
+
+	rhs := c.expr(stmt.Rhs)
+	if !c.canAssignField(sel.Pos(), let, strings.TrimPrefix(path, id.Name+".")) || (rhs == nil) {
+		return nil
+	}
+	rhs, ok = c.convert(stmt.Rhs.Pos(), rhs, f.Return)
+	if !ok {
+		return nil
+	}
+	if x != nil {
+		return &stele.Assign{Struct: x, ID: sel.Sel.Name, Val: c.copied(rhs, true)}
+	}
+	a := &stele.Assign{Recv: let.ID(), ID: sel.Sel.Name, Val: c.copied(rhs, true)}
+	c.resolve(a)
+	return a
+}
+
+// fieldPath returns the variable that the chain of selectors sel
+// starts with, along with the whole chain as it was written, such as
+// a.b.c. It returns false if the chain does not start with an
+// identifier.
+func fieldPath(sel *ast.Selector) (*ast.Ident, string, bool) {
+	switch x := sel.X.(type) {
+	case *ast.Ident:
+		return x, x.Name + "." + sel.Sel.Name, true
+	case *ast.Selector:
+		id, path, ok := fieldPath(x)
+		return id, path + "." + sel.Sel.Name, ok
+	}
+	return nil, "", false
+}
+
+// assignIndex checks an assignment to an element of an array, which
+// is a call of its set method.
+func (c *checker) assignIndex(stmt *ast.Assign, index *ast.Index) stele.Stmt {
+	if len(index.Indices) != 1 {
+		c.errorf(index.Lbrack, "assignment to an element requires a single index")
+		return nil
+	}
+
+	x := c.call(&ast.Call{
+		Fun:    &ast.Selector{X: index.X, Sel: &ast.Ident{NamePos: index.Lbrack, Name: "set"}},
+		Lparen: index.Lbrack,
+		Args:   []ast.Expr{index.Indices[0], stmt.Rhs},
+		Rparen: index.Rbrack,
+	})
+	if x == nil {
+		return nil
+	}
+	return x
+}
+
+// compoundIndex checks a compound assignment to an element, such as
+// a[i] += y. It is checked as though it was written as a[i] = a[i] + y,
+// but with a and i evaluated only once, before y, by first assigning
+// them to temporary variables in a block around the assignment. A
+// variable or a constant is used directly instead, so that assigning
+// to an element of a variable is checked the same way either way.
+func (c *checker) compoundIndex(stmt *ast.Assign, index *ast.Index, op scanner.Type) stele.Stmt {
+	if len(index.Indices) != 1 {
+		c.errorf(index.Lbrack, "assignment to an element requires a single index")
+		return nil
+	}
+
+	// Temporaries are declared in a block, but whether any will be is
+	// only known once the operands have been checked, which must be
+	// done in the block's scope for them to be evaluated in it.
+	_, simpleX := index.X.(*ast.Ident)
+	_, simpleI := index.Indices[0].(*ast.Ident)
+	_, constI := index.Indices[0].(*ast.BasicLit)
+	inBlock := !simpleX || (!simpleI && !constI)
+	if inBlock {
+		scope, locals := c.scope, c.locals
+		defer func() { c.scope, c.locals = scope, locals }()
+		c.scope = c.scope.Block()
+		if locals != nil {
+			c.locals = maps.Clone(locals)
+		}
+	}
+
+	block := stele.Block{T: stele.Unit}
+	temp := func(x ast.Expr, name string) (ast.Expr, bool) {
+		if _, ok := x.(*ast.Ident); ok {
+			return x, true
+		}
+		v := c.expr(x)
+		if v == nil {
+			return nil, false
+		}
+		if _, ok := v.(stele.Const); ok {
+			return x, true
+		}
+
+		// The names of temporaries are not valid identifiers, so they
+		// can not conflict with anything in the source.
+		c.declare(stele.Let{Name: name, T: v.Type()}, x.Pos())
+		if c.locals != nil {
+			c.locals[name] = struct{}{}
+		}
+		a := &stele.Assign{ID: name, Val: v}
+		c.resolve(a)
+		block.Stmts = append(block.Stmts, a)
+		block.Pos = append(block.Pos, x.Pos())
+		return &ast.Ident{NamePos: x.Pos(), Name: name}, true
+	}
+
+	x, ok := temp(index.X, "%array")
+	if !ok {
+		return nil
+	}
+	i, ok := temp(index.Indices[0], "%index")
+	if !ok {
+		return nil
+	}
+
+	lhs := &ast.Index{X: x, Lbrack: index.Lbrack, Indices: []ast.Expr{i}, Rbrack: index.Rbrack}
+	s := c.assignIndex(&ast.Assign{
+		Lhs:    []ast.Expr{lhs},
+		TokPos: stmt.TokPos,
+		Tok:    scanner.ASSIGN,
+		Rhs:    &ast.Binary{X: lhs, OpPos: stmt.TokPos, Op: op, Y: stmt.Rhs},
+	}, lhs)
+	if s == nil {
+		return nil
+	}
+	if !inBlock {
+		return s
+	}
+
+	block.Stmts = append(block.Stmts, s)
+	block.Pos = append(block.Pos, stmt.Pos())
+	block.Slots = c.scope.Len()
+	return block
+}
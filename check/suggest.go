@@ -0,0 +1,94 @@
+package check
+
+import (
+	"strings"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+)
+
+// undefined reports that id is not defined. If something with a
+// similar name is, it is suggested as what might have been meant. If
+// isType is true, id was used as a type, and only types are suggested.
+// Otherwise, only values are.
+func (c *checker) undefined(id *ast.Ident, isType bool) {
+	if s, ok := c.suggest(id.ID(), isType); ok {
+		c.errorf(id.Pos(), "undefined: %v (did you mean %v?)", id.Name, s)
+		return
+	}
+	c.errorf(id.Pos(), "undefined: %v", id.Name)
+}
+
+// suggest returns the visible identifier that is closest to id, if any
+// are close enough that id is likely to be a typo of it. Only the
+// names of types are considered if isType is true, and only those of
+// everything else if it is not. Ties are broken alphabetically.
+func (c *checker) suggest(id string, isType bool) (string, bool) {
+	// Short identifiers are too close to too many others for a
+	// suggestion to be useful.
+	limit := len(id) / 3
+	if limit == 0 {
+		return "", false
+	}
+
+	best, bestDist := "", limit+1
+	try := func(candidate string) {
+		if strings.HasPrefix(candidate, "%") {
+			// Temporaries, such as those of compound assignments, can
+			// not be referred to.
+			return
+		}
+		d := distance(id, candidate)
+		if (d < bestDist) || ((d == bestDist) && (candidate < best)) {
+			best, bestDist = candidate, d
+		}
+	}
+
+	c.scope.All()(func(d stele.Declaration) bool {
+		if _, ok := d.(stele.TypeDecl); ok == isType {
+			try(d.ID())
+		}
+		return true
+	})
+	if isType {
+		for name := range c.types {
+			try(name)
+		}
+		return best, bestDist <= limit
+	}
+	for name := range c.funcs {
+		try(name)
+	}
+	return best, bestDist <= limit
+}
+
+// distance returns the number of single character insertions,
+// deletions, substitutions and transpositions of adjacent characters
+// needed to change a into b.
+func distance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+
+	// Only the last three rows are needed at any given time.
+	prev2 := make([]int, len(br)+1)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if (i > 1) && (j > 1) && (ar[i-1] == br[j-2]) && (ar[i-2] == br[j-1]) {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,395 @@
+package check
+
+import (
+	"strings"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+// typeInfo tracks the lowering of a package-level type declaration.
+// Type declarations are lowered on demand so that they may be used
+// before the point at which they are declared.
+type typeInfo struct {
+	decl  *ast.TypeDecl
+	state typeState
+	t     stele.TypeDecl
+}
+
+type typeState int
+
+const (
+	typeUnresolved typeState = iota
+	typeResolving
+	typeResolved
+)
+
+func (c *checker) typeDecl(info *typeInfo) (stele.TypeDecl, bool) {
+	switch info.state {
+	case typeResolved:
+		return info.t, info.t.T.Valid()
+	case typeResolving:
+		// A type may refer to itself, such as in the types of its
+		// fields and methods. The reference shares the feature list
+		// that is filled in once the definition has been lowered.
+		return info.t, true
+	}
+
+	// Types are declared at the top level, so they can only see other
+	// top-level declarations.
+	scope := c.scope
+	c.scope = c.pkgScope.Block()
+	defer func() { c.scope = scope }()
+
+	decl := info.decl
+	info.state = typeResolving
+	params, ok := c.typeParams(decl.TypeParams, true)
+	if !ok {
+		info.state = typeResolved
+		return stele.TypeDecl{}, false
+	}
+
+	// The declared type is a new name for the same set of
+	// functionality, so embedding keeps its features intact.
+	features := make([]stele.Feature, 1)
+	info.t = stele.TypeDecl{
+		Name: decl.Name.Name,
+		T: stele.Type{
+			Name:       decl.Name.Name,
+			Features:   features,
+			TypeParams: params,
+		},
+	}
+
+	t, ok := c.typeExpr(decl.Def)
+	info.state = typeResolved
+	if !ok {
+		info.t = stele.TypeDecl{}
+		return info.t, false
+	}
+	features[0] = stele.Feature{Type: stele.EmbedFeature, Return: t}
+
+	if embedsItself(info.t.T, info.t.T.Name, make(map[string]struct{})) {
+		c.errorf(decl.Pos(), "invalid recursive type %v", decl.Name.Name)
+		info.t = stele.TypeDecl{}
+		return info.t, false
+	}
+
+	return info.t, true
+}
+
+// embedsItself returns true if t embeds, directly or indirectly, the
+// type named name.
+func embedsItself(t stele.Type, name string, seen map[string]struct{}) bool {
+	for _, f := range t.Features {
+		if f.Type != stele.EmbedFeature {
+			continue
+		}
+		if f.Return.Name == name {
+			return true
+		}
+		if f.Return.Name != "" {
+			if _, ok := seen[f.Return.Name]; ok {
+				continue
+			}
+			seen[f.Return.Name] = struct{}{}
+		}
+
+		if embedsItself(f.Return, name, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeExpr lowers a type expression.
+func (c *checker) typeExpr(expr ast.Expr) (stele.Type, bool) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		t, ok := c.typeName(expr)
+		if ok && t.Generic() {
+			c.errorf(expr.Pos(), "generic type %v must be instantiated", t)
+			return stele.Type{}, false
+		}
+		return t, ok
+
+	case *ast.Index:
+		id, ok := expr.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		return c.instantiateType(expr, id)
+
+	case *ast.FuncType:
+		return c.funcType(expr)
+
+	case *ast.TupleType:
+		return c.tupleType(expr)
+
+	case *ast.TypeLit:
+		return c.typeLit(expr)
+
+	case *ast.OneofType:
+		var t stele.Type
+		ok := c.oneof(expr, &t)
+		return t, ok
+	}
+
+	c.errorf(expr.Pos(), "%v is not supported yet", describe(expr))
+	return stele.Type{}, false
+}
+
+func (c *checker) typeName(id *ast.Ident) (stele.Type, bool) {
+	switch d := c.lookup(id.Name).(type) {
+	case stele.TypeDecl:
+		return d.T, true
+	case nil:
+		if info, ok := c.types[id.Name]; ok {
+			d, ok := c.typeDecl(info)
+			return d.T, ok
+		}
+		c.undefined(id, true)
+	default:
+		c.errorf(id.Pos(), "%v is not a type", id.Name)
+	}
+	return stele.Type{}, false
+}
+
+func (c *checker) instantiateType(index *ast.Index, id *ast.Ident) (stele.Type, bool) {
+	t, ok := c.typeName(id)
+	if !ok {
+		return stele.Type{}, false
+	}
+	if len(t.TypeParams) == 0 {
+		c.errorf(index.Lbrack, "%v is not a generic type", t)
+		return stele.Type{}, false
+	}
+
+	args := make([]stele.Type, 0, len(index.Indices))
+	for _, expr := range index.Indices {
+		arg, aok := c.typeExpr(expr)
+		args = append(args, arg)
+		ok = ok && aok
+	}
+	if !ok {
+		return stele.Type{}, false
+	}
+
+	inst, err := t.Instantiate(args...)
+	if err != nil {
+		c.errorf(index.Lbrack, "cannot instantiate %v", err)
+		return stele.Type{}, false
+	}
+	return inst, true
+}
+
+func (c *checker) funcType(expr *ast.FuncType) (stele.Type, bool) {
+	ok := true
+	var args []stele.Type
+	if expr.Params != nil {
+		for _, field := range expr.Params.List {
+			if field.Type == nil {
+				c.errorf(field.Pos(), "missing type for parameter")
+				ok = false
+				continue
+			}
+
+			t, aok := c.typeExpr(field.Type)
+			for i := 0; i < max(len(field.Names), 1); i++ {
+				args = append(args, t)
+			}
+			ok = ok && aok
+		}
+	}
+
+	ret := stele.Unit
+	if expr.Result != nil {
+		t, rok := c.typeExpr(expr.Result)
+		ret = t
+		ok = ok && rok
+	}
+
+	return stele.FuncOf(stele.Feature{Args: args, Return: ret, Mut: expr.Mut}), ok
+}
+
+func (c *checker) tupleType(expr *ast.TupleType) (stele.Type, bool) {
+	ok := true
+	elems := make([]stele.Type, 0, len(expr.Elems))
+	for _, e := range expr.Elems {
+		t, eok := c.typeExpr(e)
+		elems = append(elems, t)
+		ok = ok && eok
+	}
+	return stele.TupleType(elems...), ok
+}
+
+func (c *checker) typeLit(lit *ast.TypeLit) (stele.Type, bool) {
+	var t stele.Type
+	if lit.TypeParams != nil {
+		// Anonymous types may only have the self parameter.
+		if len(lit.TypeParams.List) > 1 {
+			c.errorf(lit.TypeParams.List[1].Pos(), "anonymous types may only have a self type parameter")
+			return stele.Type{}, false
+		}
+
+		scope := c.scope
+		defer func() { c.scope = scope }()
+		c.scope = c.scope.Block()
+
+		params, ok := c.typeParams(lit.TypeParams, true)
+		if !ok {
+			return stele.Type{}, false
+		}
+		t.TypeParams = params
+	}
+
+	var oneof *ast.OneofType
+	var tuple *ast.TupleType
+	ok := true
+	for _, entry := range lit.Entries {
+		switch entry := entry.(type) {
+		case *ast.Let:
+			ft, fok := c.typeExpr(entry.Type)
+			for _, name := range entry.Names {
+				t.Features = append(t.Features, stele.Feature{
+					Type:   stele.LetFeature,
+					Name:   name.Name,
+					Return: ft,
+				})
+			}
+			ok = ok && fok
+
+		case *ast.MethodSpec:
+			f, fok := c.methodSpec(entry)
+			t.Features = append(t.Features, f)
+			ok = ok && fok
+
+		case *ast.OneofType:
+			if oneof != nil {
+				c.errorf(entry.Pos(), "type already has a oneof list at %v", oneof.Pos())
+				ok = false
+				continue
+			}
+			oneof = entry
+			ok = c.oneof(entry, &t) && ok
+
+		case *ast.TupleType:
+			// Elements are selected by index, so more than one tuple
+			// would make it unclear which one is meant.
+			if tuple != nil {
+				c.errorf(entry.Pos(), "type already has a tuple at %v", tuple.Pos())
+				ok = false
+				continue
+			}
+			tuple = entry
+			et, eok := c.tupleType(entry)
+			t.Features = append(t.Features, stele.Feature{Type: stele.EmbedFeature, Return: et})
+			ok = ok && eok
+
+		case ast.Expr:
+			et, eok := c.typeExpr(entry)
+			t.Features = append(t.Features, stele.Feature{Type: stele.EmbedFeature, Return: et})
+			ok = ok && eok
+		}
+	}
+
+	if oneof != nil {
+		for _, f := range t.Features {
+			if f.Type != stele.EmbedFeature {
+				c.errorf(lit.Pos(), "a type with a oneof list may not have fields or methods")
+				ok = false
+				break
+			}
+		}
+	}
+
+	if !t.Valid() {
+		// An empty type has no requirements, so it is the same as any.
+		t.Name = "any"
+	}
+	return t, ok
+}
+
+func (c *checker) methodSpec(spec *ast.MethodSpec) (stele.Feature, bool) {
+	f := stele.Feature{
+		Type:    stele.FuncFeature,
+		Name:    spec.Name.Name,
+		MutRecv: spec.MutRecv,
+		Mut:     spec.Type.Mut,
+	}
+
+	ok := true
+	if spec.Type.Params != nil {
+		for _, field := range spec.Type.Params.List {
+			at, aok := c.typeExpr(field.Type)
+			for i := 0; i < max(len(field.Names), 1); i++ {
+				f.Args = append(f.Args, at)
+			}
+			ok = ok && aok
+		}
+	}
+
+	f.Return = stele.Unit
+	if spec.Type.Result != nil {
+		rt, rok := c.typeExpr(spec.Type.Result)
+		f.Return = rt
+		ok = ok && rok
+	}
+
+	return f, ok
+}
+
+func (c *checker) oneof(expr *ast.OneofType, t *stele.Type) bool {
+	ok := true
+	for _, expr := range expr.Types {
+		mt, mok := c.typeExpr(expr)
+		t.Oneof = append(t.Oneof, mt)
+		ok = ok && mok
+	}
+	if !ok {
+		return false
+	}
+
+	// Members may refer to types that are still being lowered, so
+	// they can only be compared once everything has been.
+	members := t.Oneof
+	c.later(func() { c.disjoint(expr, members) })
+	return true
+}
+
+// disjoint checks that no member of a oneof list is satisfied by
+// another, as a value of the one would then match both.
+func (c *checker) disjoint(expr *ast.OneofType, members []stele.Type) {
+	for i := range members {
+		for j := range members[:i] {
+			a, b := members[j], members[i]
+			switch {
+			case a.Satisfies(b):
+				c.errorf(expr.Types[i].Pos(), "oneof members %v and %v overlap: %v satisfies %v", a, b, a, b)
+			case b.Satisfies(a):
+				c.errorf(expr.Types[i].Pos(), "oneof members %v and %v overlap: %v satisfies %v", a, b, b, a)
+			default:
+				continue
+			}
+			break
+		}
+	}
+}
+
+// assignable reports an error at pos if a value of type from can not
+// be used as a value of type to. Unknown types are assumed to have
+// already been reported and are not checked.
+func (c *checker) assignable(pos scanner.Pos, from, to stele.Type) bool {
+	if !from.Valid() || !to.Valid() {
+		return true
+	}
+
+	reasons := from.Explain(to)
+	if len(reasons) == 0 {
+		return true
+	}
+
+	c.errorf(pos, "cannot use %v as %v: %v", from, to, strings.Join(reasons, "; "))
+	return false
+}
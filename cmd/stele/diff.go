@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// diffContext is the number of unchanged lines shown around each
+// change in a diff.
+const diffContext = 3
+
+type diffLine struct {
+	op   byte
+	text []byte
+}
+
+// unifiedDiff returns a line-based diff from a to b in unified format.
+func unifiedDiff(aname, bname string, a, b []byte) []byte {
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %v\n+++ %v\n", aname, bname)
+
+	for start := 0; start < len(lines); {
+		for (start < len(lines)) && (lines[start].op == ' ') {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+
+		// Extend the hunk until there is a long enough run of unchanged
+		// lines to end it.
+		end, same := start, 0
+		for i := start; (i < len(lines)) && (same <= 2*diffContext); i++ {
+			if lines[i].op == ' ' {
+				same++
+				continue
+			}
+			end, same = i+1, 0
+		}
+
+		first := max(start-diffContext, 0)
+		last := min(end+diffContext, len(lines))
+		writeHunk(&out, lines, first, last)
+		start = last
+	}
+
+	return out.Bytes()
+}
+
+func writeHunk(out *bytes.Buffer, lines []diffLine, first, last int) {
+	var astart, bstart, alen, blen int
+	for _, line := range lines[:first] {
+		if line.op != '+' {
+			astart++
+		}
+		if line.op != '-' {
+			bstart++
+		}
+	}
+	for _, line := range lines[first:last] {
+		if line.op != '+' {
+			alen++
+		}
+		if line.op != '-' {
+			blen++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%v,%v +%v,%v @@\n", astart+1, alen, bstart+1, blen)
+	for _, line := range lines[first:last] {
+		out.WriteByte(line.op)
+		out.Write(line.text)
+		out.WriteByte('\n')
+	}
+}
+
+func splitLines(data []byte) [][]byte {
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	if len(data) == 0 {
+		return nil
+	}
+	return bytes.Split(data, []byte("\n"))
+}
+
+// diffLines computes a minimal edit script turning a into b using the
+// longest common subsequence of their lines.
+func diffLines(a, b [][]byte) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if bytes.Equal(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+				continue
+			}
+			lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for (i < len(a)) || (j < len(b)) {
+		switch {
+		case (i < len(a)) && (j < len(b)) && bytes.Equal(a[i], b[j]):
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case (j == len(b)) || ((i < len(a)) && (lcs[i+1][j] >= lcs[i][j+1])):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines
+}
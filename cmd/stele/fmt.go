@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"deedles.dev/stele/format"
+)
+
+func runFmt(args []string) int {
+	fset := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fset.Bool("w", false, "write the result to the source file instead of standard output")
+	diff := fset.Bool("d", false, "print diffs instead of the formatted source")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage: stele fmt [-w] [-d] [files...]\n\nWith no files, fmt formats standard input.\n\n")
+		fset.PrintDefaults()
+	}
+	fset.Parse(args)
+
+	if fset.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			return 2
+		}
+
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if err := formatFile("<standard input>", src, false, *diff); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range fset.Args() {
+		src, err := os.ReadFile(path)
+		if err == nil {
+			err = formatFile(path, src, *write, *diff)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", path, err)
+			status = 2
+		}
+	}
+	return status
+}
+
+// formatFile formats src, which was read from the file at path, and
+// handles the output as requested by write and diff.
+func formatFile(path string, src []byte, write, diff bool) error {
+	res, err := format.Source(src)
+	if err != nil {
+		return err
+	}
+
+	if diff && !bytes.Equal(src, res) {
+		fmt.Printf("diff %v %v.formatted\n", path, path)
+		os.Stdout.Write(unifiedDiff(path, path+".formatted", src, res))
+	}
+
+	if write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, res, info.Mode().Perm())
+	}
+
+	if !diff {
+		_, err = os.Stdout.Write(res)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+// Command stele is the Stele command-line tool.
+//
+// Usage:
+//
+//	stele <command> [arguments]
+//
+// The commands are:
+//
+//	fmt    format Stele source files
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "fmt", short: "format Stele source files", run: runFmt},
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %v <command> [arguments]\n\nThe commands are:\n\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-6v %v\n", cmd.name, cmd.short)
+	}
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			os.Exit(cmd.run(flag.Args()[1:]))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command: %q\n", name)
+	usage()
+	os.Exit(2)
+}
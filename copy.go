@@ -0,0 +1,168 @@
+package stele
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Arrays and structs are the only values that can be changed in place,
+// and they are shared by reference between mutable variables. When one
+// is assigned from an immutable variable to a mutable one, or the
+// other way round, it is copied, so that nothing can change it through
+// a variable that it is immutable in.
+//
+// Copies are made lazily. A copy shares its elements with the original
+// until either of them is changed, at which point the one that changes
+// takes a copy of the elements for itself. Elements that are arrays or
+// structs themselves are copied in the same way, so each level of a
+// value is only copied when something inside of it changes.
+
+// Slice is the run-time representation of an array. Elems may be read
+// directly, but the array must only be changed through its methods.
+type Slice struct {
+	Elems []Value
+
+	// shared is true if Elems may be shared with a copy of the array.
+	shared bool
+}
+
+// Get returns the element at index i.
+func (s *Slice) Get(i int) Value {
+	if s.shared && s.Elems[i].mutable() {
+		// The element may be changed through what it is returned to, so
+		// it needs to be the array's own.
+		s.unshare()
+	}
+	return s.Elems[i]
+}
+
+// Set sets the element at index i to v.
+func (s *Slice) Set(i int, v Value) {
+	s.unshare()
+	s.Elems[i] = v
+}
+
+// Append adds v to the end of the array.
+func (s *Slice) Append(v Value) {
+	s.unshare()
+	s.Elems = append(s.Elems, v)
+}
+
+// Copy returns a copy of the array.
+func (s *Slice) Copy() *Slice {
+	s.shared = true
+	return &Slice{Elems: s.Elems, shared: true}
+}
+
+func (s *Slice) String() string {
+	return fmt.Sprint(s.Elems)
+}
+
+// unshare gives s its own copy of its elements if they may be shared.
+func (s *Slice) unshare() {
+	if !s.shared {
+		return
+	}
+	elems := make([]Value, len(s.Elems))
+	for i, e := range s.Elems {
+		elems[i] = e.Copy()
+	}
+	s.Elems, s.shared = elems, false
+}
+
+// Struct is the run-time representation of a value with fields, keyed
+// by name.
+type Struct struct {
+	fields map[string]Value
+
+	// shared is true if fields may be shared with a copy of the
+	// struct.
+	shared bool
+}
+
+// NewStruct returns a struct with the given fields, which it takes
+// ownership of.
+func NewStruct(fields map[string]Value) *Struct {
+	return &Struct{fields: fields}
+}
+
+// Field returns the value of the field name, or false if the struct
+// does not have one.
+func (s *Struct) Field(name string) (Value, bool) {
+	v, ok := s.fields[name]
+	if ok && s.shared && v.mutable() {
+		s.unshare()
+		v = s.fields[name]
+	}
+	return v, ok
+}
+
+// SetField sets the field name to v.
+func (s *Struct) SetField(name string, v Value) {
+	s.unshare()
+	s.fields[name] = v
+}
+
+// Copy returns a copy of the struct.
+func (s *Struct) Copy() *Struct {
+	s.shared = true
+	return &Struct{fields: s.fields, shared: true}
+}
+
+func (s *Struct) String() string {
+	return fmt.Sprint(s.fields)
+}
+
+// unshare gives s its own copy of its fields if they may be shared.
+func (s *Struct) unshare() {
+	if !s.shared {
+		return
+	}
+	fields := make(map[string]Value, len(s.fields))
+	for name, v := range s.fields {
+		fields[name] = v.Copy()
+	}
+	s.fields, s.shared = fields, false
+}
+
+// Copy returns a copy of v that can be changed without changing v, and
+// that v can be changed without changing. Only arrays and structs, and
+// tuples that contain them, are actually copied, as nothing else can
+// be changed in place.
+func (v Value) Copy() Value {
+	if v.kind != RefKind {
+		return v
+	}
+	switch r := v.ref.(type) {
+	case *Slice:
+		v.ref = r.Copy()
+	case *Struct:
+		v.ref = r.Copy()
+	case []Value:
+		// Tuples can not be changed, so they are copied right away,
+		// but only if there is anything in them that needs to be.
+		if slices.ContainsFunc(r, Value.mutable) {
+			elems := make([]Value, len(r))
+			for i, e := range r {
+				elems[i] = e.Copy()
+			}
+			v.ref = elems
+		}
+	}
+	return v
+}
+
+// mutable returns true if v is an array or struct, or a tuple that
+// contains one, which could be changed in place.
+func (v Value) mutable() bool {
+	if v.kind != RefKind {
+		return false
+	}
+	switch r := v.ref.(type) {
+	case *Slice, *Struct:
+		return true
+	case []Value:
+		return slices.ContainsFunc(r, Value.mutable)
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package stele
+
+import "testing"
+
+func TestCopy(t *testing.T) {
+	d := NewDescs().Intern(Any)
+	point := func(x int64) Value {
+		return ValueOf(d, NewStruct(map[string]Value{"x": MakeInt(intDesc, x)}))
+	}
+	x := func(p Value) int64 {
+		v, _ := p.Interface().(*Struct).Field("x")
+		return v.Int()
+	}
+
+	orig := ValueOf(d, &Slice{Elems: []Value{point(1), point(2)}})
+	c := orig.Copy()
+	s := c.Interface().(*Slice)
+	if &s.Elems[0] != &orig.Interface().(*Slice).Elems[0] {
+		t.Fatal("copy did not share elements with the original")
+	}
+
+	p := s.Get(0)
+	p.Interface().(*Struct).SetField("x", MakeInt(intDesc, 10))
+	s.Append(point(3))
+
+	o := orig.Interface().(*Slice)
+	if (len(o.Elems) != 2) || (x(o.Get(0)) != 1) {
+		t.Fatalf("original changed: %v", o)
+	}
+	if (len(s.Elems) != 3) || (x(s.Get(0)) != 10) {
+		t.Fatalf("copy did not change: %v", s)
+	}
+
+	// Changing the original after the copy has been changed should not
+	// change the copy either.
+	o.Get(1).Interface().(*Struct).SetField("x", MakeInt(intDesc, 20))
+	if x(s.Get(1)) != 2 {
+		t.Fatalf("copy changed: %v", s)
+	}
+
+	tuple := ValueOf(d, []Value{point(1), MakeInt(intDesc, 2)})
+	tc := tuple.Copy()
+	tc.Interface().([]Value)[0].Interface().(*Struct).SetField("x", MakeInt(intDesc, 5))
+	if x(tuple.Interface().([]Value)[0]) != 1 {
+		t.Fatalf("original tuple changed: %v", tuple)
+	}
+}
@@ -0,0 +1,103 @@
+package stele
+
+import "strings"
+
+// Import is a declaration introduced by importing another package.
+// Decls is the declarations exported by the imported package.
+type Import struct {
+	Name  string
+	Path  string
+	Decls []Declaration
+}
+
+func (d Import) ID() string { return d.Name }
+
+// Type returns the type of the imported module. The module's exported
+// declarations are its features.
+func (d Import) Type() Type {
+	t := Type{Name: d.Path}
+	for _, decl := range d.Decls {
+		t.Features = append(t.Features, Feature{
+			Type:   LetFeature,
+			Name:   decl.ID(),
+			Return: decl.Type(),
+		})
+	}
+	return t
+}
+
+func (d Import) Mutable() bool  { return false }
+func (d Import) Exported() bool { return false }
+
+// Let is a declaration of a variable. Name is the name as it was
+// declared, including a trailing ! if the variable is immutable. Param
+// is true if the variable is a parameter or receiver of a function,
+// which may not be assigned to even if it is mutable.
+type Let struct {
+	Name   string
+	T      Type
+	Assign *Assign
+	Param  bool
+}
+
+func (d Let) ID() string     { return strings.TrimSuffix(d.Name, "!") }
+func (d Let) Type() Type     { return d.T }
+func (d Let) Mutable() bool  { return !strings.HasSuffix(d.Name, "!") }
+func (d Let) Exported() bool { return !strings.HasPrefix(d.Name, "_") }
+
+// TypeDecl is a declaration of a named type.
+type TypeDecl struct {
+	Name string
+	T    Type
+}
+
+func (d TypeDecl) ID() string     { return d.Name }
+func (d TypeDecl) Type() Type     { return d.T }
+func (d TypeDecl) Mutable() bool  { return false }
+func (d TypeDecl) Exported() bool { return !strings.HasPrefix(d.Name, "_") }
+
+// Func is a declaration of a function. If the function is a method,
+// Recv is its receiver. Params is the names of the function's
+// parameters in the order that they are declared, and Immutable is the
+// indices in Params of those that are immutable. Their arguments, and
+// the receiver if it is immutable, are copied when the function is
+// called, as the caller may have passed them from mutable variables.
+//
+// Slots is the size of the Frame that the function's receiver and
+// parameters are kept in while it runs. The receiver, if it is named,
+// and the parameters are in its last slots, in that order. The frame
+// of Body is inside of it.
+//
+// If the type of a method's receiver is one of its type parameters,
+// Attached is the names of the types that the method is attached to.
+type Func struct {
+	Name      string
+	Recv      *Let
+	T         Type
+	Params    []string
+	Immutable []int
+	Slots     int
+	Body      Block
+	Attached  []string
+
+	// Desc is the description of T. Unit is true if the function
+	// returns unit, in which case whatever its body results in is
+	// discarded. Both are filled in by the checker so that they do not
+	// have to be worked out each time that the function is called.
+	Desc *TypeDesc
+	Unit bool
+}
+
+// RecvTypes returns the names of the types that the method f is
+// attached to.
+func (d Func) RecvTypes() []string {
+	if d.Recv.T.Param {
+		return d.Attached
+	}
+	return []string{d.Recv.T.String()}
+}
+
+func (d Func) ID() string     { return d.Name }
+func (d Func) Type() Type     { return d.T }
+func (d Func) Mutable() bool  { return false }
+func (d Func) Exported() bool { return !strings.HasPrefix(d.Name, "_") }
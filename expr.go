@@ -0,0 +1,335 @@
+package stele
+
+import "deedles.dev/stele/scanner"
+
+// Ident is a reference to a declared variable. Ref is where the
+// variable is kept at run time.
+type Ident struct {
+	ID  string
+	T   Type
+	Ref Ref
+}
+
+func (i Ident) Type() Type {
+	return i.T
+}
+
+func (i Ident) Eval(state *State) Value {
+	return state.Get(i.ID, i.Ref)
+}
+
+// Call is a call of a function. T is the type returned by the call
+// after any type arguments have been substituted in.
+type Call struct {
+	Func Expr
+	Args []Expr
+	T    Type
+}
+
+func (c Call) Type() Type {
+	return c.T
+}
+
+func (c Call) Eval(state *State) Value {
+	f := c.Func.Eval(state).Interface().(Function)
+	args := make([]Value, 0, len(c.Args))
+	for _, arg := range c.Args {
+		args = append(args, arg.Eval(state))
+	}
+	return f.Call(state, args)
+}
+
+// Selector selects a field or method of a value, or a member of an
+// imported module. If the method is one that is attached to the static
+// type of X, Recv is the name of that type. Otherwise, the method is
+// found from the type of X's value when it is called. If a method is
+// selected, Desc is the description of T.
+type Selector struct {
+	X    Expr
+	Name string
+	T    Type
+	Recv string
+	Desc *TypeDesc
+}
+
+func (s Selector) Type() Type {
+	return s.T
+}
+
+func (s Selector) Eval(state *State) Value {
+	x := s.X.Eval(state)
+	if s.Recv == "" {
+		if fields, ok := x.Interface().(*Struct); ok {
+			if v, ok := fields.Field(s.Name); ok {
+				return v
+			}
+		}
+	}
+	return ValueOf(state.desc(s.Desc, s.T), Function(boundMethod{recv: x, name: s.Name, typ: s.Recv}))
+}
+
+// If is an if expression. Else is nil, an If, or a Block. T is a
+// oneof of the types of each branch.
+type If struct {
+	Cond Expr
+	Body Block
+	Else Expr
+	T    Type
+}
+
+func (i If) Type() Type {
+	return i.T
+}
+
+func (i If) Eval(state *State) Value {
+	if i.Cond.Eval(state).Bool() {
+		return i.Body.Eval(state)
+	}
+	if i.Else != nil {
+		return i.Else.Eval(state)
+	}
+	return UnitValue
+}
+
+// Switch is a switch expression. Tag is nil if the switch has no tag.
+// T is a oneof of the types of each case.
+type Switch struct {
+	Tag   Expr
+	Cases []Case
+	T     Type
+}
+
+func (s Switch) Type() Type {
+	return s.T
+}
+
+func (s Switch) Eval(state *State) Value {
+	var tag Value
+	if s.Tag != nil {
+		tag = s.Tag.Eval(state)
+	}
+	for _, c := range s.Cases {
+		if c.matches(state, tag) {
+			return c.Body.Eval(state)
+		}
+	}
+	return UnitValue
+}
+
+// Case is a single case of a switch. If Assert is valid, the case
+// matches values of the switch's tag that can be asserted to it. If
+// Else is true, the case matches anything. Otherwise, the case matches
+// if comparing the tag to Value with Op is true or, if the switch has
+// no tag, if Value is true. Desc is the description of Assert.
+type Case struct {
+	Assert Type
+	Desc   *TypeDesc
+	Else   bool
+	Op     scanner.Type
+	Value  Expr
+	Body   Block
+}
+
+// matches returns true if the case matches tag, which is not valid if
+// the switch has no tag.
+func (c Case) matches(state *State, tag Value) bool {
+	switch {
+	case c.Else:
+		return true
+	case c.Assert.Valid():
+		_, ok := tag.Assert(state.desc(c.Desc, c.Assert))
+		return ok
+	case !tag.Valid():
+		return c.Value.Eval(state).Bool()
+	}
+
+	op := c.Op
+	if op == 0 {
+		op = scanner.EQUAL
+	}
+	name, swap, negate, _ := OperatorMethod(op, false)
+	return operate(state, name, swap, negate, tag, c.Value.Eval(state)).Bool()
+}
+
+// TypeAssert checks whether the value of X can be asserted to the
+// type Assert, whose description is Desc. Its own value is a bool.
+type TypeAssert struct {
+	X      Expr
+	Assert Type
+	Desc   *TypeDesc
+}
+
+func (a TypeAssert) Type() Type {
+	return Bool
+}
+
+func (a TypeAssert) Eval(state *State) Value {
+	_, ok := a.X.Eval(state).Assert(state.desc(a.Desc, a.Assert))
+	return MakeBool(boolDesc, ok)
+}
+
+// Narrow is the value of X asserted to T. It is the value that an
+// identifier has in the body of an if or switch case that asserted its
+// type. If the assertion fails, it panics with a *RuntimeError. Desc
+// is the description of T.
+type Narrow struct {
+	X    Expr
+	T    Type
+	Desc *TypeDesc
+}
+
+func (n Narrow) Type() Type {
+	return n.T
+}
+
+func (n Narrow) Eval(state *State) Value {
+	x := n.X.Eval(state)
+	r, ok := x.Assert(state.desc(n.Desc, n.T))
+	if !ok {
+		state.panicf("%v can not be asserted to %v", x.desc, n.T)
+	}
+	return r
+}
+
+// Try is the use of the ? operator on X. If the value of X is an
+// error, the function that it is in returns it as a value of type Ret.
+// Otherwise, its value is the value of X asserted to T, the type of the
+// values of X that are not errors. Desc and RetDesc are the
+// descriptions of T and Ret.
+type Try struct {
+	X       Expr
+	T       Type
+	Ret     Type
+	Desc    *TypeDesc
+	RetDesc *TypeDesc
+}
+
+func (t Try) Type() Type {
+	return t.T
+}
+
+func (t Try) Eval(state *State) Value {
+	x := t.X.Eval(state)
+	if err, ok := x.Assert(errorDesc); ok {
+		panic(returning{val: err.Convert(state.desc(t.RetDesc, t.Ret))})
+	}
+	r, ok := x.Assert(state.desc(t.Desc, t.T))
+	if !ok {
+		state.panicf("%v can not be asserted to %v", x.desc, t.T)
+	}
+	return r
+}
+
+// Convert is the use of the value of X as a value of type T, such as
+// by assigning it to a variable of that type. Unless X is already of
+// type T, T is added to the chain of types that the value has had.
+// Desc is the description of T.
+type Convert struct {
+	X    Expr
+	T    Type
+	Desc *TypeDesc
+}
+
+func (c Convert) Type() Type {
+	return c.T
+}
+
+func (c Convert) Eval(state *State) Value {
+	return c.X.Eval(state).Convert(state.desc(c.Desc, c.T))
+}
+
+// Copy is a copy of the value of X, which is made when it is assigned
+// from an immutable variable to a mutable one or the other way round.
+type Copy struct {
+	X Expr
+}
+
+func (c Copy) Type() Type {
+	return c.X.Type()
+}
+
+func (c Copy) Eval(state *State) Value {
+	return c.X.Eval(state).Copy()
+}
+
+// Binary is a binary operation. Method is the name of the method of
+// X's type that the operator maps to, which is called with Y. If Swap
+// is set, it is instead called on Y with X, and if Negate is set, its
+// result is negated. The logical operators, && and ||, have no method,
+// as they only evaluate Y if they need to.
+type Binary struct {
+	Op     scanner.Type
+	X, Y   Expr
+	Method string
+	Swap   bool
+	Negate bool
+	T      Type
+}
+
+func (b Binary) Type() Type {
+	return b.T
+}
+
+func (b Binary) Eval(state *State) Value {
+	x := b.X.Eval(state)
+	switch b.Op {
+	case scanner.AND:
+		if !x.Bool() {
+			return x
+		}
+		return b.Y.Eval(state)
+	case scanner.OR:
+		if x.Bool() {
+			return x
+		}
+		return b.Y.Eval(state)
+	}
+
+	return operate(state, b.Method, b.Swap, b.Negate, x, b.Y.Eval(state))
+}
+
+// operate calls the method name of x with y, or of y with x if swap is
+// true, negating the result if negate is true.
+func operate(state *State, name string, swap, negate bool, x, y Value) Value {
+	if swap {
+		x, y = y, x
+	}
+	r := state.callMethod(x, name, y)
+	if negate {
+		r = MakeBool(boolDesc, !r.Bool())
+	}
+	return r
+}
+
+// Unary is a unary operation. Method is the name of the method of X's
+// type that the operator maps to.
+type Unary struct {
+	Op     scanner.Type
+	X      Expr
+	Method string
+	T      Type
+}
+
+func (u Unary) Type() Type {
+	return u.T
+}
+
+func (u Unary) Eval(state *State) Value {
+	return state.callMethod(u.X.Eval(state), u.Method)
+}
+
+// Index is the selection of an element of an array or string. It calls
+// the get method of X's type with Index.
+type Index struct {
+	X     Expr
+	Index Expr
+	T     Type
+}
+
+func (i Index) Type() Type {
+	return i.T
+}
+
+func (i Index) Eval(state *State) Value {
+	return state.callMethod(i.X.Eval(state), "get", i.Index.Eval(state))
+}
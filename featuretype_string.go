@@ -12,11 +12,12 @@ func _() {
 	_ = x[LetFeature-1]
 	_ = x[FuncFeature-2]
 	_ = x[MemLayoutFeature-3]
+	_ = x[EmbedFeature-4]
 }
 
-const _FeatureType_name = "InvalidFeatureLetFeatureFuncFeatureMemLayoutFeature"
+const _FeatureType_name = "InvalidFeatureLetFeatureFuncFeatureMemLayoutFeatureEmbedFeature"
 
-var _FeatureType_index = [...]uint8{0, 14, 24, 35, 51}
+var _FeatureType_index = [...]uint8{0, 14, 24, 35, 51, 63}
 
 func (i FeatureType) String() string {
 	if i < 0 || i >= FeatureType(len(_FeatureType_index)-1) {
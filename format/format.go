@@ -0,0 +1,38 @@
+// Package format prints Stele syntax trees as source code in the
+// canonical Stele style.
+//
+// The canonical style indents with tabs, surrounds binary operators
+// with spaces, omits semicolons wherever a newline can take their
+// place, and always puts an opening brace on the same line as the
+// construct that it belongs to. Comments and single blank lines are
+// kept. Formatting already formatted source produces the same source.
+package format
+
+import (
+	"bytes"
+	"io"
+
+	"deedles.dev/stele/parser"
+	"deedles.dev/stele/parser/ast"
+)
+
+// File writes the canonical source form of file to w.
+func File(w io.Writer, file *ast.File) error {
+	p := printer{comments: file.Comments}
+	p.file(file)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// Source parses src as a Stele source file and returns it in the
+// canonical style.
+func Source(src []byte) ([]byte, error) {
+	file, err := parser.Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = File(&buf, file)
+	return buf.Bytes(), err
+}
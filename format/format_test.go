@@ -0,0 +1,102 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		out  string
+	}{
+		{
+			name: "Spacing",
+			src:  "let   v=a+b*-c",
+			out:  "let v = a + b * -c\n",
+		},
+		{
+			name: "Semicolons",
+			src:  "func main() { let a = 1; let b = 2; a = b; }",
+			out:  "func main() {\n\tlet a = 1\n\tlet b = 2\n\ta = b\n}\n",
+		},
+		{
+			name: "Indentation",
+			src:  "func f() int {\n        if x {\n  return 1\n }\n    2\n}",
+			out:  "func f() int {\n\tif x {\n\t\treturn 1\n\t}\n\t2\n}\n",
+		},
+		{
+			name: "InlineBlock",
+			src:  "func double(v int) int {   v*2   }",
+			out:  "func double(v int) int { v * 2 }\n",
+		},
+		{
+			name: "EmptyBlock",
+			src:  "func main() {\n\n}",
+			out:  "func main() {}\n",
+		},
+		{
+			name: "Comments",
+			src:  "# doc\nlet v = 3   # trailing\n\n\n# separate\nlet w = 4\n",
+			out:  "# doc\nlet v = 3 # trailing\n\n# separate\nlet w = 4\n",
+		},
+		{
+			name: "BlankBetweenFuncs",
+			src:  "let v = 3\nfunc a() {}\nfunc b() {}",
+			out:  "let v = 3\n\nfunc a() {}\n\nfunc b() {}\n",
+		},
+		{
+			name: "Types",
+			src:  "type [T]  example{\nfunc mut add( T )T\nlet v  (string,int)\n}",
+			out:  "type [T] example {\n\tfunc mut add(T) T\n\tlet v (string, int)\n}\n",
+		},
+		{
+			name: "FuncTypes",
+			src:  "let f ->(int,int)int\nlet g -> mut\nlet h = ->(a,b){a+b}",
+			out:  "let f -> (int, int) int\nlet g -> mut\nlet h = -> (a, b) { a + b }\n",
+		},
+		{
+			name: "Switch",
+			src:  "func fib(n int) int { switch n { <= 1 { n }; else { fib(n-1)+fib(n-2) } } }",
+			out:  "func fib(n int) int {\n\tswitch n {\n\t\t<= 1 { n }\n\t\telse { fib(n - 1) + fib(n - 2) }\n\t}\n}\n",
+		},
+		{
+			name: "Literals",
+			src:  "let v = (\"a\\t\\\"b\\\"\", 'x', '\\n', 2.0, [1,2])",
+			out:  "let v = (\"a\\t\\\"b\\\"\", 'x', '\\n', 2.0, [1, 2])\n",
+		},
+		{
+			name: "MultilineArgs",
+			src:  "func main() {\n\tf(\n\t\ta |> b()\n\t\t|> c(),\n\t\td)\n}",
+			out:  "func main() {\n\tf(\n\t\ta |> b()\n\t\t\t|> c(),\n\t\td,\n\t)\n}\n",
+		},
+		{
+			name: "Try",
+			src:  "func f() result[int] { let v = g() ?\nv.h()?.i }",
+			out:  "func f() result[int] {\n\tlet v = g()?\n\tv.h()?.i\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			out, err := Source([]byte(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != test.out {
+				t.Fatalf("output doesn't match\n\tgot: %q\n\texpected: %q", out, test.out)
+			}
+
+			again, err := Source(out)
+			if err != nil {
+				t.Fatalf("reparse formatted source: %v", err)
+			}
+			if string(again) != string(out) {
+				t.Fatalf("formatting is not idempotent\n\tfirst: %q\n\tsecond: %q", out, again)
+			}
+		})
+	}
+}
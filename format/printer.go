@@ -0,0 +1,760 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+type printer struct {
+	buf      bytes.Buffer
+	comments []*ast.Comment
+
+	indent    int
+	lineStart bool
+
+	// line is the source line of the most recently printed token.
+	line int
+
+	// cont is true if the current line-level construct has been broken
+	// across lines and the continuation lines have been indented.
+	cont bool
+}
+
+// write writes s to the output, indenting first if s starts a new
+// line.
+func (p *printer) write(s string) {
+	if s == "" {
+		return
+	}
+	if p.lineStart || (p.buf.Len() == 0) {
+		for i := 0; i < p.indent; i++ {
+			p.buf.WriteByte('\t')
+		}
+		p.lineStart = false
+	}
+	p.buf.WriteString(s)
+}
+
+// endLine ends the current output line if anything has been written
+// to it.
+func (p *printer) endLine() {
+	if p.lineStart || (p.buf.Len() == 0) {
+		return
+	}
+	p.buf.WriteByte('\n')
+	p.lineStart = true
+}
+
+// blank inserts a blank line, unless there already is one.
+func (p *printer) blank() {
+	p.endLine()
+	if (p.buf.Len() == 0) || bytes.HasSuffix(p.buf.Bytes(), []byte("\n\n")) {
+		return
+	}
+	p.buf.WriteByte('\n')
+}
+
+// token prints s as a token located at pos in the source, first
+// printing any comments that came before it.
+func (p *printer) token(pos scanner.Pos, s string) {
+	p.flush(pos, false)
+	p.write(s)
+	if pos.IsValid() {
+		p.line = pos.Line
+	}
+}
+
+func before(a, b scanner.Pos) bool {
+	return (a.Line < b.Line) || ((a.Line == b.Line) && (a.Col < b.Col))
+}
+
+// flush prints all remaining comments that come before pos. If blanks
+// is true, single blank lines between them and the surrounding code
+// are kept.
+func (p *printer) flush(pos scanner.Pos, blanks bool) {
+	if !pos.IsValid() {
+		return
+	}
+
+	for (len(p.comments) > 0) && before(p.comments[0].Hash, pos) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		switch {
+		case !p.lineStart && (p.buf.Len() > 0) && (c.Hash.Line == p.line):
+			p.write(" ")
+		case blanks && (p.line > 0) && (c.Hash.Line > p.line+1):
+			p.blank()
+		default:
+			p.endLine()
+		}
+		p.write(c.Text)
+		p.endLine()
+		p.line = c.Hash.Line
+	}
+
+	if blanks && (p.line > 0) && (pos.Line > p.line+1) {
+		p.blank()
+	}
+}
+
+// leading prepares to print a node that is an element of a list of
+// lines, such as a statement in a block. If first is false, a blank
+// line before the node is kept.
+func (p *printer) leading(pos scanner.Pos, first bool) {
+	p.endLine()
+	p.flush(pos, !first)
+}
+
+// trailing ends a line, printing a comment on the same source line as
+// the last token if there is one.
+func (p *printer) trailing() {
+	if (len(p.comments) > 0) && (p.comments[0].Hash.Line == p.line) {
+		p.write(" " + p.comments[0].Text)
+		p.comments = p.comments[1:]
+	}
+	p.endLine()
+}
+
+// scope runs f as a new line-level construct, undoing any continuation
+// indentation that f adds once it is done.
+func (p *printer) scope(f func()) {
+	cont := p.cont
+	p.cont = false
+	defer func() {
+		if p.cont {
+			p.indent--
+		}
+		p.cont = cont
+	}()
+
+	f()
+}
+
+// breakLine continues the current construct on a new line.
+func (p *printer) breakLine() {
+	p.endLine()
+	if !p.cont {
+		p.indent++
+		p.cont = true
+	}
+}
+
+func (p *printer) file(file *ast.File) {
+	var prev ast.Decl
+	for _, decl := range file.Decls {
+		if (prev != nil) && (hasBody(prev) || hasBody(decl)) {
+			p.blank()
+		}
+		p.leading(decl.Pos(), prev == nil)
+		p.scope(func() { p.decl(decl) })
+		p.trailing()
+		prev = decl
+	}
+
+	p.flush(scanner.Pos{Line: 1 << 30}, true)
+
+	// Make sure that the file ends with exactly one newline.
+	src := bytes.TrimRight(p.buf.Bytes(), "\n")
+	p.buf.Truncate(len(src))
+	if len(src) > 0 {
+		p.buf.WriteByte('\n')
+	}
+}
+
+// hasBody returns true if decl is a declaration that normally spans
+// multiple lines.
+func hasBody(decl ast.Decl) bool {
+	switch decl := decl.(type) {
+	case *ast.Func:
+		return true
+	case *ast.TypeDecl:
+		switch def := decl.Def.(type) {
+		case *ast.TypeLit:
+			return def.Lbrace.Line != def.Rbrace.Line
+		case *ast.OneofType:
+			return def.Lbrace.Line != def.Rbrace.Line
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func (p *printer) decl(decl ast.Decl) {
+	switch decl := decl.(type) {
+	case *ast.Import:
+		p.token(decl.Import, "import ")
+		p.expr(decl.Path)
+		if decl.Name != nil {
+			p.write(" as ")
+			p.expr(decl.Name)
+		}
+
+	case *ast.Let:
+		p.let(decl)
+
+	case *ast.Func:
+		p.token(decl.Func, "func ")
+		if decl.TypeParams != nil {
+			p.typeParams(decl.TypeParams)
+			p.write(" ")
+		}
+		if decl.Recv != nil {
+			p.write("(")
+			p.field(decl.Recv)
+			p.write(") ")
+		}
+		p.expr(decl.Name)
+		p.signature(decl.Type)
+		p.write(" ")
+		p.block(decl.Body)
+
+	case *ast.TypeDecl:
+		p.token(decl.Type, "type ")
+		if decl.TypeParams != nil {
+			p.typeParams(decl.TypeParams)
+			p.write(" ")
+		}
+		p.expr(decl.Name)
+		p.write(" ")
+		p.expr(decl.Def)
+
+	default:
+		panic(fmt.Errorf("unexpected declaration type: %T", decl))
+	}
+}
+
+func (p *printer) let(let *ast.Let) {
+	p.token(let.Let, "let ")
+	p.identList(let.Names)
+	if let.Type != nil {
+		p.write(" ")
+		p.expr(let.Type)
+	}
+	if let.Value != nil {
+		p.write(" = ")
+		p.expr(let.Value)
+	}
+}
+
+func (p *printer) identList(list []*ast.Ident) {
+	for i, id := range list {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(id)
+	}
+}
+
+func (p *printer) typeParams(list *ast.TypeParamList) {
+	p.token(list.Lbrack, "[")
+	for i, param := range list.List {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(param.Name)
+		if param.Constraint != nil {
+			p.write(" ")
+			p.expr(param.Constraint)
+		}
+	}
+	p.token(list.Rbrack, "]")
+}
+
+// signature prints a function's parameters, mutability, and result.
+func (p *printer) signature(sig *ast.FuncType) {
+	if sig.Params != nil {
+		p.token(sig.Params.Lparen, "(")
+		for i, field := range sig.Params.List {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.field(field)
+		}
+		p.token(sig.Params.Rparen, ")")
+	}
+	if sig.Mut {
+		p.write(" mut")
+	}
+	if sig.Result != nil {
+		p.write(" ")
+		p.expr(sig.Result)
+	}
+}
+
+func (p *printer) field(field *ast.Field) {
+	p.identList(field.Names)
+	if field.Type != nil {
+		if len(field.Names) > 0 {
+			p.write(" ")
+		}
+		p.expr(field.Type)
+	}
+}
+
+// inline returns true if a braced construct was written on a single
+// line and has no more than limit entries, in which case it is printed
+// on a single line. Empty constructs are always printed on a single
+// line unless they contain comments.
+func (p *printer) inline(lbrace, rbrace scanner.Pos, n, limit int) bool {
+	if n > limit {
+		return false
+	}
+	if n == 0 {
+		return (len(p.comments) == 0) || !before(p.comments[0].Hash, rbrace) || before(p.comments[0].Hash, lbrace)
+	}
+	return lbrace.Line == rbrace.Line
+}
+
+func (p *printer) block(block *ast.Block) {
+	if p.inline(block.Lbrace, block.Rbrace, len(block.Stmts), 1) && !hasSwitch(block) {
+		p.token(block.Lbrace, "{")
+		if len(block.Stmts) > 0 {
+			p.write(" ")
+			p.stmt(block.Stmts[0])
+			p.write(" ")
+		}
+		p.token(block.Rbrace, "}")
+		return
+	}
+
+	p.token(block.Lbrace, "{")
+	p.indent++
+	for i, stmt := range block.Stmts {
+		p.leading(stmt.Pos(), i == 0)
+		p.scope(func() { p.stmt(stmt) })
+		p.trailing()
+	}
+	p.closing(block.Rbrace)
+}
+
+// hasSwitch returns true if node contains a switch, which is always
+// printed across multiple lines.
+func hasSwitch(node ast.Node) (found bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		_, ok := n.(*ast.Switch)
+		found = found || ok
+		return !found
+	})
+	return found
+}
+
+// closing prints the closing brace of a multi-line braced construct,
+// undoing the indentation of its contents.
+func (p *printer) closing(rbrace scanner.Pos) {
+	p.endLine()
+	p.flush(rbrace, false)
+	p.indent--
+	p.token(rbrace, "}")
+}
+
+func (p *printer) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.Let:
+		p.let(stmt)
+
+	case *ast.ExprStmt:
+		p.expr(stmt.X)
+
+	case *ast.Assign:
+		p.exprList(stmt.Lhs)
+		p.write(" ")
+		p.token(stmt.TokPos, stmt.Tok.Text())
+		p.write(" ")
+		p.expr(stmt.Rhs)
+
+	case *ast.Return:
+		p.token(stmt.Return, "return")
+		if stmt.Result != nil {
+			p.write(" ")
+			p.expr(stmt.Result)
+		}
+
+	case *ast.Branch:
+		p.token(stmt.TokPos, stmt.Tok.Text())
+
+	case *ast.For:
+		p.token(stmt.For, "for ")
+		if stmt.Cond != nil {
+			p.expr(stmt.Cond)
+			p.write(" ")
+		}
+		p.block(stmt.Body)
+
+	case *ast.Block:
+		p.block(stmt)
+
+	default:
+		panic(fmt.Errorf("unexpected statement type: %T", stmt))
+	}
+}
+
+func (p *printer) exprList(list []ast.Expr) {
+	for i, x := range list {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(x)
+	}
+}
+
+// args prints a delimited, comma-separated list of expressions. If the
+// first element was on a later line than the opening delimiter, each
+// element is printed on its own line.
+func (p *printer) args(open scanner.Pos, list []ast.Expr, close scanner.Pos, delims string) {
+	p.token(open, delims[:1])
+	if (len(list) == 0) || (list[0].Pos().Line == open.Line) {
+		p.exprList(list)
+		p.token(close, delims[1:])
+		return
+	}
+
+	p.indent++
+	for i, x := range list {
+		p.leading(x.Pos(), i == 0)
+		p.scope(func() { p.expr(x) })
+		p.write(",")
+		p.trailing()
+	}
+	p.endLine()
+	p.flush(close, false)
+	p.indent--
+	p.token(close, delims[1:])
+}
+
+func (p *printer) expr(expr ast.Expr) {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		p.token(x.NamePos, x.Name)
+
+	case *ast.BasicLit:
+		p.token(x.ValuePos, literal(x))
+
+	case *ast.Selector:
+		p.expr(x.X)
+		if x.Sel.NamePos.Line > p.line {
+			p.breakLine()
+		}
+		p.write(".")
+		p.expr(x.Sel)
+
+	case *ast.Index:
+		p.expr(x.X)
+		p.args(x.Lbrack, x.Indices, x.Rbrack, "[]")
+
+	case *ast.Call:
+		p.expr(x.Fun)
+		p.args(x.Lparen, x.Args, x.Rparen, "()")
+
+	case *ast.Unary:
+		p.token(x.OpPos, x.Op.Text())
+		p.expr(x.X)
+
+	case *ast.Binary:
+		p.expr(x.X)
+		if (x.Op == scanner.PIPE) && (x.OpPos.Line > p.line) {
+			p.breakLine()
+		} else {
+			p.write(" ")
+		}
+		p.token(x.OpPos, x.Op.Text())
+		p.write(" ")
+		p.expr(x.Y)
+
+	case *ast.Paren:
+		p.token(x.Lparen, "(")
+		p.expr(x.X)
+		p.token(x.Rparen, ")")
+
+	case *ast.TypeAssert:
+		p.expr(x.X)
+		p.write(".")
+		p.token(x.Lparen, "(")
+		p.expr(x.Type)
+		p.token(x.Rparen, ")")
+
+	case *ast.Try:
+		p.expr(x.X)
+		p.token(x.Question, "?")
+
+	case *ast.TupleLit:
+		p.args(x.Lparen, x.Elems, x.Rparen, "()")
+
+	case *ast.ArrayLit:
+		p.args(x.Lbrack, x.Elems, x.Rbrack, "[]")
+
+	case *ast.StructLit:
+		p.structLit(x)
+
+	case *ast.FuncLit:
+		p.token(x.Type.Arrow, "->")
+		if (x.Type.Params != nil) || x.Type.Mut || (x.Type.Result != nil) {
+			p.write(" ")
+			p.funcType(x.Type)
+		}
+		p.write(" ")
+		p.block(x.Body)
+
+	case *ast.If:
+		p.ifExpr(x)
+
+	case *ast.Switch:
+		p.switchExpr(x)
+
+	case *ast.Block:
+		p.block(x)
+
+	case *ast.TupleType:
+		p.args(x.Lparen, x.Elems, x.Rparen, "()")
+
+	case *ast.FuncType:
+		p.token(x.Arrow, "->")
+		if (x.Params != nil) || x.Mut || (x.Result != nil) {
+			p.write(" ")
+			p.funcType(x)
+		}
+
+	case *ast.OneofType:
+		p.token(x.Oneof, "oneof ")
+		p.entries(x.Lbrace, len(x.Types), x.Rbrace, func(i int) ast.Node { return x.Types[i] })
+
+	case *ast.TypeLit:
+		if x.Type.IsValid() {
+			p.token(x.Type, "type ")
+			if x.TypeParams != nil {
+				p.typeParams(x.TypeParams)
+				p.write(" ")
+			}
+		}
+		p.entries(x.Lbrace, len(x.Entries), x.Rbrace, func(i int) ast.Node { return x.Entries[i] })
+
+	default:
+		panic(fmt.Errorf("unexpected expression type: %T", expr))
+	}
+}
+
+// funcType prints a function type without its leading arrow. Unlike
+// signature, the mutability and result are not preceded by a space if
+// the parameters were omitted.
+func (p *printer) funcType(sig *ast.FuncType) {
+	if sig.Params != nil {
+		p.signature(sig)
+		return
+	}
+
+	if sig.Mut {
+		p.write("mut")
+		if sig.Result != nil {
+			p.write(" ")
+		}
+	}
+	if sig.Result != nil {
+		p.expr(sig.Result)
+	}
+}
+
+// entries prints the braced entries of a type definition or oneof
+// list.
+func (p *printer) entries(lbrace scanner.Pos, n int, rbrace scanner.Pos, entry func(int) ast.Node) {
+	if p.inline(lbrace, rbrace, n, n) {
+		p.token(lbrace, "{")
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.write(";")
+			}
+			p.write(" ")
+			p.entry(entry(i))
+		}
+		if n > 0 {
+			p.write(" ")
+		}
+		p.token(rbrace, "}")
+		return
+	}
+
+	p.token(lbrace, "{")
+	p.indent++
+	for i := 0; i < n; i++ {
+		e := entry(i)
+		p.leading(e.Pos(), i == 0)
+		p.scope(func() { p.entry(e) })
+		p.trailing()
+	}
+	p.closing(rbrace)
+}
+
+func (p *printer) entry(entry ast.Node) {
+	switch entry := entry.(type) {
+	case *ast.Let:
+		p.let(entry)
+
+	case *ast.MethodSpec:
+		p.token(entry.Func, "func ")
+		if entry.MutRecv {
+			p.write("mut ")
+		}
+		p.expr(entry.Name)
+		p.signature(entry.Type)
+
+	case ast.Expr:
+		p.expr(entry)
+
+	default:
+		panic(fmt.Errorf("unexpected type entry: %T", entry))
+	}
+}
+
+func (p *printer) structLit(lit *ast.StructLit) {
+	p.token(lit.Amp, "&")
+	p.expr(lit.Type)
+	if _, ok := lit.Type.(*ast.Call); ok {
+		// Separate the braces from the parentheses of an embedded
+		// tuple's values.
+		p.write(" ")
+	}
+
+	if p.inline(lit.Lbrace, lit.Rbrace, len(lit.Fields), len(lit.Fields)) {
+		p.token(lit.Lbrace, "{")
+		for i, field := range lit.Fields {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.fieldInit(field)
+		}
+		p.token(lit.Rbrace, "}")
+		return
+	}
+
+	p.token(lit.Lbrace, "{")
+	p.indent++
+	for i, field := range lit.Fields {
+		p.leading(field.Pos(), i == 0)
+		p.scope(func() { p.fieldInit(field) })
+		p.trailing()
+	}
+	p.closing(lit.Rbrace)
+}
+
+func (p *printer) fieldInit(field *ast.FieldInit) {
+	p.expr(field.Name)
+	p.write(" = ")
+	p.expr(field.Value)
+}
+
+func (p *printer) ifExpr(x *ast.If) {
+	p.token(x.If, "if ")
+	p.expr(x.Cond)
+	p.write(" ")
+	p.block(x.Body)
+
+	if x.Else != nil {
+		p.write(" else ")
+		p.expr(x.Else)
+	}
+}
+
+func (p *printer) switchExpr(x *ast.Switch) {
+	p.token(x.Switch, "switch ")
+	if x.Tag != nil {
+		p.expr(x.Tag)
+		p.write(" ")
+	}
+
+	p.token(x.Lbrace, "{")
+	p.indent++
+	for i, c := range x.Cases {
+		p.leading(c.Pos(), i == 0)
+		p.scope(func() { p.switchCase(c) })
+		p.trailing()
+	}
+	p.closing(x.Rbrace)
+}
+
+func (p *printer) switchCase(c *ast.Case) {
+	switch {
+	case c.Else:
+		p.token(c.CasePos, "else")
+	case c.Type != nil:
+		p.token(c.CasePos, ".(")
+		p.expr(c.Type)
+		p.write(")")
+	case c.Op != scanner.INVALID:
+		p.token(c.CasePos, c.Op.Text())
+		p.write(" ")
+		p.expr(c.Value)
+	default:
+		p.expr(c.Value)
+	}
+
+	p.write(" ")
+	p.block(c.Body)
+}
+
+// literal returns the canonical source form of a basic literal.
+func literal(lit *ast.BasicLit) string {
+	switch v := lit.Value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+
+	case *big.Int:
+		return v.String()
+
+	case rune:
+		if v == '\'' {
+			return `'\''`
+		}
+		return "'" + escape(string(v)) + "'"
+
+	case float64:
+		return floatLiteral(strconv.FormatFloat(v, 'f', -1, 64))
+
+	case *big.Float:
+		return floatLiteral(v.Text('f', -1))
+
+	case string:
+		return `"` + strings.ReplaceAll(escape(v), `"`, `\"`) + `"`
+
+	default:
+		panic(fmt.Errorf("unexpected literal value type: %T", v))
+	}
+}
+
+// floatLiteral makes sure that a formatted float has a decimal point
+// so that it is scanned as a float again.
+func floatLiteral(s string) string {
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// escape escapes the characters in s that can't appear directly in a
+// string or character literal.
+func escape(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\\':
+			sb.WriteString(`\\`)
+		case (c < 0x100) && !unicode.IsPrint(c):
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
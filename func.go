@@ -0,0 +1,131 @@
+package stele
+
+import "fmt"
+
+// FuncType returns the type of a pure function with the given type
+// parameters, argument types and return type.
+func FuncType(params []TypeParam, args []Type, ret Type) Type {
+	return FuncOf(Feature{Args: args, Return: ret, TypeParams: params})
+}
+
+// FuncOf returns the type of a function with the signature of sig,
+// including whether or not it is mutable. This can be used to get the
+// type of a method value.
+func FuncOf(sig Feature) Type {
+	return Type{Features: []Feature{{
+		Type:       MemLayoutFeature,
+		Name:       "func",
+		Args:       sig.Args,
+		Return:     sig.Return,
+		TypeParams: sig.TypeParams,
+		Mut:        sig.Mut,
+	}}}
+}
+
+// Func returns the signature of t if t is a function type.
+func (t Type) Func() (Feature, bool) {
+	features, _ := t.flatten(Type{})
+	for _, f := range features {
+		if (f.Type == MemLayoutFeature) && (f.Name == "func") {
+			return f, true
+		}
+	}
+	return Feature{}, false
+}
+
+func (f Feature) describeFunc() string {
+	var params string
+	if len(f.TypeParams) > 0 {
+		names := make([]string, 0, len(f.TypeParams))
+		for _, p := range f.TypeParams {
+			if p.Constraint.Valid() {
+				names = append(names, fmt.Sprintf("%v %v", p.Name, p.Constraint))
+				continue
+			}
+			names = append(names, p.Name)
+		}
+		params = fmt.Sprintf("[%v] ", joinNames(names))
+	}
+
+	str := fmt.Sprintf("%v-> (%v)", params, typeList(f.Args))
+	if f.Mut {
+		str += " mut"
+	}
+	if f.Return.Valid() {
+		str += " " + f.Return.String()
+	}
+	return str
+}
+
+// Function is the run-time representation of a function value.
+type Function interface {
+	// Call calls the function with the given arguments and returns its
+	// result.
+	Call(state *State, args []Value) Value
+}
+
+// zeroFunc is the zero value of a function type. It does nothing
+// and returns the zero value of its return type.
+type zeroFunc struct {
+	ret Type
+}
+
+func (f zeroFunc) Call(state *State, args []Value) Value {
+	return state.Descs.Zero(f.ret)
+}
+
+// Closure is a function declared by a script, along with the frame of
+// the block that it was declared in, which its body can see the
+// variables of. Frame is nil for top-level functions.
+type Closure struct {
+	Func  *Func
+	Frame *Frame
+}
+
+func (c Closure) Call(state *State, args []Value) Value {
+	return c.call(state, nil, args)
+}
+
+// call calls the function. If it is a method, recv is the receiver
+// that it is called on. If the function returns a result, run-time
+// errors that occur while it is running are returned as errors.
+func (c Closure) call(state *State, recv *Value, args []Value) (r Value) {
+	f := c.Func
+	frame := NewFrame(c.Frame, f.Slots)
+	params := frame.Slots[f.Slots-len(args):]
+	copy(params, args)
+	for _, i := range f.Immutable {
+		params[i] = params[i].Copy()
+	}
+	if (recv != nil) && (f.Recv != nil) && (f.Recv.Name != "") {
+		r := *recv
+		if !f.Recv.Mutable() {
+			r = r.Copy()
+		}
+		frame.Slots[f.Slots-len(args)-1] = r
+	}
+
+	state.Call(f.Name, frame)
+	defer state.Return()
+	defer func() {
+		switch p := recover().(type) {
+		case nil:
+		case returning:
+			r = p.val
+		case *RuntimeError:
+			sig, _ := f.T.Func()
+			if !sig.Return.Fallible() {
+				panic(p)
+			}
+			r = ValueOf(errorDesc, p).Convert(state.Descs.Intern(sig.Return))
+		default:
+			panic(p)
+		}
+	}()
+
+	r = f.Body.Eval(state)
+	if f.Unit {
+		return UnitValue
+	}
+	return r
+}
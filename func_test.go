@@ -0,0 +1,110 @@
+package stele
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuncSatisfies(t *testing.T) {
+	var (
+		intT    = layout("int")
+		reader  = Type{Name: "reader", Features: []Feature{method("read", false, intT)}}
+		file    = Type{Name: "file", Features: []Feature{method("read", false, intT), method("close", false, Unit)}}
+		pure    = FuncType(nil, []Type{reader}, file)
+		mutable = FuncOf(Feature{Args: []Type{reader}, Return: file, Mut: true})
+	)
+
+	tests := []struct {
+		name    string
+		from    Type
+		to      Type
+		reasons []string
+	}{
+		{name: "Same", from: pure, to: pure},
+		{name: "PureAsMut", from: pure, to: mutable},
+		{name: "MutAsPure", from: mutable, to: pure, reasons: []string{"function is mutable"}},
+		{name: "Variance", from: pure, to: FuncType(nil, []Type{file}, reader)},
+		{
+			name: "WrongArg",
+			from: pure,
+			to:   FuncType(nil, []Type{intT}, file),
+			reasons: []string{
+				"function argument 1 has type reader, which int does not satisfy",
+			},
+		},
+		{
+			name: "WrongReturn",
+			from: FuncType(nil, []Type{reader}, reader),
+			to:   pure,
+			reasons: []string{
+				"function return: missing method close",
+			},
+		},
+		{
+			name:    "Arity",
+			from:    pure,
+			to:      FuncType(nil, nil, file),
+			reasons: []string{"function has 1 arguments, but 0 are required"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			reasons := test.from.Explain(test.to)
+			if !slices.Equal(reasons, test.reasons) {
+				t.Fatalf("unexpected reasons: %q", reasons)
+			}
+		})
+	}
+}
+
+func TestFuncString(t *testing.T) {
+	intT := layout("int")
+	f := FuncOf(Feature{Args: []Type{intT, intT}, Return: Unit, Mut: true})
+	if s := f.String(); s != "-> (int, int) mut unit" {
+		t.Fatalf("unexpected string: %v", s)
+	}
+}
+
+func TestFuncZero(t *testing.T) {
+	f := FuncType(nil, []Type{Bool}, TupleType(Bool, Unit))
+	state := NewState()
+	z := state.Descs.Zero(f)
+	fn, ok := z.Interface().(Function)
+	if !ok {
+		t.Fatalf("zero value is not a function: %#v", z)
+	}
+
+	r := fn.Call(state, []Value{MakeBool(boolDesc, true)})
+	elems, ok := r.Interface().([]Value)
+	if !ok || (len(elems) != 2) || (elems[0].Interface() != false) || (elems[1].Interface() != struct{}{}) {
+		t.Fatalf("unexpected result: %#v", r)
+	}
+}
+
+func TestClosureAllocs(t *testing.T) {
+	state := NewState()
+	ft := FuncType(nil, []Type{Int}, Int)
+	f := Func{
+		T:      ft,
+		Params: []string{"x"},
+		Slots:  1,
+		Body:   Block{Stmts: []Stmt{Ident{ID: "x", T: Int, Ref: Ref{Local: true, Depth: 1}}}, T: Int},
+		Desc:   state.Descs.Intern(ft),
+	}
+	fn := Closure{Func: &f}
+	args := []Value{MakeInt(intDesc, 3)}
+	if r := fn.Call(state, args); r.Interface() != int64(3) {
+		t.Fatalf("unexpected result: %#v", r)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() { fn.Call(state, args) })
+	// The function's frame and its slots, and its body's frame and
+	// the list of frames that it is inside of.
+	if allocs > 4 {
+		t.Fatalf("calling a closure allocated %v times", allocs)
+	}
+}
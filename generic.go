@@ -0,0 +1,278 @@
+package stele
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeParam is a type parameter of a generic type or function. The
+// zero Constraint leaves the parameter unconstrained.
+type TypeParam struct {
+	Name       string
+	Constraint Type
+}
+
+// Ref returns a reference to p for use in the definition of whatever
+// p is a parameter of.
+func (p TypeParam) Ref() Type {
+	t := Type{Name: p.Name, Param: true}
+	if p.Constraint.Valid() {
+		t.Features = []Feature{{Type: EmbedFeature, Return: p.Constraint}}
+	}
+	return t
+}
+
+// Generic returns true if t has type parameters other than its self
+// parameter, meaning that it must be instantiated before it can be
+// used.
+func (t Type) Generic() bool {
+	return len(t.TypeParams) > 1
+}
+
+// Instantiate returns the instantiation of the generic type t with
+// the given type arguments. The self parameter may either be included
+// as the first argument or left off, in which case the instantiated
+// type keeps it.
+//
+// Each argument must satisfy the constraint of its parameter. If one
+// does not, the returned error explains why.
+func (t Type) Instantiate(args ...Type) (Type, error) {
+	params, err := bindParams(t.TypeParams, args)
+	if err != nil {
+		return Type{}, fmt.Errorf("%v: %w", t, err)
+	}
+
+	err = checkConstraints(params, args)
+	if err != nil {
+		return Type{}, fmt.Errorf("%v: %w", t, err)
+	}
+
+	origin := t
+	inst := Type{
+		Name:   fmt.Sprintf("%v[%v]", t.Name, typeList(args)),
+		Origin: &origin,
+		Args:   args,
+	}
+	if len(params) < len(t.TypeParams) {
+		inst.TypeParams = t.TypeParams[:1]
+	}
+	return inst, nil
+}
+
+// bindParams returns the parameters that args are for, which may or
+// may not include the self parameter.
+func bindParams(params []TypeParam, args []Type) ([]TypeParam, error) {
+	switch len(args) {
+	case len(params):
+		return params, nil
+	case len(params) - 1:
+		return params[1:], nil
+	default:
+		return nil, fmt.Errorf("expected %v type arguments but got %v", len(params)-1, len(args))
+	}
+}
+
+// checkConstraints checks that each of args satisfies the constraint
+// of the corresponding parameter. Constraints may refer to earlier
+// parameters.
+func checkConstraints(params []TypeParam, args []Type) error {
+	m := make(map[string]Type, len(params))
+	for i, p := range params {
+		m[p.Name] = args[i]
+		if !p.Constraint.Valid() {
+			continue
+		}
+
+		c := p.Constraint.subst(m)
+		if reasons := args[i].Explain(c); len(reasons) > 0 {
+			return fmt.Errorf("%v does not satisfy %v (%v)", args[i], c, strings.Join(reasons, "; "))
+		}
+	}
+	return nil
+}
+
+// Infer works out the type arguments of a call to a generic function
+// with the signature f from the types of the arguments passed to it.
+// It returns the inferred type arguments in the order of f's type
+// parameters, or an error naming the first parameter that could not
+// be inferred.
+func (f Feature) Infer(args []Type) ([]Type, error) {
+	m := make(map[string]Type, len(f.TypeParams))
+	for _, p := range f.TypeParams {
+		m[p.Name] = Type{}
+	}
+
+	for i := 0; i < min(len(args), len(f.Args)); i++ {
+		infer(f.Args[i], args[i], m)
+	}
+
+	targs := make([]Type, 0, len(f.TypeParams))
+	for _, p := range f.TypeParams {
+		t := m[p.Name]
+		if !t.Valid() {
+			return nil, fmt.Errorf("cannot infer %v", p.Name)
+		}
+		targs = append(targs, t)
+	}
+	return targs, nil
+}
+
+// infer binds any unbound type parameters in m that are mentioned by
+// param to the corresponding parts of arg.
+func infer(param, arg Type, m map[string]Type) {
+	if param.Param {
+		if t, ok := m[param.Name]; ok && !t.Valid() {
+			m[param.Name] = arg
+		}
+		return
+	}
+
+	if (param.Origin != nil) && (arg.Origin != nil) && (param.Origin.Name == arg.Origin.Name) {
+		for i := 0; i < min(len(param.Args), len(arg.Args)); i++ {
+			infer(param.Args[i], arg.Args[i], m)
+		}
+		return
+	}
+
+	if param.Name != "" {
+		return
+	}
+
+	pf, _ := param.flatten(Type{})
+	af, _ := arg.flatten(arg)
+	for _, f := range pf {
+		for _, a := range af {
+			if f.key() != a.key() {
+				continue
+			}
+			for i := 0; i < min(len(f.Args), len(a.Args)); i++ {
+				infer(f.Args[i], a.Args[i], m)
+			}
+			infer(f.Return, a.Return, m)
+		}
+	}
+}
+
+// Instantiate returns the signature f with its type parameters
+// replaced by args.
+func (f Feature) Instantiate(args ...Type) (Feature, error) {
+	if len(args) != len(f.TypeParams) {
+		return Feature{}, fmt.Errorf("expected %v type arguments but got %v", len(f.TypeParams), len(args))
+	}
+	if err := checkConstraints(f.TypeParams, args); err != nil {
+		return Feature{}, err
+	}
+
+	m := make(map[string]Type, len(args))
+	for i, p := range f.TypeParams {
+		m[p.Name] = args[i]
+	}
+
+	f.TypeParams = nil
+	return f.subst(m), nil
+}
+
+// resolve returns t with its instantiation, if any, expanded and its
+// self parameter, if it has one, bound to self.
+func (t Type) resolve(self Type) Type {
+	m := make(map[string]Type)
+	base := t
+	if t.Origin != nil {
+		base = *t.Origin
+		params, _ := bindParams(base.TypeParams, t.Args)
+		for i, p := range params {
+			m[p.Name] = t.Args[i]
+		}
+	}
+	if (len(t.TypeParams) > 0) && self.Valid() {
+		m[t.TypeParams[0].Name] = self
+	}
+	if len(m) == 0 {
+		return t
+	}
+
+	return Type{
+		Name:     t.Name,
+		Features: substFeatures(base.Features, m),
+		Oneof:    substTypes(base.Oneof, m),
+	}
+}
+
+// subst returns t with the type parameters named in m replaced.
+// Named types are never changed, as the only type parameters that
+// they can refer to are their own.
+func (t Type) subst(m map[string]Type) Type {
+	switch {
+	case len(m) == 0:
+		return t
+
+	case t.Param:
+		if r, ok := m[t.Name]; ok {
+			return r
+		}
+		return t
+
+	case t.Origin != nil:
+		args := substTypes(t.Args, m)
+		inst := t
+		inst.Args = args
+		inst.Name = fmt.Sprintf("%v[%v]", t.Origin.Name, typeList(args))
+		return inst
+
+	case t.Name != "":
+		return t
+	}
+
+	m = unbind(m, t.TypeParams)
+	t.Features = substFeatures(t.Features, m)
+	t.Oneof = substTypes(t.Oneof, m)
+	return t
+}
+
+func (f Feature) subst(m map[string]Type) Feature {
+	m = unbind(m, f.TypeParams)
+	f.Args = substTypes(f.Args, m)
+	f.Return = f.Return.subst(m)
+	return f
+}
+
+// unbind returns m without the parameters in params, which shadow
+// them.
+func unbind(m map[string]Type, params []TypeParam) map[string]Type {
+	if len(params) == 0 {
+		return m
+	}
+
+	n := make(map[string]Type, len(m))
+	for k, v := range m {
+		n[k] = v
+	}
+	for _, p := range params {
+		delete(n, p.Name)
+	}
+	return n
+}
+
+func substTypes(types []Type, m map[string]Type) []Type {
+	if len(types) == 0 {
+		return types
+	}
+
+	r := make([]Type, 0, len(types))
+	for _, t := range types {
+		r = append(r, t.subst(m))
+	}
+	return r
+}
+
+func substFeatures(features []Feature, m map[string]Type) []Feature {
+	if len(features) == 0 {
+		return features
+	}
+
+	r := make([]Feature, 0, len(features))
+	for _, f := range features {
+		r = append(r, f.subst(m))
+	}
+	return r
+}
@@ -0,0 +1,113 @@
+package stele
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInstantiate(t *testing.T) {
+	intT := layout("int")
+	stringT := layout("string")
+
+	// type [T] adder { func add(T) T }
+	self := TypeParam{Name: "T"}
+	adder := Type{
+		Name:       "adder",
+		TypeParams: []TypeParam{self},
+		Features:   []Feature{method("add", false, self.Ref(), self.Ref())},
+	}
+	num := Type{Name: "num", Features: []Feature{
+		embed(intT),
+		method("add", false, Type{}, Type{}),
+	}}
+	num.Features[1].Args[0] = num
+	num.Features[1].Return = num
+
+	if !num.Satisfies(adder) {
+		t.Fatalf("num does not satisfy adder: %q", num.Explain(adder))
+	}
+	if reasons := intT.Explain(adder); !slices.Equal(reasons, []string{"missing method add"}) {
+		t.Fatalf("unexpected reasons: %q", reasons)
+	}
+
+	// type [T, E adder] box { let val E }
+	e := TypeParam{Name: "E", Constraint: adder}
+	box := Type{
+		Name:       "box",
+		TypeParams: []TypeParam{self, e},
+		Features:   []Feature{field("val", e.Ref())},
+	}
+	if !box.Generic() {
+		t.Fatal("box is not generic")
+	}
+
+	inst, err := box.Instantiate(num)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.Name != "box[num]" {
+		t.Fatalf("unexpected name: %v", inst.Name)
+	}
+	if inst.Generic() {
+		t.Fatal("instantiated type is still generic")
+	}
+
+	want := Type{Name: "want", Features: []Feature{field("val", num)}}
+	if !inst.Satisfies(want) {
+		t.Fatalf("instantiation does not satisfy %v: %q", want, inst.Explain(want))
+	}
+
+	_, err = box.Instantiate(stringT)
+	if (err == nil) || (err.Error() != "box: string does not satisfy adder (missing method add)") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = box.Instantiate(num, num, num)
+	if err == nil {
+		t.Fatal("expected error for too many arguments")
+	}
+}
+
+func TestInfer(t *testing.T) {
+	intT := layout("int")
+	p := TypeParam{Name: "T", Constraint: Any}
+	q := TypeParam{Name: "U", Constraint: Any}
+
+	// func [T, U any] pick(T, box[U]) U
+	boxParam := TypeParam{Name: "V", Constraint: Any}
+	box := Type{
+		Name:       "box",
+		TypeParams: []TypeParam{{Name: "S"}, boxParam},
+		Features:   []Feature{field("val", boxParam.Ref())},
+	}
+	boxU, err := box.Instantiate(q.Ref())
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxInt, err := box.Instantiate(intT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, _ := FuncType([]TypeParam{p, q}, []Type{p.Ref(), boxU}, q.Ref()).Func()
+	targs, err := sig.Infer([]Type{layout("string"), boxInt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (targs[0].Name != "string") || (targs[1].Name != "int") {
+		t.Fatalf("unexpected type arguments: %v", targs)
+	}
+
+	inst, err := sig.Instantiate(targs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (inst.Return.Name != "int") || (inst.Args[1].Name != "box[int]") {
+		t.Fatalf("unexpected instantiation: %v", inst.describeFunc())
+	}
+
+	_, err = sig.Infer([]Type{intT})
+	if (err == nil) || (err.Error() != "cannot infer U") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
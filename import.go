@@ -0,0 +1,16 @@
+package stele
+
+import "errors"
+
+// ErrNotFound is returned, possibly wrapped, by an Importer that has
+// no package at the requested path.
+var ErrNotFound = errors.New("package not found")
+
+// An Importer resolves import paths to the declarations exported by
+// the packages at those paths.
+type Importer interface {
+	// Import returns the exported declarations of the package at
+	// path. If there is no such package, the returned error wraps
+	// ErrNotFound.
+	Import(path string) ([]Declaration, error)
+}
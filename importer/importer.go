@@ -0,0 +1,181 @@
+// Package importer provides implementations of [stele.Importer].
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/check"
+	"deedles.dev/stele/loader"
+	"deedles.dev/stele/parser"
+)
+
+// Native is an Importer for modules that are implemented by the host
+// program rather than in Stele. The zero value is an empty set of
+// modules ready to use.
+type Native struct {
+	m       sync.RWMutex
+	modules map[string][]stele.Declaration
+}
+
+// Register makes decls importable at path, replacing any module
+// previously registered there. Only the declarations that are
+// exported are visible to importers.
+func (n *Native) Register(path string, decls ...stele.Declaration) {
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	if n.modules == nil {
+		n.modules = make(map[string][]stele.Declaration)
+	}
+
+	exports := make([]stele.Declaration, 0, len(decls))
+	for _, d := range decls {
+		if d.Exported() {
+			exports = append(exports, d)
+		}
+	}
+	n.modules[path] = exports
+}
+
+func (n *Native) Import(path string) ([]stele.Declaration, error) {
+	n.m.RLock()
+	defer n.m.RUnlock()
+
+	decls, ok := n.modules[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", stele.ErrNotFound, path)
+	}
+	return decls, nil
+}
+
+// Source is an Importer that loads packages from Stele source code in
+// a file system. Each package is parsed and checked only once.
+type Source struct {
+	// FS is the file system that packages are loaded from.
+	FS fs.FS
+
+	// Parent, if not nil, is consulted before FS. Packages that it can
+	// import shadow those in FS. It is typically a *Native.
+	Parent stele.Importer
+
+	// Fset, if not nil, records every file that is parsed.
+	Fset *parser.FileSet
+
+	m       sync.Mutex
+	loader  *loader.Loader
+	checked map[string]result
+}
+
+type result struct {
+	decls []stele.Declaration
+	err   error
+}
+
+// SearchPath returns a Source that loads each package from the first
+// of the directories in dirs that contains it.
+func SearchPath(parent stele.Importer, dirs ...string) *Source {
+	fsys := make(searchFS, 0, len(dirs))
+	for _, dir := range dirs {
+		fsys = append(fsys, os.DirFS(dir))
+	}
+	return &Source{FS: fsys, Parent: parent}
+}
+
+func (s *Source) Import(path string) ([]stele.Declaration, error) {
+	if decls, ok, err := s.parent(path); ok {
+		return decls, err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if r, ok := s.checked[path]; ok {
+		return r.decls, r.err
+	}
+
+	if s.loader == nil {
+		s.loader = &loader.Loader{
+			FS:   s.FS,
+			Fset: s.Fset,
+			Skip: func(path string) bool {
+				_, ok, _ := s.parent(path)
+				return ok
+			},
+		}
+		s.checked = make(map[string]result)
+	}
+
+	pkgs, err := s.loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dependencies come first, so every import of a package is
+	// already checked by the time that the package itself is.
+	conf := check.Config{Importer: cached{checked: s.checked, parent: s.Parent}}
+	for _, pkg := range pkgs {
+		if _, ok := s.checked[pkg.Path]; ok {
+			continue
+		}
+
+		script, err := conf.Package(pkg.Files)
+		if err != nil {
+			err = fmt.Errorf("%v: %w", pkg.Path, err)
+		}
+		s.checked[pkg.Path] = result{decls: script.Exports(), err: err}
+	}
+
+	r := s.checked[path]
+	return r.decls, r.err
+}
+
+// parent imports path from s.Parent. It returns false if s.Parent
+// does not have a package at path.
+func (s *Source) parent(path string) ([]stele.Declaration, bool, error) {
+	if s.Parent == nil {
+		return nil, false, nil
+	}
+
+	decls, err := s.Parent.Import(path)
+	if errors.Is(err, stele.ErrNotFound) {
+		return nil, false, nil
+	}
+	return decls, true, err
+}
+
+// cached is an Importer that imports packages that have already been
+// checked, falling back to parent for anything else.
+type cached struct {
+	checked map[string]result
+	parent  stele.Importer
+}
+
+func (c cached) Import(path string) ([]stele.Declaration, error) {
+	if r, ok := c.checked[path]; ok {
+		return r.decls, r.err
+	}
+	if c.parent != nil {
+		return c.parent.Import(path)
+	}
+	return nil, fmt.Errorf("%w: %q", stele.ErrNotFound, path)
+}
+
+// searchFS is a file system that opens each file from the first of
+// several file systems that contains it.
+type searchFS []fs.FS
+
+func (fsys searchFS) Open(name string) (fs.File, error) {
+	for _, sub := range fsys {
+		f, err := sub.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
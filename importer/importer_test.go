@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"deedles.dev/stele"
+)
+
+func TestNative(t *testing.T) {
+	var n Native
+	n.Register("host/io", stele.Let{Name: "out!"}, stele.Let{Name: "_private"})
+
+	decls, err := n.Import("host/io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(decls) != 1) || (decls[0].ID() != "out") {
+		t.Fatalf("unexpected declarations: %#v", decls)
+	}
+
+	_, err = n.Import("host/missing")
+	if !errors.Is(err, stele.ErrNotFound) {
+		t.Fatalf("expected not found but got %v", err)
+	}
+}
+
+func TestSource(t *testing.T) {
+	var n Native
+	n.Register("host", stele.Let{Name: "x"})
+
+	fsys := fstest.MapFS{
+		"main.stele":   {Data: []byte("import \"lib\"\nimport \"host\"\nlet a int = 1\nlet _b int = 2")},
+		"lib/a.stele":  {Data: []byte("import \"host\"\nlet c int = 3")},
+		"bad.stele":    {Data: []byte("import \"lib\"\nimport \"nowhere\"")},
+		"host/x.stele": {Data: []byte("let shadowed int = 1")},
+	}
+
+	s := Source{FS: fsys, Parent: &n}
+	decls, err := s.Import("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(decls) != 1) || (decls[0].ID() != "a") {
+		t.Fatalf("unexpected declarations: %#v", decls)
+	}
+
+	decls, err = s.Import("host")
+	if (err != nil) || (len(decls) != 1) || (decls[0].ID() != "x") {
+		t.Fatalf("native module not preferred: %#v, %v", decls, err)
+	}
+
+	_, err = s.Import("bad")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	_, err = s.Import("nothing")
+	if !errors.Is(err, stele.ErrNotFound) {
+		t.Fatalf("expected not found but got %v", err)
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	dirs := []string{t.TempDir(), t.TempDir()}
+	write := func(name, src string) {
+		err := os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(name, []byte(src), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(dirs[0], "a.stele"), "import \"b\"\nlet a int = 1")
+	write(filepath.Join(dirs[1], "b", "b.stele"), "let b int = 2")
+	write(filepath.Join(dirs[1], "a.stele"), "let hidden int = 1")
+
+	s := SearchPath(nil, dirs...)
+	decls, err := s.Import("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(decls) != 1) || (decls[0].ID() != "a") {
+		t.Fatalf("unexpected declarations: %#v", decls)
+	}
+
+	decls, err = s.Import("b")
+	if (err != nil) || (len(decls) != 1) || (decls[0].ID() != "b") {
+		t.Fatalf("unexpected declarations: %#v, %v", decls, err)
+	}
+}
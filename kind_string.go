@@ -0,0 +1,31 @@
+// Code generated by "stringer -type Kind"; DO NOT EDIT.
+
+package stele
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[InvalidKind-0]
+	_ = x[UnitKind-1]
+	_ = x[BoolKind-2]
+	_ = x[IntKind-3]
+	_ = x[UintKind-4]
+	_ = x[ByteKind-5]
+	_ = x[FloatKind-6]
+	_ = x[RefKind-7]
+}
+
+const _Kind_name = "InvalidKindUnitKindBoolKindIntKindUintKindByteKindFloatKindRefKind"
+
+var _Kind_index = [...]uint8{0, 11, 19, 27, 34, 42, 50, 59, 66}
+
+func (i Kind) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Kind_index)-1 {
+		return "Kind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Kind_name[_Kind_index[idx]:_Kind_index[idx+1]]
+}
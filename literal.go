@@ -0,0 +1,174 @@
+package stele
+
+import (
+	"fmt"
+	"go/constant"
+	"math/big"
+)
+
+// Const is a constant value. If T is untyped, the constant has not
+// been given a type yet, and its value may be of any precision.
+//
+// Desc is the description of T. It is filled in by Resolve, along
+// with the value that the constant evaluates to if that value can be
+// shared. If Desc is nil, they are instead worked out each time that
+// the constant is evaluated.
+type Const struct {
+	Val  constant.Value
+	T    Type
+	Desc *TypeDesc
+
+	val Value
+}
+
+// UntypedConst returns an untyped constant with the value v.
+func UntypedConst(v constant.Value) Const {
+	if v.Kind() == constant.Int {
+		return Const{Val: v, T: UntypedInt}
+	}
+	return Const{Val: v, T: UntypedFloat}
+}
+
+// Resolve returns c with its Desc, interned in ds, filled in. c must
+// not be untyped.
+func (c Const) Resolve(ds *Descs) Const {
+	c.Desc = ds.Intern(c.T)
+	switch name, _ := c.T.number(); name {
+	case "bigint", "bigfloat":
+		// These are held by pointer, so each evaluation needs its
+		// own.
+	default:
+		c.val = c.value()
+	}
+	return c
+}
+
+func (c Const) Type() Type {
+	return c.T
+}
+
+func (c Const) Eval(state *State) Value {
+	if c.val.desc != nil {
+		return c.val
+	}
+
+	if c.Desc == nil {
+		if c.T.Untyped() {
+			// The checker gives every constant that is used a type,
+			// but one that isn't still needs to be represented
+			// somehow.
+			if d, ok := c.Default(Any); ok {
+				c = d
+			}
+		}
+		c.Desc = state.Descs.Intern(c.T)
+	}
+	return c.value()
+}
+
+// value returns a new Value of type c.Desc holding c.
+func (c Const) value() Value {
+	v, d := c.Val, c.Desc
+	switch name, _ := c.T.number(); name {
+	case "int":
+		i, _ := constant.Int64Val(v)
+		return MakeInt(d, i)
+	case "uint":
+		u, _ := constant.Uint64Val(v)
+		return MakeUint(d, u)
+	case "byte":
+		u, _ := constant.Uint64Val(v)
+		return MakeByte(d, byte(u))
+	case "float":
+		f, _ := constant.Float64Val(v)
+		return MakeFloat(d, f)
+	case "bigint":
+		i, _ := new(big.Int).SetString(constant.ToInt(v).ExactString(), 10)
+		return ValueOf(d, i)
+	case "bigfloat":
+		return ValueOf(d, bigFloat(constant.ToFloat(v)))
+	}
+
+	switch v.Kind() {
+	case constant.Bool:
+		return MakeBool(d, constant.BoolVal(v))
+	case constant.String:
+		return ValueOf(d, constant.StringVal(v))
+	}
+	panic(fmt.Errorf("constant %v of type %v can not be represented", v, c.T))
+}
+
+// bigFloat returns the value of a floating point constant as a
+// *big.Float.
+func bigFloat(v constant.Value) *big.Float {
+	switch x := constant.Val(v).(type) {
+	case *big.Float:
+		return new(big.Float).Set(x)
+	case *big.Rat:
+		return new(big.Float).SetRat(x)
+	default:
+		f, _ := constant.Float64Val(v)
+		return big.NewFloat(f)
+	}
+}
+
+// StructLit is a struct literal. Fields includes every field of T,
+// with those that were not given a value initialized to their zero
+// value. Desc is the description of T.
+type StructLit struct {
+	Fields []FieldInit
+	T      Type
+	Desc   *TypeDesc
+}
+
+// FieldInit is the initialization of a single field in a StructLit.
+type FieldInit struct {
+	Name string
+	Val  Expr
+}
+
+func (l StructLit) Type() Type {
+	return l.T
+}
+
+func (l StructLit) Eval(state *State) Value {
+	fields := make(map[string]Value, len(l.Fields))
+	for _, f := range l.Fields {
+		fields[f.Name] = f.Val.Eval(state)
+	}
+	return ValueOf(state.desc(l.Desc, l.T), NewStruct(fields))
+}
+
+// ArrayLit is an array literal. Desc is the description of T, which
+// is left nil until the types of the elements are known.
+type ArrayLit struct {
+	Elems []Expr
+	T     Type
+	Desc  *TypeDesc
+}
+
+func (l ArrayLit) Type() Type {
+	return l.T
+}
+
+func (l ArrayLit) Eval(state *State) Value {
+	elems := make([]Value, 0, len(l.Elems))
+	for _, e := range l.Elems {
+		elems = append(elems, e.Eval(state))
+	}
+	return ValueOf(state.desc(l.Desc, l.T), &Slice{Elems: elems})
+}
+
+// FuncLit is a function literal. Its value is a Closure of the frame
+// that it is evaluated in.
+type FuncLit struct {
+	Func Func
+}
+
+func (l FuncLit) Type() Type {
+	return l.Func.T
+}
+
+func (l FuncLit) Eval(state *State) Value {
+	return ValueOf(l.Func.Desc, Function(Closure{Func: &l.Func, Frame: state.Frame}))
+}
@@ -0,0 +1,197 @@
+// Package loader finds, parses, and orders the packages that make up
+// a Stele program.
+//
+// An import path names either a directory of Stele source files or,
+// with the source file extension left off, a single source file. Each
+// package is parsed only once no matter how many times it is
+// imported.
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser"
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+// Package is a parsed package along with the packages that it
+// directly imports.
+type Package struct {
+	*ast.Package
+
+	// Imports is the packages directly imported by the package, in
+	// the order that they are first imported.
+	Imports []*Package
+}
+
+// Loader loads packages from a file system.
+type Loader struct {
+	// FS is the file system that import paths are resolved in.
+	FS fs.FS
+
+	// Fset, if not nil, records every file that is parsed.
+	Fset *parser.FileSet
+
+	// Skip, if not nil, reports whether an import path is provided by
+	// something other than the loader, such as a native module.
+	// Skipped imports are not followed.
+	Skip func(path string) bool
+
+	pkgs  map[string]*Package
+	state map[string]state
+}
+
+type state int
+
+const (
+	unvisited state = iota
+	visiting
+	done
+)
+
+// Load loads the packages at the given paths along with all of the
+// packages that they import, directly or indirectly. The returned
+// packages are in dependency order, with every package appearing
+// after all of the packages that it imports.
+//
+// Packages loaded by a previous call are not loaded again, but are
+// not included in the returned list unless they are reachable from
+// paths.
+func (l *Loader) Load(paths ...string) ([]*Package, error) {
+	if l.pkgs == nil {
+		l.pkgs = make(map[string]*Package)
+		l.state = make(map[string]state)
+	}
+
+	var order []*Package
+	seen := make(map[*Package]struct{})
+	for _, p := range paths {
+		pkg, err := l.load(p, nil, scanner.Pos{})
+		if err != nil {
+			return nil, err
+		}
+		order = appendDeps(order, seen, pkg)
+	}
+	return order, nil
+}
+
+func appendDeps(order []*Package, seen map[*Package]struct{}, pkg *Package) []*Package {
+	if _, ok := seen[pkg]; ok {
+		return order
+	}
+	seen[pkg] = struct{}{}
+
+	for _, dep := range pkg.Imports {
+		order = appendDeps(order, seen, dep)
+	}
+	return append(order, pkg)
+}
+
+// load loads the package at p. stack is the chain of imports that led
+// to p and pos is the position of the import of p, if any.
+func (l *Loader) load(p string, stack []string, pos scanner.Pos) (*Package, error) {
+	switch l.state[p] {
+	case visiting:
+		return nil, &CycleError{Pos: pos, Path: cycle(stack, p)}
+	case done:
+		return l.pkgs[p], nil
+	}
+
+	file, err := l.resolve(p)
+	if err != nil {
+		if pos.IsValid() {
+			return nil, fmt.Errorf("(%v) %w", pos, err)
+		}
+		return nil, err
+	}
+
+	l.state[p] = visiting
+	defer func() {
+		if l.state[p] == visiting {
+			l.state[p] = unvisited
+		}
+	}()
+
+	astpkg, err := parser.ParseFS(l.Fset, l.FS, file)
+	if err != nil {
+		return nil, err
+	}
+	astpkg.Path = p
+
+	pkg := Package{Package: astpkg}
+	imported := make(map[*Package]struct{})
+	stack = append(stack, p)
+	for _, file := range astpkg.Files {
+		for _, imp := range file.Imports {
+			path, ok := imp.Path.Value.(string)
+			if !ok || ((l.Skip != nil) && l.Skip(path)) {
+				continue
+			}
+
+			dep, err := l.load(path, stack, imp.Path.ValuePos)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := imported[dep]; !ok {
+				imported[dep] = struct{}{}
+				pkg.Imports = append(pkg.Imports, dep)
+			}
+		}
+	}
+
+	l.pkgs[p] = &pkg
+	l.state[p] = done
+	return &pkg, nil
+}
+
+// resolve returns the path in l.FS of the directory or file that the
+// import path p refers to.
+func (l *Loader) resolve(p string) (string, error) {
+	if !fs.ValidPath(p) {
+		return "", fmt.Errorf("invalid import path %q", p)
+	}
+
+	info, err := fs.Stat(l.FS, p)
+	if err == nil && info.IsDir() {
+		return p, nil
+	}
+
+	file := p + parser.Ext
+	info, err = fs.Stat(l.FS, file)
+	if err == nil && !info.IsDir() {
+		return file, nil
+	}
+	if (err != nil) && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	return "", fmt.Errorf("%w: %q", stele.ErrNotFound, p)
+}
+
+func cycle(stack []string, p string) []string {
+	for i, s := range stack {
+		if s == p {
+			return append(stack[i:len(stack):len(stack)], p)
+		}
+	}
+	return append(stack[:len(stack):len(stack)], p)
+}
+
+// CycleError is returned when packages import each other in a cycle.
+type CycleError struct {
+	// Pos is the position of the import that closes the cycle.
+	Pos scanner.Pos
+
+	// Path is the import paths of the packages in the cycle, starting
+	// and ending with the same package.
+	Path []string
+}
+
+func (err *CycleError) Error() string {
+	return fmt.Sprintf("(%v) import cycle: %v", err.Pos, strings.Join(err.Path, " -> "))
+}
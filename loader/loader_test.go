@@ -0,0 +1,116 @@
+package loader
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/parser"
+)
+
+func paths(pkgs []*Package) []string {
+	var paths []string
+	for _, pkg := range pkgs {
+		paths = append(paths, pkg.Path)
+	}
+	return paths
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main/a.stele":   {Data: []byte("import \"lib\"\nimport \"util\"")},
+		"main/b.stele":   {Data: []byte("import \"util\"")},
+		"lib/lib.stele":  {Data: []byte("import \"util\"\nlet x = 1")},
+		"util.stele":     {Data: []byte("let y = 2")},
+		"main/notes.txt": {Data: []byte("not source")},
+	}
+
+	fset := parser.NewFileSet()
+	l := Loader{FS: fsys, Fset: fset}
+	pkgs, err := l.Load("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := paths(pkgs); !slices.Equal(p, []string{"util", "lib", "main"}) {
+		t.Fatalf("unexpected order: %v", p)
+	}
+	if n := len(pkgs[2].Files); n != 2 {
+		t.Fatalf("expected main to have 2 files but got %v", n)
+	}
+	if p := paths(pkgs[2].Imports); !slices.Equal(p, []string{"lib", "util"}) {
+		t.Fatalf("unexpected imports of main: %v", p)
+	}
+	if pkgs[1].Imports[0] != pkgs[0] {
+		t.Fatal("util was loaded more than once")
+	}
+
+	// util is imported three times but must only be parsed once.
+	if n := len(fset.Files()); n != 4 {
+		t.Fatalf("expected 4 parsed files but got %v", n)
+	}
+	if fset.File("util.stele") == nil {
+		t.Fatal("util.stele missing from file set")
+	}
+}
+
+func TestLoadCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.stele": {Data: []byte(`import "b"`)},
+		"b.stele": {Data: []byte(`import "c"`)},
+		"c.stele": {Data: []byte(`import "b"`)},
+	}
+
+	l := Loader{FS: fsys}
+	_, err := l.Load("a")
+
+	var cerr *CycleError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected cycle error but got %v", err)
+	}
+	if !slices.Equal(cerr.Path, []string{"b", "c", "b"}) {
+		t.Fatalf("unexpected cycle: %v", cerr.Path)
+	}
+	if cerr.Pos.File != "c.stele" {
+		t.Fatalf("unexpected position: %v", cerr.Pos)
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.stele": {Data: []byte("\nimport \"missing\"")},
+	}
+
+	l := Loader{FS: fsys}
+	_, err := l.Load("a")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "a.stele:2:8") || !errors.Is(err, stele.ErrNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = l.Load("../escape")
+	if err == nil {
+		t.Fatal("expected error for invalid path")
+	}
+}
+
+func TestLoadParseError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/one.stele": {Data: []byte("let = 3")},
+	}
+
+	l := Loader{FS: fsys}
+	_, err := l.Load("a")
+	if (err == nil) || !strings.HasPrefix(err.Error(), "(a/one.stele:1:5)") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("parse error reported as missing file")
+	}
+}
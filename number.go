@@ -0,0 +1,261 @@
+package stele
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"math"
+)
+
+var (
+	// Int, Uint, Byte and Float are the fixed-size number types.
+	Int   = builtinType("int", nil, integerMethods()...)
+	Uint  = builtinType("uint", nil, integerMethods()...)
+	Byte  = builtinType("byte", nil, integerMethods()...)
+	Float = builtinType("float", nil, numberMethods()...)
+
+	// BigInt and BigFloat are the arbitrary-precision number types.
+	BigInt   = builtinType("bigint", nil, integerMethods()...)
+	BigFloat = builtinType("bigfloat", nil, numberMethods()...)
+
+	// AnyInt, AnyFloat and Numeric are satisfied by the integer,
+	// floating point and all number types, respectively. The methods
+	// of their members take and return the members themselves, so
+	// they have no methods in common, and are given them over their
+	// own self parameters instead.
+	AnyInt   = numberConstraint("anyint", integerMethods(), Int, Uint, Byte, BigInt)
+	AnyFloat = numberConstraint("anyfloat", numberMethods(), Float, BigFloat)
+	Numeric  = numberConstraint("numeric", numberMethods(), Int, Uint, Byte, Float, BigInt, BigFloat)
+
+	// UntypedInt and UntypedFloat are the types of numeric constants
+	// that have not been given a type yet.
+	UntypedInt   = layoutType("untyped int")
+	UntypedFloat = layoutType("untyped float")
+)
+
+// numberTypes is the number types in the order in which they are
+// preferred when a constant needs to be given one.
+var numberTypes = []Type{Int, Uint, Byte, Float, BigInt, BigFloat}
+
+// numberConstraint returns a oneof of the number types members with
+// the given methods.
+func numberConstraint(name string, methods []Feature, members ...Type) Type {
+	return Type{
+		Name:       name,
+		TypeParams: []TypeParam{{Name: self.Name}},
+		Features:   methods,
+		Oneof:      members,
+	}
+}
+
+// numberMethods returns the methods that the arithmetic and
+// comparison operators map to for a number type.
+func numberMethods() []Feature {
+	return []Feature{
+		operator("add", self, self),
+		operator("sub", self, self),
+		operator("mul", self, self),
+		operator("div", self, self),
+		operator("neg", self),
+		operator("eq", Bool, self),
+		operator("lt", Bool, self),
+		operator("le", Bool, self),
+	}
+}
+
+// integerMethods returns the methods of an integer type. These are
+// those of all numbers as well as those that the integer-only
+// operators map to.
+func integerMethods() []Feature {
+	return append(
+		numberMethods(),
+		operator("mod", self, self),
+		operator("and", self, self),
+		operator("or", self, self),
+		operator("xor", self, self),
+		operator("shl", self, self),
+		operator("shr", self, self),
+		operator("compl", self),
+	)
+}
+
+// Number returns true if t is one of the number types, or a type that
+// embeds one, including the types of untyped constants.
+func (t Type) Number() bool {
+	_, ok := t.number()
+	return ok
+}
+
+// Untyped returns true if t is the type of an untyped constant.
+func (t Type) Untyped() bool {
+	_, ok := t.number()
+	return ok && ((t.Name == UntypedInt.Name) || (t.Name == UntypedFloat.Name))
+}
+
+// number returns the name of the number layout of t, if it has one.
+func (t Type) number() (string, bool) {
+	for _, f := range t.FeatureSet() {
+		if f.Type != MemLayoutFeature {
+			continue
+		}
+		switch f.Name {
+		case "int", "uint", "byte", "float", "bigint", "bigfloat", "untyped int", "untyped float":
+			return f.Name, true
+		}
+	}
+	return "", false
+}
+
+// Convert returns c converted to type t. It returns an error if t is
+// not a number type or if the value of c can not be represented by t
+// without overflowing or being truncated.
+func (c Const) Convert(t Type) (Const, error) {
+	name, ok := t.number()
+	if !ok {
+		return Const{}, fmt.Errorf("constant %v is not a %v", c.Val, t)
+	}
+
+	v := c.Val
+	switch name {
+	case "int", "uint", "byte", "bigint", "untyped int":
+		i := constant.ToInt(v)
+		if i.Kind() != constant.Int {
+			return Const{}, fmt.Errorf("constant %v truncated to %v", c.Val, t)
+		}
+		v = i
+	case "float", "bigfloat", "untyped float":
+		v = constant.ToFloat(v)
+	}
+
+	var fits bool
+	switch name {
+	case "int":
+		_, fits = constant.Int64Val(v)
+	case "uint":
+		_, fits = constant.Uint64Val(v)
+	case "byte":
+		u, ok := constant.Uint64Val(v)
+		fits = ok && (u <= math.MaxUint8)
+	case "float":
+		f, _ := constant.Float64Val(v)
+		fits = !math.IsInf(f, 0)
+	default:
+		fits = true
+	}
+	if !fits {
+		return Const{}, fmt.Errorf("constant %v overflows %v", c.Val, t)
+	}
+
+	return Const{Val: v, T: t}, nil
+}
+
+// Default returns c converted to the first number type that satisfies
+// t and can represent c's value. This is used when a constant is used
+// as a type that many different number types satisfy.
+func (c Const) Default(t Type) (Const, bool) {
+	for _, n := range numberTypes {
+		if !n.Satisfies(t) {
+			continue
+		}
+		if r, err := c.Convert(n); err == nil {
+			return r, true
+		}
+	}
+	return Const{}, false
+}
+
+// Fold evaluates the binary operation op on a pair of constants. If
+// both are untyped, so is the result. Otherwise, the untyped operand,
+// if any, is converted to the type of the other first. Comparisons
+// result in a bool constant.
+func Fold(x Const, op token.Token, y Const) (Const, error) {
+	if x.T.Untyped() && !y.T.Untyped() {
+		var err error
+		if x, err = x.Convert(y.T); err != nil {
+			return Const{}, err
+		}
+	}
+	if y.T.Untyped() && !x.T.Untyped() {
+		var err error
+		if y, err = y.Convert(x.T); err != nil {
+			return Const{}, err
+		}
+	}
+	if !x.T.Untyped() && !(x.T.Satisfies(y.T) && y.T.Satisfies(x.T)) {
+		return Const{}, fmt.Errorf("mismatched types %v and %v", x.T, y.T)
+	}
+
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return Const{Val: constant.MakeBool(constant.Compare(x.Val, op, y.Val)), T: Bool}, nil
+
+	case token.SHL, token.SHR:
+		s, ok := constant.Uint64Val(constant.ToInt(y.Val))
+		if !ok || (s > math.MaxUint32) {
+			return Const{}, fmt.Errorf("invalid shift count %v", y.Val)
+		}
+		if constant.ToInt(x.Val).Kind() != constant.Int {
+			return Const{}, fmt.Errorf("invalid shift of non-integer constant %v", x.Val)
+		}
+		return x.result(constant.Shift(constant.ToInt(x.Val), op, uint(s)))
+
+	case token.QUO, token.REM:
+		if constant.Sign(y.Val) == 0 {
+			return Const{}, fmt.Errorf("division by zero")
+		}
+	}
+
+	if (op == token.QUO) && (x.Val.Kind() == constant.Int) && (y.Val.Kind() == constant.Int) {
+		// Integer constants stay integers when divided. go/constant
+		// uses QUO_ASSIGN to mean integer division.
+		op = token.QUO_ASSIGN
+	}
+	if (op == token.REM) || (op == token.AND) || (op == token.OR) || (op == token.XOR) {
+		xi, yi := constant.ToInt(x.Val), constant.ToInt(y.Val)
+		if (xi.Kind() != constant.Int) || (yi.Kind() != constant.Int) {
+			return Const{}, fmt.Errorf("operator %v not defined on non-integer constants", op)
+		}
+		x.Val, y.Val = xi, yi
+	}
+
+	return x.result(constant.BinaryOp(x.Val, op, y.Val))
+}
+
+// FoldUnary evaluates the unary operation op on a constant.
+func FoldUnary(op token.Token, x Const) (Const, error) {
+	switch op {
+	case token.NOT:
+		if x.Val.Kind() != constant.Bool {
+			return Const{}, fmt.Errorf("operator ! not defined on %v", x.T)
+		}
+		return Const{Val: constant.UnaryOp(op, x.Val, 0), T: x.T}, nil
+	case token.XOR:
+		if constant.ToInt(x.Val).Kind() != constant.Int {
+			return Const{}, fmt.Errorf("operator ^ not defined on non-integer constant %v", x.Val)
+		}
+
+		// The complement of an unsigned value depends on its size.
+		var prec uint
+		switch name, _ := x.T.number(); name {
+		case "uint":
+			prec = 64
+		case "byte":
+			prec = 8
+		}
+		return x.result(constant.UnaryOp(op, constant.ToInt(x.Val), prec))
+	}
+	return x.result(constant.UnaryOp(op, x.Val, 0))
+}
+
+// result returns v as a constant of the same type as c, checking that
+// it still fits.
+func (c Const) result(v constant.Value) (Const, error) {
+	r := Const{Val: v, T: c.T}
+	switch {
+	case c.T.Untyped():
+		return UntypedConst(v), nil
+	case !c.T.Number():
+		return r, nil
+	}
+	return r.Convert(c.T)
+}
@@ -0,0 +1,125 @@
+package stele
+
+import (
+	"go/constant"
+	"go/token"
+	"testing"
+)
+
+func TestConstConvert(t *testing.T) {
+	big := constant.Shift(constant.MakeInt64(1), token.SHL, 100)
+
+	tests := []struct {
+		name string
+		val  constant.Value
+		to   Type
+		err  string
+	}{
+		{name: "Int", val: constant.MakeInt64(3), to: Int},
+		{name: "Byte", val: constant.MakeInt64(255), to: Byte},
+		{name: "ByteOverflow", val: constant.MakeInt64(256), to: Byte, err: "constant 256 overflows byte"},
+		{name: "UintNegative", val: constant.MakeInt64(-1), to: Uint, err: "constant -1 overflows uint"},
+		{name: "IntOverflow", val: big, to: Int, err: "constant 1267650600228229401496703205376 overflows int"},
+		{name: "BigInt", val: big, to: BigInt},
+		{name: "Truncated", val: constant.MakeFloat64(1.5), to: Int, err: "constant 1.5 truncated to int"},
+		{name: "WholeFloat", val: constant.MakeFloat64(2), to: Int},
+		{name: "Float", val: constant.MakeInt64(2), to: Float},
+		{name: "NotNumber", val: constant.MakeInt64(2), to: Bool, err: "constant 2 is not a bool"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := UntypedConst(test.val).Convert(test.to)
+			if test.err != "" {
+				if (err == nil) || (err.Error() != test.err) {
+					t.Fatalf("expected error %q but got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.T.Name != test.to.Name {
+				t.Fatalf("unexpected type: %v", c.T)
+			}
+		})
+	}
+}
+
+func TestConstDefault(t *testing.T) {
+	c, ok := UntypedConst(constant.MakeFloat64(1.5)).Default(Numeric)
+	if !ok || (c.T.Name != "float") {
+		t.Fatalf("unexpected default: %v, %v", c.T, ok)
+	}
+
+	c, ok = UntypedConst(constant.MakeInt64(3)).Default(Any)
+	if !ok || (c.T.Name != "int") {
+		t.Fatalf("unexpected default: %v, %v", c.T, ok)
+	}
+
+	if _, ok := UntypedConst(constant.MakeInt64(3)).Default(Bool); ok {
+		t.Fatal("expected no default for bool")
+	}
+}
+
+func TestFold(t *testing.T) {
+	one := UntypedConst(constant.MakeInt64(1))
+
+	// Untyped constants keep their precision until they are converted.
+	huge, err := Fold(one, token.SHL, UntypedConst(constant.MakeInt64(200)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Fold(huge, token.SHR, UntypedConst(constant.MakeInt64(199)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := constant.Int64Val(r.Val); (v != 2) || !r.T.Untyped() {
+		t.Fatalf("unexpected result: %v %v", r.Val, r.T)
+	}
+
+	r, err = Fold(UntypedConst(constant.MakeInt64(7)), token.QUO, UntypedConst(constant.MakeInt64(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := constant.Int64Val(r.Val); v != 3 {
+		t.Fatalf("unexpected integer division result: %v", r.Val)
+	}
+
+	typed, _ := UntypedConst(constant.MakeInt64(200)).Convert(Byte)
+	if _, err := Fold(typed, token.ADD, UntypedConst(constant.MakeInt64(100))); (err == nil) || (err.Error() != "constant 300 overflows byte") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := Fold(one, token.QUO, UntypedConst(constant.MakeInt64(0))); (err == nil) || (err.Error() != "division by zero") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err = Fold(one, token.LSS, UntypedConst(constant.MakeInt64(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !constant.BoolVal(r.Val) || (r.T.Name != "bool") {
+		t.Fatalf("unexpected comparison result: %v %v", r.Val, r.T)
+	}
+}
+
+func TestConstAllocs(t *testing.T) {
+	state := NewState()
+	consts := []Const{
+		{Val: constant.MakeInt64(3), T: Int},
+		{Val: constant.MakeInt64(3), T: Byte},
+		{Val: constant.MakeFloat64(0.5), T: Float},
+		{Val: constant.MakeBool(true), T: Bool},
+	}
+	for _, c := range consts {
+		c := c.Resolve(state.Descs)
+		allocs := testing.AllocsPerRun(100, func() { c.Eval(state) })
+		if allocs != 0 {
+			t.Fatalf("evaluating %v constant %v allocated %v times", c.T, c.Val, allocs)
+		}
+	}
+}
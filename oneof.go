@@ -0,0 +1,103 @@
+package stele
+
+import "slices"
+
+// Oneof returns a oneof type with the given members. Members that are
+// equivalent to an earlier member are dropped, and if only a single
+// member is left, it is returned as is.
+func Oneof(members ...Type) Type {
+	var list []Type
+	for _, m := range members {
+		// Nested oneof lists are flattened into a single list.
+		mf, mo := m.flatten(Type{})
+		if (len(mo) > 0) && (len(mf) == 0) {
+			list = appendMembers(list, mo...)
+			continue
+		}
+		list = appendMembers(list, m)
+	}
+
+	if len(list) == 1 {
+		return list[0]
+	}
+	return Type{Oneof: list}
+}
+
+func appendMembers(list []Type, members ...Type) []Type {
+outer:
+	for _, m := range members {
+		for _, prev := range list {
+			if prev.Satisfies(m) && m.Satisfies(prev) {
+				continue outer
+			}
+		}
+		list = append(list, m)
+	}
+	return list
+}
+
+// Members returns the members of t's oneof list, including those of
+// any oneof types that it embeds.
+func (t Type) Members() []Type {
+	_, oneof := t.flatten(Type{})
+	return oneof
+}
+
+// FeatureSet returns the features that may be used on a value of type
+// t. For most types, these are t's own features along with those of
+// every type that it embeds, with t's self parameter referring to t.
+// For a oneof type, they are the features that all of its members have
+// in common, along with any that the oneof type has itself, such as
+// the operator methods of the predeclared number constraints.
+func (t Type) FeatureSet() []Feature {
+	features, oneof := t.flatten(t)
+	if len(oneof) == 0 {
+		return features
+	}
+
+	common := oneof[0].FeatureSet()
+	for _, m := range oneof[1:] {
+		common = intersect(common, m.FeatureSet())
+	}
+	for _, f := range features {
+		if !slices.ContainsFunc(common, func(c Feature) bool { return c.key() == f.key() }) {
+			common = append(common, f)
+		}
+	}
+	return common
+}
+
+// intersect returns the features in a that are also in b with the
+// same signature.
+func intersect(a, b []Feature) []Feature {
+	index := make(map[featureKey]Feature, len(b))
+	for _, f := range b {
+		index[f.key()] = f
+	}
+
+	var r []Feature
+	for _, f := range a {
+		o, ok := index[f.key()]
+		if !ok || (f.MutRecv != o.MutRecv) || (f.Mut != o.Mut) {
+			continue
+		}
+
+		var s satisfier
+		s.seen = make(map[[2]string]struct{})
+		if s.same(f.Args, o.Args) && s.same([]Type{f.Return}, []Type{o.Return}) {
+			r = append(r, f)
+		}
+	}
+	return r
+}
+
+// Feature returns the feature of t's FeatureSet that is of the given
+// type and has the given name.
+func (t Type) Feature(ft FeatureType, name string) (Feature, bool) {
+	for _, f := range t.FeatureSet() {
+		if (f.Type == ft) && (f.Name == name) {
+			return f, true
+		}
+	}
+	return Feature{}, false
+}
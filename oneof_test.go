@@ -0,0 +1,50 @@
+package stele
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOneof(t *testing.T) {
+	var (
+		intT    = layout("int")
+		stringT = layout("string")
+		alias   = Type{Name: "alias", Features: []Feature{embed(intT)}}
+		number  = Oneof(intT, stringT)
+	)
+
+	if m := Oneof(intT); m.Name != "int" {
+		t.Fatalf("expected single member to be returned but got %v", m)
+	}
+	if m := Oneof(intT, alias).Members(); len(m) != 0 {
+		t.Fatalf("expected equivalent members to be merged but got %v", typeList(m))
+	}
+	if m := Oneof(number, intT, Unit).Members(); !slices.EqualFunc(m, []Type{intT, stringT, Unit}, func(a, b Type) bool { return a.Name == b.Name }) {
+		t.Fatalf("unexpected members: %v", typeList(m))
+	}
+}
+
+func TestFeatureSet(t *testing.T) {
+	var (
+		intT = layout("int")
+		a    = Type{Name: "a", Features: []Feature{
+			method("a", false, intT),
+			method("b", false, intT),
+		}}
+		b = Type{Name: "b", Features: []Feature{
+			method("a", false, intT),
+			method("b", false, Unit),
+		}}
+		e = Type{Name: "e", Oneof: []Type{a, b}}
+	)
+
+	if _, ok := e.Feature(FuncFeature, "a"); !ok {
+		t.Fatal("expected common method a to be in feature set")
+	}
+	if _, ok := e.Feature(FuncFeature, "b"); ok {
+		t.Fatal("expected method b with differing signatures not to be in feature set")
+	}
+	if fs := a.FeatureSet(); len(fs) != 2 {
+		t.Fatalf("unexpected feature set: %v", fs)
+	}
+}
@@ -0,0 +1,388 @@
+package stele
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// The methods of the predeclared types, including those that operators
+// map to, are implemented here for the Go representations of their
+// values.
+
+type integer interface {
+	int64 | uint64 | byte
+}
+
+// CallBuiltin calls the method name of one of the predeclared types
+// on recv. It returns false if recv has no such method. Errors, such as
+// division by zero, panic with a *RuntimeError at s.Pos.
+func (s *State) CallBuiltin(recv Value, name string, args ...Value) (Value, bool) {
+	if (len(args) == 1) && !sameNumber(recv, args[0]) {
+		args = []Value{coerce(recv, args[0])}
+	}
+
+	switch recv.kind {
+	case IntKind:
+		return integerOp(s, recv, int64(recv.bits), name, args)
+	case UintKind:
+		return integerOp(s, recv, recv.bits, name, args)
+	case ByteKind:
+		return integerOp(s, recv, byte(recv.bits), name, args)
+	case FloatKind:
+		return s.floatMethod(recv, recv.Float(), name, args)
+	case BoolKind:
+		return boolMethod(recv.Bool(), name, args)
+	case RefKind:
+	default:
+		return Value{}, false
+	}
+
+	var r any
+	switch x := recv.ref.(type) {
+	case *big.Int:
+		r = s.bigIntMethod(x, name, args)
+	case *big.Float:
+		r = s.bigFloatMethod(x, name, args)
+	case string:
+		r = s.stringMethod(x, name, args)
+	case *Slice:
+		r = s.arrayMethod(x, name, args)
+	case *RuntimeError:
+		r = errorMethod(x, name)
+	}
+
+	switch r := r.(type) {
+	case nil:
+		return Value{}, false
+	case Value:
+		return r, true
+	case bool:
+		return MakeBool(boolDesc, r), true
+	default:
+		// Everything else is the result of an operation on the
+		// receiver's type, such as the sum of two numbers.
+		return Value{desc: recv.desc, kind: RefKind, ref: r, chain: recv.chain}, true
+	}
+}
+
+// numberRep returns the name of the number layout that v is
+// represented as, if it is a number.
+func numberRep(v Value) (string, bool) {
+	switch v.kind {
+	case IntKind:
+		return "int", true
+	case UintKind:
+		return "uint", true
+	case ByteKind:
+		return "byte", true
+	case FloatKind:
+		return "float", true
+	case RefKind:
+		switch v.ref.(type) {
+		case *big.Int:
+			return "bigint", true
+		case *big.Float:
+			return "bigfloat", true
+		}
+	}
+	return "", false
+}
+
+// sameNumber returns false if x and y are numbers that are represented
+// differently.
+func sameNumber(x, y Value) bool {
+	if (x.kind == y.kind) && (x.kind != RefKind) {
+		return true
+	}
+	xr, xok := numberRep(x)
+	yr, yok := numberRep(y)
+	return !xok || !yok || (xr == yr)
+}
+
+// coerce returns y, a number that is an operand of an operator on the
+// number x, as a number with the same representation as x. They only
+// differ when the static type of the operation is one that several
+// number types satisfy, such as numeric, whose constants are ints.
+func coerce(x, y Value) Value {
+	var f *big.Float
+	switch y.kind {
+	case IntKind:
+		f = new(big.Float).SetInt64(y.Int())
+	case UintKind, ByteKind:
+		f = new(big.Float).SetUint64(y.bits)
+	case FloatKind:
+		if math.IsNaN(y.Float()) {
+			if x.kind == FloatKind {
+				return MakeFloat(x.desc, y.Float())
+			}
+			f = new(big.Float)
+			break
+		}
+		f = big.NewFloat(y.Float())
+	default:
+		switch r := y.ref.(type) {
+		case *big.Int:
+			f = new(big.Float).SetInt(r)
+		case *big.Float:
+			f = r
+		}
+	}
+
+	switch x.kind {
+	case IntKind:
+		i, _ := f.Int64()
+		return MakeInt(x.desc, i)
+	case UintKind:
+		u, _ := f.Uint64()
+		return MakeUint(x.desc, u)
+	case ByteKind:
+		u, _ := f.Uint64()
+		return MakeByte(x.desc, byte(u))
+	case FloatKind:
+		r, _ := f.Float64()
+		return MakeFloat(x.desc, r)
+	}
+	if _, ok := x.ref.(*big.Int); ok {
+		i, _ := f.Int(nil)
+		return ValueOf(x.desc, i)
+	}
+	return ValueOf(x.desc, new(big.Float).Set(f))
+}
+
+// integerOp calls the method name on recv, which is an integer with
+// the value x. Its result, if it is another integer, is of the same
+// type.
+func integerOp[T integer](s *State, recv Value, x T, name string, args []Value) (Value, bool) {
+	switch name {
+	case "neg":
+		return recv.with(uint64(-x)), true
+	case "compl":
+		return recv.with(uint64(^x)), true
+	}
+	if len(args) != 1 {
+		return Value{}, false
+	}
+
+	y := T(args[0].bits)
+	switch name {
+	case "add":
+		x += y
+	case "sub":
+		x -= y
+	case "mul":
+		x *= y
+	case "div":
+		if y == 0 {
+			s.panicf("integer division by zero")
+		}
+		x /= y
+	case "mod":
+		if y == 0 {
+			s.panicf("integer division by zero")
+		}
+		x %= y
+	case "and":
+		x &= y
+	case "or":
+		x |= y
+	case "xor":
+		x ^= y
+	case "shl", "shr":
+		if y < 0 {
+			s.panicf("negative shift count %v", y)
+		}
+		if name == "shl" {
+			x <<= y
+		} else {
+			x >>= y
+		}
+	case "eq":
+		return MakeBool(boolDesc, x == y), true
+	case "lt":
+		return MakeBool(boolDesc, x < y), true
+	case "le":
+		return MakeBool(boolDesc, x <= y), true
+	default:
+		return Value{}, false
+	}
+	return recv.with(uint64(x)), true
+}
+
+func (s *State) floatMethod(recv Value, x float64, name string, args []Value) (Value, bool) {
+	if name == "neg" {
+		return recv.with(math.Float64bits(-x)), true
+	}
+	if len(args) != 1 {
+		return Value{}, false
+	}
+
+	y := args[0].Float()
+	switch name {
+	case "add":
+		x += y
+	case "sub":
+		x -= y
+	case "mul":
+		x *= y
+	case "div":
+		x /= y
+	case "eq":
+		return MakeBool(boolDesc, x == y), true
+	case "lt":
+		return MakeBool(boolDesc, x < y), true
+	case "le":
+		return MakeBool(boolDesc, x <= y), true
+	default:
+		return Value{}, false
+	}
+	return recv.with(math.Float64bits(x)), true
+}
+
+func (s *State) bigIntMethod(x *big.Int, name string, args []Value) any {
+	r := new(big.Int)
+	switch name {
+	case "neg":
+		return r.Neg(x)
+	case "compl":
+		return r.Not(x)
+	}
+	if len(args) != 1 {
+		return nil
+	}
+
+	y := args[0].ref.(*big.Int)
+	switch name {
+	case "add":
+		return r.Add(x, y)
+	case "sub":
+		return r.Sub(x, y)
+	case "mul":
+		return r.Mul(x, y)
+	case "div", "mod":
+		if y.Sign() == 0 {
+			s.panicf("integer division by zero")
+		}
+		if name == "div" {
+			return r.Quo(x, y)
+		}
+		return r.Rem(x, y)
+	case "and":
+		return r.And(x, y)
+	case "or":
+		return r.Or(x, y)
+	case "xor":
+		return r.Xor(x, y)
+	case "shl", "shr":
+		if !y.IsUint64() || (y.Uint64() > 1<<32) {
+			s.panicf("invalid shift count %v", y)
+		}
+		if name == "shl" {
+			return r.Lsh(x, uint(y.Uint64()))
+		}
+		return r.Rsh(x, uint(y.Uint64()))
+	case "eq":
+		return x.Cmp(y) == 0
+	case "lt":
+		return x.Cmp(y) < 0
+	case "le":
+		return x.Cmp(y) <= 0
+	}
+	return nil
+}
+
+func (s *State) bigFloatMethod(x *big.Float, name string, args []Value) any {
+	r := new(big.Float)
+	if name == "neg" {
+		return r.Neg(x)
+	}
+	if len(args) != 1 {
+		return nil
+	}
+
+	y := args[0].ref.(*big.Float)
+	switch name {
+	case "add":
+		return r.Add(x, y)
+	case "sub":
+		return r.Sub(x, y)
+	case "mul":
+		return r.Mul(x, y)
+	case "div":
+		if y.Sign() == 0 {
+			s.panicf("division by zero")
+		}
+		return r.Quo(x, y)
+	case "eq":
+		return x.Cmp(y) == 0
+	case "lt":
+		return x.Cmp(y) < 0
+	case "le":
+		return x.Cmp(y) <= 0
+	}
+	return nil
+}
+
+func boolMethod(x bool, name string, args []Value) (Value, bool) {
+	switch name {
+	case "not":
+		return MakeBool(boolDesc, !x), true
+	case "eq":
+		return MakeBool(boolDesc, x == args[0].Bool()), true
+	}
+	return Value{}, false
+}
+
+func (s *State) stringMethod(x string, name string, args []Value) any {
+	switch name {
+	case "len":
+		return MakeInt(intDesc, int64(len(x)))
+	case "get":
+		i := s.index(args[0], len(x))
+		return MakeByte(byteDesc, x[i])
+	case "add":
+		return x + args[0].ref.(string)
+	case "eq":
+		return x == args[0].ref.(string)
+	case "lt":
+		return strings.Compare(x, args[0].ref.(string)) < 0
+	case "le":
+		return strings.Compare(x, args[0].ref.(string)) <= 0
+	}
+	return nil
+}
+
+// errorMethod calls the method name of a run-time error that has been
+// returned as a value. Its message does not include its position.
+func errorMethod(x *RuntimeError, name string) any {
+	if name == "error" {
+		return ValueOf(stringDesc, x.Msg)
+	}
+	return nil
+}
+
+func (s *State) arrayMethod(x *Slice, name string, args []Value) any {
+	switch name {
+	case "len":
+		return MakeInt(intDesc, int64(len(x.Elems)))
+	case "get":
+		return x.Get(s.index(args[0], len(x.Elems)))
+	case "set":
+		x.Set(s.index(args[0], len(x.Elems)), args[1])
+		return UnitValue
+	case "append":
+		x.Append(args[0])
+		return UnitValue
+	}
+	return nil
+}
+
+// index returns the int value of i, checking that it is a valid index
+// into something of length n.
+func (s *State) index(i Value, n int) int {
+	v := i.Int()
+	if (v < 0) || (v >= int64(n)) {
+		s.panicf("index %v out of range with length %v", v, n)
+	}
+	return int(v)
+}
@@ -0,0 +1,77 @@
+// Package ast declares the types used to represent the syntax tree of
+// Stele source code.
+//
+// The syntax tree is purely syntactic. It records what was written,
+// in the order that it was written, without any attempt to resolve
+// identifiers or to check types. Lowering a syntax tree into a
+// runnable [stele.Script] is done by a separate stage.
+package ast
+
+import "deedles.dev/stele/scanner"
+
+// Node is implemented by all nodes in the syntax tree.
+type Node interface {
+	// Pos returns the position of the first character belonging to the
+	// node.
+	Pos() scanner.Pos
+}
+
+// Expr is implemented by all expression nodes, including type
+// expressions.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Stmt is implemented by all statement nodes.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Decl is implemented by all top-level declaration nodes.
+type Decl interface {
+	Node
+	declNode()
+}
+
+// File is the syntax tree of a single source file.
+type File struct {
+	// Name is the name that the file was parsed with. It may be empty.
+	Name string
+
+	// Decls is every top-level declaration in the file, including
+	// imports, in the order in which they appear.
+	Decls []Decl
+
+	// Imports is the subset of Decls that are imports.
+	Imports []*Import
+
+	// Comments is every comment in the file in the order in which
+	// they appear.
+	Comments []*Comment
+}
+
+func (f *File) Pos() scanner.Pos {
+	if len(f.Decls) == 0 {
+		return scanner.Pos{Line: 1, Col: 1}
+	}
+	return f.Decls[0].Pos()
+}
+
+// Comment is a single line comment. Text includes the leading #.
+type Comment struct {
+	Hash scanner.Pos
+	Text string
+}
+
+func (c *Comment) Pos() scanner.Pos { return c.Hash }
+
+// Package is a set of files that together make up a single package.
+type Package struct {
+	// Path is the path that the package was loaded from.
+	Path string
+
+	// Files is the files of the package, sorted by name.
+	Files []*File
+}
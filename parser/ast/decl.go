@@ -1,28 +1,52 @@
 package ast
 
-import (
-	"strings"
-
-	"deedles.dev/stele"
-)
+import "deedles.dev/stele/scanner"
 
+// Import is an import declaration. If the import was not explicitly
+// named with as, Name is nil.
 type Import struct {
-	Name string
-	Path string
+	Import scanner.Pos
+	Path   *BasicLit
+	Name   *Ident
 }
 
-func (d Import) ID() string       { return d.Name }
-func (d Import) Type() stele.Type { panic("Not implemented.") }
-func (d Import) Mutable() bool    { return false }
-func (d Import) Exported() bool   { return false }
-
+// Let declares one or more variables. Type and Value are nil if they
+// were omitted. A Let is valid both at the top level of a file and as
+// a statement.
 type Let struct {
-	Name   string
-	T      stele.Type
-	Assign *stele.Assign
+	Let   scanner.Pos
+	Names []*Ident
+	Type  Expr
+	Value Expr
+}
+
+// Func is a function declaration. Recv and TypeParams are nil if they
+// were omitted.
+type Func struct {
+	Func       scanner.Pos
+	TypeParams *TypeParamList
+	Recv       *Field
+	Name       *Ident
+	Type       *FuncType
+	Body       *Block
 }
 
-func (d Let) ID() string       { return d.Name }
-func (d Let) Type() stele.Type { return d.T }
-func (d Let) Mutable() bool    { return !strings.HasSuffix(d.Name, "!") }
-func (d Let) Exported() bool   { return !strings.HasPrefix(d.Name, "_") }
+// TypeDecl is a type declaration.
+type TypeDecl struct {
+	Type       scanner.Pos
+	TypeParams *TypeParamList
+	Name       *Ident
+	Def        Expr
+}
+
+func (d *Import) Pos() scanner.Pos   { return d.Import }
+func (d *Let) Pos() scanner.Pos      { return d.Let }
+func (d *Func) Pos() scanner.Pos     { return d.Func }
+func (d *TypeDecl) Pos() scanner.Pos { return d.Type }
+
+func (*Import) declNode()   {}
+func (*Let) declNode()      {}
+func (*Func) declNode()     {}
+func (*TypeDecl) declNode() {}
+
+func (*Let) stmtNode() {}
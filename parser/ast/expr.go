@@ -0,0 +1,146 @@
+package ast
+
+import (
+	"strings"
+
+	"deedles.dev/stele/scanner"
+)
+
+// Ident is an identifier. Name includes the trailing ! of an
+// immutable identifier, if there is one.
+type Ident struct {
+	NamePos scanner.Pos
+	Name    string
+}
+
+// IsImmutable returns true if the identifier was declared with a
+// trailing !.
+func (x *Ident) IsImmutable() bool {
+	return strings.HasSuffix(x.Name, "!")
+}
+
+// ID returns the name of the identifier without any trailing !.
+func (x *Ident) ID() string {
+	return strings.TrimSuffix(x.Name, "!")
+}
+
+// Selector is a field or method selection, such as x.y.
+type Selector struct {
+	X   Expr
+	Sel *Ident
+}
+
+// Index is an index expression, such as x[0]. If X is a generic type,
+// the Indices are type arguments, such as in array[int].
+type Index struct {
+	X       Expr
+	Lbrack  scanner.Pos
+	Indices []Expr
+	Rbrack  scanner.Pos
+}
+
+// Call is a function call, including a conversion.
+type Call struct {
+	Fun    Expr
+	Lparen scanner.Pos
+	Args   []Expr
+	Rparen scanner.Pos
+}
+
+// Unary is a unary operation, such as -x.
+type Unary struct {
+	OpPos scanner.Pos
+	Op    scanner.Type
+	X     Expr
+}
+
+// Binary is a binary operation, such as x + y. Pipes are represented
+// as Binary expressions with an Op of PIPE.
+type Binary struct {
+	X     Expr
+	OpPos scanner.Pos
+	Op    scanner.Type
+	Y     Expr
+}
+
+// Paren is a parenthesized expression.
+type Paren struct {
+	Lparen scanner.Pos
+	X      Expr
+	Rparen scanner.Pos
+}
+
+// TypeAssert is a type assertion, such as x.(int).
+type TypeAssert struct {
+	X      Expr
+	Lparen scanner.Pos
+	Type   Expr
+	Rparen scanner.Pos
+}
+
+// Try is a use of the error propagation operator, such as x?.
+type Try struct {
+	X        Expr
+	Question scanner.Pos
+}
+
+// If is an if expression. Else is either nil, a *Block, or another
+// *If.
+type If struct {
+	If   scanner.Pos
+	Cond Expr
+	Body *Block
+	Else Expr
+}
+
+// Switch is a switch expression. Tag is nil if it was omitted, in
+// which case each case is a boolean condition.
+type Switch struct {
+	Switch scanner.Pos
+	Tag    Expr
+	Lbrace scanner.Pos
+	Cases  []*Case
+	Rbrace scanner.Pos
+}
+
+// Case is a single case of a switch. Exactly one of the following
+// is true for any given case:
+//
+//   - Else is true, for an else case.
+//   - Type is non-nil, for a type assertion case, such as .(int).
+//   - Op is a comparison operator and Value is non-nil, such as <= 1.
+//   - Op is INVALID and Value is non-nil, for a boolean condition.
+type Case struct {
+	CasePos scanner.Pos
+	Else    bool
+	Type    Expr
+	Op      scanner.Type
+	Value   Expr
+	Body    *Block
+}
+
+func (x *Ident) Pos() scanner.Pos      { return x.NamePos }
+func (x *Selector) Pos() scanner.Pos   { return x.X.Pos() }
+func (x *Index) Pos() scanner.Pos      { return x.X.Pos() }
+func (x *Call) Pos() scanner.Pos       { return x.Fun.Pos() }
+func (x *Unary) Pos() scanner.Pos      { return x.OpPos }
+func (x *Binary) Pos() scanner.Pos     { return x.X.Pos() }
+func (x *Paren) Pos() scanner.Pos      { return x.Lparen }
+func (x *TypeAssert) Pos() scanner.Pos { return x.X.Pos() }
+func (x *Try) Pos() scanner.Pos        { return x.X.Pos() }
+func (x *If) Pos() scanner.Pos         { return x.If }
+func (x *Switch) Pos() scanner.Pos     { return x.Switch }
+func (x *Case) Pos() scanner.Pos       { return x.CasePos }
+
+func (*Ident) exprNode()      {}
+func (*Selector) exprNode()   {}
+func (*Index) exprNode()      {}
+func (*Call) exprNode()       {}
+func (*Unary) exprNode()      {}
+func (*Binary) exprNode()     {}
+func (*Paren) exprNode()      {}
+func (*TypeAssert) exprNode() {}
+func (*Try) exprNode()        {}
+func (*If) exprNode()         {}
+func (*Switch) exprNode()     {}
+func (*Block) exprNode()      {}
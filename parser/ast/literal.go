@@ -1,16 +1,71 @@
 package ast
 
-import "deedles.dev/stele"
+import "deedles.dev/stele/scanner"
 
-type Int struct {
-	Val int64
+// BasicLit is a literal of a basic kind. Kind is one of INT, FLOAT, or
+// STRING, and Value is the value produced by the scanner for the
+// literal. Character literals are INTs with a rune Value. INTs and
+// FLOATs that are too large for an int64 or float64 have a *big.Int or
+// *big.Float Value, respectively.
+type BasicLit struct {
+	ValuePos scanner.Pos
+	Kind     scanner.Type
+	Value    any
 }
 
-func (i Int) Type() stele.Type {
-	// TODO: Return a type for int literals.
-	return stele.Type{}
+// IsChar returns true if the literal was written as a character
+// literal.
+func (lit *BasicLit) IsChar() bool {
+	_, ok := lit.Value.(rune)
+	return ok
 }
 
-func (i Int) Eval(state *stele.State) stele.Value {
-	panic("Not implemented.")
+// TupleLit is a tuple literal, such as (1, "two").
+type TupleLit struct {
+	Lparen scanner.Pos
+	Elems  []Expr
+	Rparen scanner.Pos
 }
+
+// ArrayLit is an array literal, such as [1, 2, 3].
+type ArrayLit struct {
+	Lbrack scanner.Pos
+	Elems  []Expr
+	Rbrack scanner.Pos
+}
+
+// StructLit is a struct literal, such as &example{name = "example"}.
+// Type may be a Call if the literal also initializes an embedded
+// tuple.
+type StructLit struct {
+	Amp    scanner.Pos
+	Type   Expr
+	Lbrace scanner.Pos
+	Fields []*FieldInit
+	Rbrace scanner.Pos
+}
+
+// FieldInit is the initialization of a single field in a StructLit.
+type FieldInit struct {
+	Name  *Ident
+	Value Expr
+}
+
+// FuncLit is a closure.
+type FuncLit struct {
+	Type *FuncType
+	Body *Block
+}
+
+func (x *BasicLit) Pos() scanner.Pos  { return x.ValuePos }
+func (x *TupleLit) Pos() scanner.Pos  { return x.Lparen }
+func (x *ArrayLit) Pos() scanner.Pos  { return x.Lbrack }
+func (x *StructLit) Pos() scanner.Pos { return x.Amp }
+func (x *FieldInit) Pos() scanner.Pos { return x.Name.Pos() }
+func (x *FuncLit) Pos() scanner.Pos   { return x.Type.Pos() }
+
+func (*BasicLit) exprNode()  {}
+func (*TupleLit) exprNode()  {}
+func (*ArrayLit) exprNode()  {}
+func (*StructLit) exprNode() {}
+func (*FuncLit) exprNode()   {}
@@ -0,0 +1,59 @@
+package ast
+
+import "deedles.dev/stele/scanner"
+
+// Block is a braced list of statements. A Block is also an Expr, as
+// a block whose only statement is an expression evaluates to the value
+// of that expression.
+type Block struct {
+	Lbrace scanner.Pos
+	Stmts  []Stmt
+	Rbrace scanner.Pos
+}
+
+// ExprStmt is an expression used as a statement.
+type ExprStmt struct {
+	X Expr
+}
+
+// Assign is an assignment, such as x = 3 or a, b = t. Tok is either
+// ASSIGN or one of the operator assignments, such as PLUSASSIGN.
+type Assign struct {
+	Lhs    []Expr
+	TokPos scanner.Pos
+	Tok    scanner.Type
+	Rhs    Expr
+}
+
+// Return is a return statement. Result is nil if it was omitted.
+type Return struct {
+	Return scanner.Pos
+	Result Expr
+}
+
+// Branch is a break or continue statement.
+type Branch struct {
+	TokPos scanner.Pos
+	Tok    scanner.Type
+}
+
+// For is a for loop. Cond is nil for an infinite loop.
+type For struct {
+	For  scanner.Pos
+	Cond Expr
+	Body *Block
+}
+
+func (s *Block) Pos() scanner.Pos    { return s.Lbrace }
+func (s *ExprStmt) Pos() scanner.Pos { return s.X.Pos() }
+func (s *Assign) Pos() scanner.Pos   { return s.Lhs[0].Pos() }
+func (s *Return) Pos() scanner.Pos   { return s.Return }
+func (s *Branch) Pos() scanner.Pos   { return s.TokPos }
+func (s *For) Pos() scanner.Pos      { return s.For }
+
+func (*Block) stmtNode()    {}
+func (*ExprStmt) stmtNode() {}
+func (*Assign) stmtNode()   {}
+func (*Return) stmtNode()   {}
+func (*Branch) stmtNode()   {}
+func (*For) stmtNode()      {}
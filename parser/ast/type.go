@@ -0,0 +1,125 @@
+package ast
+
+import "deedles.dev/stele/scanner"
+
+// TypeParamList is a bracketed list of type parameters, such as
+// [T, E any].
+type TypeParamList struct {
+	Lbrack scanner.Pos
+	List   []*TypeParam
+	Rbrack scanner.Pos
+}
+
+// TypeParam is a single type parameter. Constraint is nil if the
+// parameter is unconstrained.
+type TypeParam struct {
+	Name       *Ident
+	Constraint Expr
+}
+
+// FieldList is a parenthesized list of function parameters.
+type FieldList struct {
+	Lparen scanner.Pos
+	List   []*Field
+	Rparen scanner.Pos
+}
+
+// NumFields returns the number of parameters declared by the list.
+func (l *FieldList) NumFields() int {
+	if l == nil {
+		return 0
+	}
+
+	var n int
+	for _, f := range l.List {
+		n += max(len(f.Names), 1)
+	}
+	return n
+}
+
+// Field is a group of function parameters sharing a type. Names is
+// empty in a function type, and Type is nil for an untyped parameter
+// of a closure.
+type Field struct {
+	Names []*Ident
+	Type  Expr
+}
+
+// FuncType is a function signature. Arrow is only valid if the
+// signature was written as a standalone function type or closure
+// starting with ->. Params is nil if the parameter list was omitted.
+type FuncType struct {
+	Arrow  scanner.Pos
+	Params *FieldList
+	Mut    bool
+	Result Expr
+}
+
+// TupleType is a tuple type, such as (string, int).
+type TupleType struct {
+	Lparen scanner.Pos
+	Elems  []Expr
+	Rparen scanner.Pos
+}
+
+// OneofType is a oneof list, such as oneof { int; string }.
+type OneofType struct {
+	Oneof  scanner.Pos
+	Lbrace scanner.Pos
+	Types  []Expr
+	Rbrace scanner.Pos
+}
+
+// TypeLit is a braced type definition. Type is only valid if the
+// definition is an anonymous type that started with the type keyword.
+// Each entry is a *Let for a field, a *MethodSpec for a method, or an
+// Expr for an embedded type.
+type TypeLit struct {
+	Type       scanner.Pos
+	TypeParams *TypeParamList
+	Lbrace     scanner.Pos
+	Entries    []Node
+	Rbrace     scanner.Pos
+}
+
+// MethodSpec is a method required by a type definition. MutRecv is
+// true if the method was declared as requiring a mutable receiver.
+type MethodSpec struct {
+	Func    scanner.Pos
+	MutRecv bool
+	Name    *Ident
+	Type    *FuncType
+}
+
+func (x *TypeParamList) Pos() scanner.Pos { return x.Lbrack }
+func (x *TypeParam) Pos() scanner.Pos     { return x.Name.Pos() }
+func (x *FieldList) Pos() scanner.Pos     { return x.Lparen }
+func (x *TupleType) Pos() scanner.Pos     { return x.Lparen }
+func (x *OneofType) Pos() scanner.Pos     { return x.Oneof }
+func (x *MethodSpec) Pos() scanner.Pos    { return x.Func }
+
+func (x *Field) Pos() scanner.Pos {
+	if len(x.Names) > 0 {
+		return x.Names[0].Pos()
+	}
+	return x.Type.Pos()
+}
+
+func (x *FuncType) Pos() scanner.Pos {
+	if x.Arrow.IsValid() || (x.Params == nil) {
+		return x.Arrow
+	}
+	return x.Params.Pos()
+}
+
+func (x *TypeLit) Pos() scanner.Pos {
+	if x.Type.IsValid() {
+		return x.Type
+	}
+	return x.Lbrace
+}
+
+func (*FuncType) exprNode()  {}
+func (*TupleType) exprNode() {}
+func (*OneofType) exprNode() {}
+func (*TypeLit) exprNode()   {}
@@ -0,0 +1,196 @@
+package ast
+
+import "fmt"
+
+// Inspect traverses the syntax tree rooted at node in depth-first
+// order, calling f for each node. If f returns false, the children of
+// that node are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *File:
+		for _, d := range n.Decls {
+			Inspect(d, f)
+		}
+
+	case *Comment, *Ident, *BasicLit, *Branch:
+
+	case *Import:
+		Inspect(n.Path, f)
+		Inspect(n.Name, f)
+
+	case *Let:
+		for _, id := range n.Names {
+			Inspect(id, f)
+		}
+		Inspect(n.Type, f)
+		Inspect(n.Value, f)
+
+	case *Func:
+		Inspect(n.TypeParams, f)
+		Inspect(n.Recv, f)
+		Inspect(n.Name, f)
+		Inspect(n.Type, f)
+		Inspect(n.Body, f)
+
+	case *TypeDecl:
+		Inspect(n.TypeParams, f)
+		Inspect(n.Name, f)
+		Inspect(n.Def, f)
+
+	case *TupleLit:
+		inspectList(n.Elems, f)
+
+	case *ArrayLit:
+		inspectList(n.Elems, f)
+
+	case *StructLit:
+		Inspect(n.Type, f)
+		for _, field := range n.Fields {
+			Inspect(field, f)
+		}
+
+	case *FieldInit:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+
+	case *FuncLit:
+		Inspect(n.Type, f)
+		Inspect(n.Body, f)
+
+	case *Selector:
+		Inspect(n.X, f)
+		Inspect(n.Sel, f)
+
+	case *Index:
+		Inspect(n.X, f)
+		inspectList(n.Indices, f)
+
+	case *Call:
+		Inspect(n.Fun, f)
+		inspectList(n.Args, f)
+
+	case *Unary:
+		Inspect(n.X, f)
+
+	case *Binary:
+		Inspect(n.X, f)
+		Inspect(n.Y, f)
+
+	case *Paren:
+		Inspect(n.X, f)
+
+	case *TypeAssert:
+		Inspect(n.X, f)
+		Inspect(n.Type, f)
+
+	case *Try:
+		Inspect(n.X, f)
+
+	case *If:
+		Inspect(n.Cond, f)
+		Inspect(n.Body, f)
+		Inspect(n.Else, f)
+
+	case *Switch:
+		Inspect(n.Tag, f)
+		for _, c := range n.Cases {
+			Inspect(c, f)
+		}
+
+	case *Case:
+		Inspect(n.Type, f)
+		Inspect(n.Value, f)
+		Inspect(n.Body, f)
+
+	case *TypeParamList:
+		for _, param := range n.List {
+			Inspect(param, f)
+		}
+
+	case *TypeParam:
+		Inspect(n.Name, f)
+		Inspect(n.Constraint, f)
+
+	case *FieldList:
+		for _, field := range n.List {
+			Inspect(field, f)
+		}
+
+	case *Field:
+		for _, id := range n.Names {
+			Inspect(id, f)
+		}
+		Inspect(n.Type, f)
+
+	case *FuncType:
+		Inspect(n.Params, f)
+		Inspect(n.Result, f)
+
+	case *TupleType:
+		inspectList(n.Elems, f)
+
+	case *OneofType:
+		inspectList(n.Types, f)
+
+	case *TypeLit:
+		Inspect(n.TypeParams, f)
+		for _, entry := range n.Entries {
+			Inspect(entry, f)
+		}
+
+	case *MethodSpec:
+		Inspect(n.Name, f)
+		Inspect(n.Type, f)
+
+	case *Block:
+		for _, stmt := range n.Stmts {
+			Inspect(stmt, f)
+		}
+
+	case *ExprStmt:
+		Inspect(n.X, f)
+
+	case *Assign:
+		inspectList(n.Lhs, f)
+		Inspect(n.Rhs, f)
+
+	case *Return:
+		Inspect(n.Result, f)
+
+	case *For:
+		Inspect(n.Cond, f)
+		Inspect(n.Body, f)
+
+	default:
+		panic(fmt.Errorf("unexpected node type: %T", node))
+	}
+}
+
+func inspectList[T Node](list []T, f func(Node) bool) {
+	for _, n := range list {
+		Inspect(n, f)
+	}
+}
+
+// isNil returns true if node is nil or is a nil pointer to one of the
+// node types that are used for optional parts of other nodes.
+func isNil(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case *Ident:
+		return n == nil
+	case *TypeParamList:
+		return n == nil
+	case *Field:
+		return n == nil
+	case *FieldList:
+		return n == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
+)
+
+// Ext is the file extension of Stele source files.
+const Ext = ".stele"
+
+// FileSet is a set of parsed source files, keyed by name. A FileSet
+// makes it possible to find the syntax tree of a file that has
+// already been parsed instead of parsing it again.
+type FileSet struct {
+	files map[string]*ast.File
+	names []string
+}
+
+// NewFileSet returns a new, empty FileSet.
+func NewFileSet() *FileSet {
+	return &FileSet{files: make(map[string]*ast.File)}
+}
+
+// File returns the previously parsed file with the given name, or nil
+// if there is no such file in the set.
+func (fset *FileSet) File(name string) *ast.File {
+	return fset.files[name]
+}
+
+// Files returns every file in the set in the order in which they were
+// added.
+func (fset *FileSet) Files() []*ast.File {
+	files := make([]*ast.File, 0, len(fset.names))
+	for _, name := range fset.names {
+		files = append(files, fset.files[name])
+	}
+	return files
+}
+
+func (fset *FileSet) add(file *ast.File) {
+	if _, ok := fset.files[file.Name]; !ok {
+		fset.names = append(fset.names, file.Name)
+	}
+	fset.files[file.Name] = file
+}
+
+// ParseFile parses a single source file and adds it to fset. The
+// positions in the returned syntax tree refer to the file by name.
+//
+// If src is non-nil, the source is read from it. It must be a string,
+// a []byte, or an io.Reader. If src is nil, the source is read from
+// the file at the path name.
+func ParseFile(fset *FileSet, name string, src any) (*ast.File, error) {
+	r, err := openSource(name, src)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	p := parser{name: name, s: scanner.NewMode(r, scanner.ScanComments)}
+	file, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	file.Name = name
+
+	if fset != nil {
+		fset.add(file)
+	}
+	return file, nil
+}
+
+func openSource(name string, src any) (io.Reader, error) {
+	switch src := src.(type) {
+	case nil:
+		return os.Open(name)
+	case string:
+		return strings.NewReader(src), nil
+	case []byte:
+		return bytes.NewReader(src), nil
+	case io.Reader:
+		return src, nil
+	default:
+		return nil, fmt.Errorf("invalid source type: %T", src)
+	}
+}
+
+// ParseDir parses every Stele source file directly inside of the
+// directory dir as a single package. The files are named by joining
+// their names to dir.
+func ParseDir(fset *FileSet, dir string) (*ast.Package, error) {
+	return parseFS(fset, os.DirFS(dir), ".", func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	})
+}
+
+// ParseFS parses every Stele source file directly inside of the
+// directory p in fsys as a single package. The files are named by
+// their paths in fsys. If p names a single source file instead of a
+// directory, the package consists of only that file.
+func ParseFS(fset *FileSet, fsys fs.FS, p string) (*ast.Package, error) {
+	return parseFS(fset, fsys, p, func(name string) string { return name })
+}
+
+func parseFS(fset *FileSet, fsys fs.FS, dir string, name func(string) string) (*ast.Package, error) {
+	paths, err := sourceFiles(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := ast.Package{Path: dir}
+	var errs []error
+	for _, p := range paths {
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		file, err := ParseFile(fset, name(p), src)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pkg.Files = append(pkg.Files, file)
+	}
+
+	return &pkg, errors.Join(errs...)
+}
+
+// sourceFiles returns the paths of the Stele source files making up
+// the package at p in fsys, sorted by name.
+func sourceFiles(fsys fs.FS, p string) ([]string, error) {
+	info, err := fs.Stat(fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{p}, nil
+	}
+
+	entries, err := fs.ReadDir(fsys, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() || (path.Ext(entry.Name()) != Ext) {
+			continue
+		}
+		paths = append(paths, path.Join(p, entry.Name()))
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no %v files in %v", Ext, p)
+	}
+
+	slices.Sort(paths)
+	return paths, nil
+}
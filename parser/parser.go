@@ -4,128 +4,919 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"path/filepath"
+	"slices"
+	"strings"
 
-	"deedles.dev/stele"
 	"deedles.dev/stele/parser/ast"
 	"deedles.dev/stele/scanner"
 )
 
-func Parse(r io.Reader) (script stele.Script, err error) {
-	p := parser{s: scanner.New(r)}
+// ErrIncomplete is matched by errors that are caused by the input
+// ending inside of an unterminated construct, such as an unclosed
+// brace, an operator with no right-hand operand, or an unterminated
+// string. Input that caused such an error may become valid if more is
+// appended to it.
+var ErrIncomplete = errors.New("incomplete input")
+
+// Parse parses a single source file from r and returns its syntax
+// tree. The file is unnamed. To parse a named file, use ParseFile.
+func Parse(r io.Reader) (*ast.File, error) {
+	p := parser{s: scanner.NewMode(r, scanner.ScanComments)}
+	return p.parse()
+}
+
+// ParseExpr parses a single expression. The entire source must be
+// consumed by the expression, though it may be followed by
+// semicolons.
+func ParseExpr(src string) (ast.Expr, error) {
+	return parseOne(src, (*parser).parseExpr)
+}
+
+// ParseStmt parses a single statement. The entire source must be
+// consumed by the statement, though it may be followed by
+// semicolons.
+func ParseStmt(src string) (ast.Stmt, error) {
+	return parseOne(src, (*parser).parseStmt)
+}
+
+func parseOne[T any](src string, parse func(*parser) T) (node T, err error) {
+	p := parser{s: scanner.New(strings.NewReader(src))}
 	defer p.catch(&err)
-	return p.parseScript(), nil
+
+	p.skipSemis()
+	node = parse(&p)
+	p.skipSemis()
+	if tok := p.peek(); tok.Type != scanner.INVALID {
+		p.throw(p.unexpected(tok))
+	}
+	return node, nil
+}
+
+func (p *parser) parse() (file *ast.File, err error) {
+	defer p.catch(&err)
+	return p.parseFile(), nil
 }
 
 type parser struct {
-	s   *scanner.Scanner
-	buf scanner.Token
+	name     string
+	s        *scanner.Scanner
+	buf      []scanner.Token
+	comments []*ast.Comment
+}
+
+// scan returns the next token from the scanner, skipping and recording
+// comments. At the end of the input, it returns a token of type
+// INVALID.
+func (p *parser) scan() scanner.Token {
+	for {
+		ok := p.s.Scan()
+		if err := p.s.Err(); err != nil {
+			if p.name != "" {
+				err = fmt.Errorf("%v: scan for next token: %w", p.name, err)
+			} else {
+				err = fmt.Errorf("scan for next token: %w", err)
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = incompleteError{err}
+			}
+			p.throw(err)
+		}
+		if !ok {
+			return scanner.Token{}
+		}
+
+		tok := p.s.Tok()
+		if tok.Type == scanner.COMMENT {
+			p.comments = append(p.comments, &ast.Comment{Hash: p.pos(tok), Text: tok.Val.(string)})
+			continue
+		}
+		return tok
+	}
+}
+
+// pos returns the position of tok in the file being parsed.
+func (p *parser) pos(tok scanner.Token) scanner.Pos {
+	pos := tok.Pos()
+	pos.File = p.name
+	return pos
+}
+
+// fill makes sure that at least n tokens are buffered. A semicolon
+// inserted at the end of a line is dropped if the next line starts
+// with a . or a |>.
+func (p *parser) fill(n int) {
+	for len(p.buf) < n {
+		tok := p.scan()
+		if (tok.Type == scanner.DOT) || (tok.Type == scanner.PIPE) {
+			if i := len(p.buf) - 1; (i >= 0) && isAutoSemi(p.buf[i]) {
+				p.buf = p.buf[:i]
+			}
+		}
+		p.buf = append(p.buf, tok)
+
+		if isAutoSemi(tok) {
+			// Make sure that the decision about whether or not to keep
+			// the semicolon is made before it is returned.
+			n = max(n, len(p.buf)+1)
+		}
+		if tok.Type == scanner.INVALID {
+			return
+		}
+	}
+}
+
+func isAutoSemi(tok scanner.Token) bool {
+	return (tok.Type == scanner.SEMI) && (tok.Val == "\n")
+}
+
+// atEnd returns true if tok, which has either just been consumed or is
+// still buffered, is the semicolon inserted at the end of the input.
+// Finding it where something else is required means that the input is
+// incomplete rather than invalid.
+func (p *parser) atEnd(tok scanner.Token) bool {
+	if !isAutoSemi(tok) {
+		return false
+	}
+	for i, b := range p.buf {
+		if b.Pos() == tok.Pos() {
+			return p.peekN(i+1).Type == scanner.INVALID
+		}
+	}
+	return p.peek().Type == scanner.INVALID
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() scanner.Token {
+	return p.peekN(0)
+}
+
+// peekN returns the token n tokens past the next one without consuming
+// anything.
+func (p *parser) peekN(n int) scanner.Token {
+	p.fill(n + 1)
+	if n >= len(p.buf) {
+		return scanner.Token{}
+	}
+	return p.buf[n]
 }
 
 func (p *parser) next() (scanner.Token, bool) {
-	if p.buf.Type != scanner.INVALID {
-		tok := p.buf
-		p.buf = scanner.Token{}
-		return tok, true
+	tok := p.peek()
+	if tok.Type == scanner.INVALID {
+		return tok, false
 	}
+	p.buf = p.buf[1:]
+	return tok, true
+}
 
-	ok := p.s.Scan()
-	if err := p.s.Err(); err != nil {
-		p.throw(fmt.Errorf("scan for next token: %w", err))
+// accept consumes the next token and returns true if it is of type t.
+// Otherwise, it consumes nothing and returns false.
+func (p *parser) accept(t scanner.Type) (scanner.Token, bool) {
+	tok := p.peek()
+	if tok.Type != t {
+		return tok, false
 	}
-	return p.s.Tok(), ok
+	p.next()
+	return tok, true
 }
 
 func (p *parser) expect(t scanner.Type) scanner.Token {
 	tok, ok := p.next()
 	if !ok {
-		p.throw(fmt.Errorf("expected %v but found end of input", t))
+		p.throw(incompleteError{fmt.Errorf("expected %v but found end of input", t)})
 	}
 	if (t >= 0) && (tok.Type != t) {
-		p.throw(fmt.Errorf("(%v:%v) expected %v but found %v", tok.Line, tok.Col, t, tok.Type))
+		if p.atEnd(tok) {
+			p.throw(incompleteError{fmt.Errorf("expected %v but found end of input", t)})
+		}
+		p.throw(fmt.Errorf("(%v) expected %v but found %v", p.pos(tok), t, tok.Type))
 	}
 
 	return tok
 }
 
-func (p *parser) parseScript() stele.Script {
-	var script stele.Script
-	var decls []stele.Declaration
-	allowImport := true
+// expectSemi expects the end of a declaration or statement. A
+// semicolon may be omitted directly before a closing brace or
+// parenthesis.
+func (p *parser) expectSemi() {
+	switch p.peek().Type {
+	case scanner.RBRACE, scanner.RPAREN:
+		return
+	}
+	p.expect(scanner.SEMI)
+}
 
+// skipSemis skips any number of semicolons.
+func (p *parser) skipSemis() {
 	for {
-		tok, ok := p.next()
-		if !ok {
-			script.Scope = script.Scope.AddAll(decls)
-			return script
+		if _, ok := p.accept(scanner.SEMI); !ok {
+			return
 		}
+	}
+}
+
+func (p *parser) parseFile() *ast.File {
+	var file ast.File
+	allowImport := true
 
+	for {
+		p.skipSemis()
+
+		tok := p.peek()
 		switch tok.Type {
+		case scanner.INVALID:
+			file.Comments = p.comments
+			return &file
+
 		case scanner.IMPORT:
 			if !allowImport {
-				p.throw(errors.New("imports must come before all other top-level declarations"))
+				p.throw(fmt.Errorf("(%v) imports must come before all other top-level declarations", p.pos(tok)))
 			}
-			decls = append(decls, p.parseImport())
+			imp := p.parseImport()
+			file.Imports = append(file.Imports, imp)
+			file.Decls = append(file.Decls, imp)
+
 		case scanner.LET:
 			allowImport = false
-			decls = append(decls, p.parseLet())
+			file.Decls = append(file.Decls, p.parseLet())
+
+		case scanner.FUNC:
+			allowImport = false
+			file.Decls = append(file.Decls, p.parseFunc())
+
+		case scanner.TYPE:
+			allowImport = false
+			file.Decls = append(file.Decls, p.parseTypeDecl())
+
 		default:
-			p.throw(UnexpectedTokenError{tok})
+			p.throw(p.unexpected(tok))
 		}
+
+		p.expectSemi()
 	}
 }
 
-func (p *parser) parseImport() ast.Import {
-	path := p.expect(scanner.STRING).Val.(string)
+func (p *parser) parseImport() *ast.Import {
+	imp := ast.Import{Import: p.pos(p.expect(scanner.IMPORT))}
 
-	tok := p.expect(-1)
-	switch tok.Type {
-	case scanner.AS:
-		id := p.expect(scanner.IDENT).Val.(string)
-		p.expect(scanner.SEMI)
-		return ast.Import{Name: id, Path: path}
+	path := p.expect(scanner.STRING)
+	imp.Path = &ast.BasicLit{ValuePos: p.pos(path), Kind: path.Type, Value: path.Val}
+
+	if _, ok := p.accept(scanner.AS); ok {
+		imp.Name = p.parseIdent()
+	}
+
+	return &imp
+}
+
+func (p *parser) parseIdent() *ast.Ident {
+	tok := p.expect(scanner.IDENT)
+	return &ast.Ident{NamePos: p.pos(tok), Name: tok.Val.(string)}
+}
+
+func (p *parser) parseIdentList() []*ast.Ident {
+	list := []*ast.Ident{p.parseIdent()}
+	for {
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			return list
+		}
+		list = append(list, p.parseIdent())
+	}
+}
 
-	case scanner.SEMI:
-		// TODO: Is the basename good enough?
-		return ast.Import{Name: filepath.Base(path), Path: path}
+func (p *parser) parseLet() *ast.Let {
+	let := ast.Let{Let: p.pos(p.expect(scanner.LET))}
+	let.Names = p.parseIdentList()
 
+	switch p.peek().Type {
+	case scanner.ASSIGN, scanner.SEMI, scanner.RBRACE, scanner.INVALID:
 	default:
-		p.throw(UnexpectedTokenError{tok})
-		return ast.Import{}
+		let.Type = p.parseType()
+	}
+
+	if _, ok := p.accept(scanner.ASSIGN); ok {
+		let.Value = p.parseExpr()
+	}
+
+	return &let
+}
+
+func (p *parser) parseFunc() *ast.Func {
+	f := ast.Func{Func: p.pos(p.expect(scanner.FUNC))}
+
+	if p.peek().Type == scanner.LBRACKET {
+		f.TypeParams = p.parseTypeParams()
+	}
+
+	if p.peek().Type == scanner.LPAREN {
+		p.expect(scanner.LPAREN)
+		f.Recv = &ast.Field{Names: []*ast.Ident{p.parseIdent()}}
+		if p.peek().Type != scanner.RPAREN {
+			f.Recv.Type = p.parseType()
+		}
+		p.expect(scanner.RPAREN)
+	}
+
+	f.Name = p.parseIdent()
+	f.Type = p.parseSignature(scanner.Pos{}, true)
+	f.Body = p.parseBlock()
+
+	return &f
+}
+
+// parseSignature parses the parameters, mutability, and result of a
+// function. If named is true, the parameters are expected to have
+// names. Otherwise, they are just a list of types.
+func (p *parser) parseSignature(arrow scanner.Pos, named bool) *ast.FuncType {
+	sig := ast.FuncType{Arrow: arrow}
+
+	if !arrow.IsValid() || (p.peek().Type == scanner.LPAREN) {
+		if named {
+			sig.Params = p.parseParams(false)
+		} else {
+			sig.Params = p.parseParamTypes()
+		}
+	}
+
+	if _, ok := p.accept(scanner.MUT); ok {
+		sig.Mut = true
+	}
+
+	if startsType(p.peek().Type) {
+		sig.Result = p.parseType()
+	}
+
+	return &sig
+}
+
+// startsType returns true if a token of type t can start a type in a
+// position where a type is optional.
+func startsType(t scanner.Type) bool {
+	return slices.Contains([]scanner.Type{
+		scanner.IDENT,
+		scanner.LPAREN,
+		scanner.ARROW,
+		scanner.ONEOF,
+		scanner.TYPE,
+	}, t)
+}
+
+// parseParams parses a parenthesized list of named parameters. If
+// untyped is true, the parameters are not required to have types.
+func (p *parser) parseParams(untyped bool) *ast.FieldList {
+	list := ast.FieldList{Lparen: p.pos(p.expect(scanner.LPAREN))}
+
+	var names []*ast.Ident
+	for p.peek().Type != scanner.RPAREN {
+		names = append(names, p.parseIdent())
+
+		switch tok := p.peek(); tok.Type {
+		case scanner.COMMA:
+			p.next()
+			continue
+
+		case scanner.RPAREN:
+			if !untyped {
+				p.throw(fmt.Errorf("(%v) missing type for parameter %v", p.pos(tok), names[len(names)-1].Name))
+			}
+			list.List = append(list.List, &ast.Field{Names: names})
+			names = nil
+			continue
+		}
+
+		list.List = append(list.List, &ast.Field{Names: names, Type: p.parseType()})
+		names = nil
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			break
+		}
+	}
+	if len(names) > 0 {
+		list.List = append(list.List, &ast.Field{Names: names})
+	}
+
+	list.Rparen = p.pos(p.expect(scanner.RPAREN))
+	return &list
+}
+
+// parseParamTypes parses a parenthesized list of parameter types with
+// no names.
+func (p *parser) parseParamTypes() *ast.FieldList {
+	list := ast.FieldList{Lparen: p.pos(p.expect(scanner.LPAREN))}
+	for p.peek().Type != scanner.RPAREN {
+		list.List = append(list.List, &ast.Field{Type: p.parseType()})
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			break
+		}
 	}
+	list.Rparen = p.pos(p.expect(scanner.RPAREN))
+	return &list
 }
 
-func (p *parser) parseLet() ast.Let {
-	id := p.expect(scanner.IDENT).Val.(string)
+func (p *parser) parseTypeParams() *ast.TypeParamList {
+	list := ast.TypeParamList{Lbrack: p.pos(p.expect(scanner.LBRACKET))}
+	for {
+		param := ast.TypeParam{Name: p.parseIdent()}
+		switch p.peek().Type {
+		case scanner.COMMA, scanner.RBRACKET:
+		default:
+			param.Constraint = p.parseType()
+		}
+		list.List = append(list.List, &param)
 
-	tok := p.expect(-1)
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			break
+		}
+	}
+	list.Rbrack = p.pos(p.expect(scanner.RBRACKET))
+	return &list
+}
+
+func (p *parser) parseTypeDecl() *ast.TypeDecl {
+	decl := ast.TypeDecl{Type: p.pos(p.expect(scanner.TYPE))}
+	if p.peek().Type == scanner.LBRACKET {
+		decl.TypeParams = p.parseTypeParams()
+	}
+	decl.Name = p.parseIdent()
+	decl.Def = p.parseType()
+	return &decl
+}
+
+func (p *parser) parseType() ast.Expr {
+	tok := p.peek()
 	switch tok.Type {
 	case scanner.IDENT:
-		// TODO: Handle explicit typing.
-		panic("Not implemented.")
-	case scanner.ASSIGN:
-		rhs := p.parseExpr()
-		return ast.Let{
-			Name:   id,
-			T:      rhs.Type(),
-			Assign: &stele.Assign{ID: id, Val: rhs},
+		var t ast.Expr = p.parseIdent()
+		if _, ok := p.accept(scanner.DOT); ok {
+			t = &ast.Selector{X: t, Sel: p.parseIdent()}
+		}
+		if p.peek().Type == scanner.LBRACKET {
+			t = p.parseIndex(t, p.parseType)
+		}
+		return t
+
+	case scanner.LPAREN:
+		lparen := p.pos(p.expect(scanner.LPAREN))
+		elems := []ast.Expr{p.parseType()}
+		for {
+			if _, ok := p.accept(scanner.COMMA); !ok {
+				break
+			}
+			elems = append(elems, p.parseType())
+		}
+		rparen := p.pos(p.expect(scanner.RPAREN))
+
+		if len(elems) == 1 {
+			return &ast.Paren{Lparen: lparen, X: elems[0], Rparen: rparen}
+		}
+		return &ast.TupleType{Lparen: lparen, Elems: elems, Rparen: rparen}
+
+	case scanner.ARROW:
+		arrow := p.pos(p.expect(scanner.ARROW))
+		return p.parseSignature(arrow, false)
+
+	case scanner.ONEOF:
+		return p.parseOneof()
+
+	case scanner.TYPE:
+		lit := p.parseTypeLit(p.pos(p.expect(scanner.TYPE)))
+		return lit
+
+	case scanner.LBRACE:
+		return p.parseTypeLit(scanner.Pos{})
+
+	default:
+		p.throw(p.unexpected(tok))
+		return nil
+	}
+}
+
+func (p *parser) parseOneof() *ast.OneofType {
+	oneof := ast.OneofType{
+		Oneof:  p.pos(p.expect(scanner.ONEOF)),
+		Lbrace: p.pos(p.expect(scanner.LBRACE)),
+	}
+	for {
+		p.skipSemis()
+		if p.peek().Type == scanner.RBRACE {
+			break
+		}
+
+		oneof.Types = append(oneof.Types, p.parseType())
+		p.expectSemi()
+	}
+	oneof.Rbrace = p.pos(p.expect(scanner.RBRACE))
+	return &oneof
+}
+
+// parseTypeLit parses a braced type definition. If typ is valid, it is
+// the position of the type keyword that started an anonymous type.
+func (p *parser) parseTypeLit(typ scanner.Pos) *ast.TypeLit {
+	lit := ast.TypeLit{Type: typ}
+	if typ.IsValid() && (p.peek().Type == scanner.LBRACKET) {
+		lit.TypeParams = p.parseTypeParams()
+	}
+
+	lit.Lbrace = p.pos(p.expect(scanner.LBRACE))
+	for {
+		p.skipSemis()
+
+		switch p.peek().Type {
+		case scanner.RBRACE:
+			lit.Rbrace = p.pos(p.expect(scanner.RBRACE))
+			return &lit
+
+		case scanner.LET:
+			let := p.parseLet()
+			if let.Type == nil {
+				p.throw(fmt.Errorf("(%v) missing type for field", let.Pos()))
+			}
+			if let.Value != nil {
+				p.throw(fmt.Errorf("(%v) fields may not have values", let.Pos()))
+			}
+			lit.Entries = append(lit.Entries, let)
+
+		case scanner.FUNC:
+			lit.Entries = append(lit.Entries, p.parseMethodSpec())
+
+		default:
+			lit.Entries = append(lit.Entries, p.parseType())
+		}
+
+		p.expectSemi()
+	}
+}
+
+func (p *parser) parseMethodSpec() *ast.MethodSpec {
+	spec := ast.MethodSpec{Func: p.pos(p.expect(scanner.FUNC))}
+	if _, ok := p.accept(scanner.MUT); ok {
+		spec.MutRecv = true
+	}
+	spec.Name = p.parseIdent()
+	spec.Type = p.parseSignature(scanner.Pos{}, false)
+	return &spec
+}
+
+func (p *parser) parseBlock() *ast.Block {
+	block := ast.Block{Lbrace: p.pos(p.expect(scanner.LBRACE))}
+	for {
+		p.skipSemis()
+		if tok, ok := p.accept(scanner.RBRACE); ok {
+			block.Rbrace = p.pos(tok)
+			return &block
+		}
+
+		block.Stmts = append(block.Stmts, p.parseStmt())
+		p.expectSemi()
+	}
+}
+
+func (p *parser) parseStmt() ast.Stmt {
+	tok := p.peek()
+	switch tok.Type {
+	case scanner.LET:
+		return p.parseLet()
+
+	case scanner.RETURN:
+		ret := ast.Return{Return: p.pos(p.expect(scanner.RETURN))}
+		switch p.peek().Type {
+		case scanner.SEMI, scanner.RBRACE:
+		default:
+			ret.Result = p.parseExpr()
+		}
+		return &ret
+
+	case scanner.BREAK, scanner.CONTINUE:
+		p.next()
+		return &ast.Branch{TokPos: p.pos(tok), Tok: tok.Type}
+
+	case scanner.FOR:
+		loop := ast.For{For: p.pos(p.expect(scanner.FOR))}
+		if p.peek().Type != scanner.LBRACE {
+			loop.Cond = p.parseExpr()
+		}
+		loop.Body = p.parseBlock()
+		return &loop
+
+	case scanner.LBRACE:
+		return p.parseBlock()
+	}
+
+	x := p.parseExpr()
+	lhs := []ast.Expr{x}
+	for {
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			break
+		}
+		lhs = append(lhs, p.parseExpr())
+	}
+
+	tok = p.peek()
+	if !isAssign(tok.Type) {
+		if len(lhs) > 1 {
+			p.throw(p.unexpected(tok))
 		}
+		return &ast.ExprStmt{X: x}
+	}
+	p.next()
+
+	if (len(lhs) > 1) && (tok.Type != scanner.ASSIGN) {
+		p.throw(fmt.Errorf("(%v) %v may only have a single operand on its left", p.pos(tok), tok.Val))
+	}
+
+	return &ast.Assign{
+		Lhs:    lhs,
+		TokPos: p.pos(tok),
+		Tok:    tok.Type,
+		Rhs:    p.parseExpr(),
+	}
+}
+
+func isAssign(t scanner.Type) bool {
+	return slices.Contains([]scanner.Type{
+		scanner.ASSIGN,
+		scanner.PLUSASSIGN,
+		scanner.MINUSASSIGN,
+		scanner.MULTASSIGN,
+		scanner.DIVASSIGN,
+		scanner.MODASSIGN,
+	}, t)
+}
+
+// precedence returns the precedence of the binary operator t, or 0 if
+// t is not a binary operator.
+func precedence(t scanner.Type) int {
+	switch t {
+	case scanner.MULT, scanner.DIV, scanner.MOD, scanner.LSHIFT, scanner.RSHIFT, scanner.BITAND:
+		return 6
+	case scanner.PLUS, scanner.MINUS, scanner.BITOR, scanner.BITNOT:
+		return 5
+	case scanner.EQUAL, scanner.NOTEQUAL, scanner.LT, scanner.LE, scanner.GT, scanner.GE:
+		return 4
+	case scanner.AND:
+		return 3
+	case scanner.OR:
+		return 2
+	case scanner.PIPE:
+		return 1
 	default:
-		p.throw(UnexpectedTokenError{tok})
-		return ast.Let{}
+		return 0
 	}
 }
 
-func (p *parser) parseExpr() stele.Expr {
-	tok := p.expect(-1)
+func (p *parser) parseExpr() ast.Expr {
+	return p.parseBinary(1)
+}
+
+func (p *parser) parseBinary(prec int) ast.Expr {
+	x := p.parseUnary()
+	for {
+		tok := p.peek()
+		oprec := precedence(tok.Type)
+		if oprec < prec {
+			return x
+		}
+		p.next()
+
+		y := p.parseBinary(oprec + 1)
+		x = &ast.Binary{X: x, OpPos: p.pos(tok), Op: tok.Type, Y: y}
+	}
+}
+
+func (p *parser) parseUnary() ast.Expr {
+	tok := p.peek()
+	switch tok.Type {
+	case scanner.MINUS, scanner.NOT, scanner.BITNOT:
+		p.next()
+		return &ast.Unary{OpPos: p.pos(tok), Op: tok.Type, X: p.parseUnary()}
+
+	case scanner.BITAND:
+		p.next()
+		return p.parseStructLit(p.pos(tok))
+
+	default:
+		return p.parsePrimary(p.parseOperand())
+	}
+}
+
+func (p *parser) parseOperand() ast.Expr {
+	tok := p.peek()
 	switch tok.Type {
-	case scanner.INT:
-		// TODO: Handle binary operators.
-		p.expect(scanner.SEMI)
-		return ast.Int{Val: tok.Val.(int64)}
+	case scanner.IDENT:
+		return p.parseIdent()
+
+	case scanner.INT, scanner.FLOAT, scanner.STRING:
+		p.next()
+		return &ast.BasicLit{ValuePos: p.pos(tok), Kind: tok.Type, Value: tok.Val}
+
+	case scanner.LPAREN:
+		p.next()
+		x := p.parseExpr()
+		if rparen, ok := p.accept(scanner.RPAREN); ok {
+			return &ast.Paren{Lparen: p.pos(tok), X: x, Rparen: p.pos(rparen)}
+		}
+
+		elems := []ast.Expr{x}
+		for {
+			if _, ok := p.accept(scanner.COMMA); !ok {
+				break
+			}
+			if p.peek().Type == scanner.RPAREN {
+				break
+			}
+			elems = append(elems, p.parseExpr())
+		}
+		return &ast.TupleLit{Lparen: p.pos(tok), Elems: elems, Rparen: p.pos(p.expect(scanner.RPAREN))}
+
+	case scanner.LBRACKET:
+		p.next()
+		lit := ast.ArrayLit{Lbrack: p.pos(tok)}
+		lit.Elems = p.parseExprList(scanner.RBRACKET)
+		lit.Rbrack = p.pos(p.expect(scanner.RBRACKET))
+		return &lit
+
+	case scanner.ARROW:
+		p.next()
+		sig := ast.FuncType{Arrow: p.pos(tok)}
+		if p.peek().Type == scanner.LPAREN {
+			sig.Params = p.parseParams(true)
+		}
+		if _, ok := p.accept(scanner.MUT); ok {
+			sig.Mut = true
+		}
+		if startsType(p.peek().Type) {
+			sig.Result = p.parseType()
+		}
+		return &ast.FuncLit{Type: &sig, Body: p.parseBlock()}
+
+	case scanner.IF:
+		return p.parseIf()
+
+	case scanner.SWITCH:
+		return p.parseSwitch()
+
+	case scanner.LBRACE:
+		return p.parseBlock()
+
+	default:
+		p.throw(p.unexpected(tok))
+		return nil
+	}
+}
+
+// parseExprList parses a comma-separated list of expressions ending
+// just before a token of type end. A trailing comma is allowed.
+func (p *parser) parseExprList(end scanner.Type) []ast.Expr {
+	var list []ast.Expr
+	for p.peek().Type != end {
+		list = append(list, p.parseExpr())
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			break
+		}
+	}
+	return list
+}
+
+func (p *parser) parsePrimary(x ast.Expr) ast.Expr {
+	for {
+		switch p.peek().Type {
+		case scanner.DOT:
+			p.next()
+			if lparen, ok := p.accept(scanner.LPAREN); ok {
+				t := p.parseType()
+				x = &ast.TypeAssert{X: x, Lparen: p.pos(lparen), Type: t, Rparen: p.pos(p.expect(scanner.RPAREN))}
+				continue
+			}
+			x = &ast.Selector{X: x, Sel: p.parseIdent()}
+
+		case scanner.LBRACKET:
+			x = p.parseIndex(x, p.parseExpr)
+
+		case scanner.LPAREN:
+			call := ast.Call{Fun: x, Lparen: p.pos(p.expect(scanner.LPAREN))}
+			call.Args = p.parseExprList(scanner.RPAREN)
+			call.Rparen = p.pos(p.expect(scanner.RPAREN))
+			x = &call
+
+		case scanner.QUESTION:
+			x = &ast.Try{X: x, Question: p.pos(p.expect(scanner.QUESTION))}
+
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) parseIndex(x ast.Expr, parse func() ast.Expr) *ast.Index {
+	index := ast.Index{X: x, Lbrack: p.pos(p.expect(scanner.LBRACKET))}
+	for {
+		index.Indices = append(index.Indices, parse())
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			break
+		}
+	}
+	index.Rbrack = p.pos(p.expect(scanner.RBRACKET))
+	return &index
+}
+
+func (p *parser) parseStructLit(amp scanner.Pos) *ast.StructLit {
+	lit := ast.StructLit{Amp: amp}
+	lit.Type = p.parsePrimary(p.parseIdent())
+	lit.Lbrace = p.pos(p.expect(scanner.LBRACE))
+	for {
+		p.skipSemis()
+		if tok, ok := p.accept(scanner.RBRACE); ok {
+			lit.Rbrace = p.pos(tok)
+			return &lit
+		}
+
+		field := ast.FieldInit{Name: p.parseIdent()}
+		p.expect(scanner.ASSIGN)
+		field.Value = p.parseExpr()
+		lit.Fields = append(lit.Fields, &field)
+
+		if _, ok := p.accept(scanner.COMMA); !ok {
+			p.expectSemi()
+		}
+	}
+}
+
+func (p *parser) parseIf() *ast.If {
+	x := ast.If{If: p.pos(p.expect(scanner.IF))}
+	x.Cond = p.parseExpr()
+	x.Body = p.parseBlock()
+
+	if _, ok := p.accept(scanner.ELSE); ok {
+		if p.peek().Type == scanner.IF {
+			x.Else = p.parseIf()
+		} else {
+			x.Else = p.parseBlock()
+		}
+	}
+
+	return &x
+}
+
+func (p *parser) parseSwitch() *ast.Switch {
+	x := ast.Switch{Switch: p.pos(p.expect(scanner.SWITCH))}
+	if p.peek().Type != scanner.LBRACE {
+		x.Tag = p.parseExpr()
+	}
+
+	x.Lbrace = p.pos(p.expect(scanner.LBRACE))
+	for {
+		p.skipSemis()
+		if tok, ok := p.accept(scanner.RBRACE); ok {
+			x.Rbrace = p.pos(tok)
+			return &x
+		}
+
+		x.Cases = append(x.Cases, p.parseCase())
+
+		// The semicolon after a case is dropped if the next case is a
+		// type assertion, as it starts with a dot.
+		if p.peek().Type != scanner.DOT {
+			p.expectSemi()
+		}
+	}
+}
+
+func (p *parser) parseCase() *ast.Case {
+	tok := p.peek()
+	c := ast.Case{CasePos: p.pos(tok)}
+
+	switch {
+	case tok.Type == scanner.ELSE:
+		p.next()
+		c.Else = true
+
+	case (tok.Type == scanner.DOT) && (p.peekN(1).Type == scanner.LPAREN):
+		p.next()
+		p.next()
+		c.Type = p.parseType()
+		p.expect(scanner.RPAREN)
+
+	case isComparison(tok.Type):
+		p.next()
+		c.Op = tok.Type
+		c.Value = p.parseExpr()
+
 	default:
-		panic("Not implemented.")
+		c.Value = p.parseExpr()
 	}
+
+	c.Body = p.parseBlock()
+	return &c
+}
+
+func isComparison(t scanner.Type) bool {
+	return slices.Contains([]scanner.Type{
+		scanner.EQUAL,
+		scanner.NOTEQUAL,
+		scanner.LT,
+		scanner.LE,
+		scanner.GT,
+		scanner.GE,
+	}, t)
 }
 
 func (p *parser) throw(err error) {
@@ -145,10 +936,36 @@ func (p *parser) catch(err *error) {
 
 type parseErr struct{ err error }
 
+// incompleteError marks an error as being caused by the end of the
+// input.
+type incompleteError struct{ error }
+
+func (err incompleteError) Unwrap() error { return err.error }
+
+func (err incompleteError) Is(target error) bool { return target == ErrIncomplete }
+
 type UnexpectedTokenError struct {
 	tok scanner.Token
+	pos scanner.Pos
+}
+
+func (p *parser) unexpected(tok scanner.Token) UnexpectedTokenError {
+	if p.atEnd(tok) {
+		tok = scanner.Token{}
+	}
+	return UnexpectedTokenError{tok: tok, pos: p.pos(tok)}
 }
 
 func (err UnexpectedTokenError) Error() string {
-	return fmt.Sprintf("unexpected token: %v", err.tok.Val)
+	if err.tok.Type == scanner.INVALID {
+		if err.pos.File != "" {
+			return fmt.Sprintf("(%v) unexpected end of input", err.pos.File)
+		}
+		return "unexpected end of input"
+	}
+	return fmt.Sprintf("(%v) unexpected token: %v", err.pos, err.tok.Val)
+}
+
+func (err UnexpectedTokenError) Is(target error) bool {
+	return (target == ErrIncomplete) && (err.tok.Type == scanner.INVALID)
 }
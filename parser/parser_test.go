@@ -1,8 +1,13 @@
 package parser
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"testing/fstest"
+
+	"deedles.dev/stele/parser/ast"
+	"deedles.dev/stele/scanner"
 )
 
 func TestParse(t *testing.T) {
@@ -11,9 +16,268 @@ import "something/else" as something;
 
 let v = 3;`
 
-	script, err := Parse(strings.NewReader(src))
+	file, err := Parse(strings.NewReader(src))
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("%+v", script)
+	t.Logf("%+v", file)
+
+	if len(file.Imports) != 2 {
+		t.Fatalf("expected 2 imports but got %v", len(file.Imports))
+	}
+	if len(file.Decls) != 3 {
+		t.Fatalf("expected 3 declarations but got %v", len(file.Decls))
+	}
+	if name := file.Imports[1].Name; (name == nil) || (name.Name != "something") {
+		t.Fatalf("unexpected import name: %+v", name)
+	}
+}
+
+func TestParseDecls(t *testing.T) {
+	const src = `# A comment.
+import "io"
+
+type [T, E any] list {
+	let prev, next opt[list[T, E]]
+	let val E
+	func mut push(E)
+}
+
+type pair (string, int)
+
+type number oneof {
+	int
+	float
+}
+
+func [T any] (v T) example(a, b! int, f -> (int) mut) T {
+	let x = a + b * 2
+	x = x |> double()
+	if x > 3 {
+		return v
+	} else {
+		v
+	}
+}
+
+func fib(n int) int {
+	switch n {
+		<= 1 { n }
+		else { fib(n - 1) + fib(n - 2) }
+	}
+}
+
+func describe(v any) string {
+	switch v {
+		.(int) { "int" }
+		.(string) { "string" }
+		else { "something else" }
+	}
+}
+
+func main() mut {
+	let p = &pair("one", 1) { name = "example" }
+	let add = -> (a, b) int { a + b }
+	for {
+		break
+	}
+	io.stdout()
+		.writeln("done")
+}
+`
+
+	file, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var idents int
+	ast.Inspect(file, func(n ast.Node) bool {
+		if _, ok := n.(*ast.Ident); ok {
+			idents++
+		}
+		return true
+	})
+	if idents == 0 {
+		t.Fatal("no identifiers found by Inspect")
+	}
+
+	if len(file.Comments) != 1 {
+		t.Fatalf("expected 1 comment but got %v", len(file.Comments))
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.TypeDecl:
+			names = append(names, decl.Name.Name)
+		case *ast.Func:
+			names = append(names, decl.Name.Name)
+		}
+	}
+	expected := []string{"list", "pair", "number", "example", "fib", "describe", "main"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Fatalf("declarations out of order\n\tgot: %v\n\texpected: %v", names, expected)
+	}
+
+	example := file.Decls[4].(*ast.Func)
+	if example.Recv == nil {
+		t.Fatal("receiver not parsed")
+	}
+	if n := example.Type.Params.NumFields(); n != 3 {
+		t.Fatalf("expected 3 parameters but got %v", n)
+	}
+
+	let := example.Body.Stmts[0].(*ast.Let)
+	bin := let.Value.(*ast.Binary)
+	if (bin.Op != scanner.PLUS) || (bin.Y.(*ast.Binary).Op != scanner.MULT) {
+		t.Fatalf("incorrect precedence: %+v", bin)
+	}
+
+	main := file.Decls[7].(*ast.Func)
+	last := main.Body.Stmts[len(main.Body.Stmts)-1].(*ast.ExprStmt)
+	if sel := last.X.(*ast.Call).Fun.(*ast.Selector); sel.Sel.Name != "writeln" {
+		t.Fatalf("method chain across lines not parsed: %+v", sel)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{name: "LateImport", src: "let v = 3\nimport \"io\""},
+		{name: "UnclosedBlock", src: "func main() {"},
+		{name: "BadTopLevel", src: "3"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(strings.NewReader(test.src))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pkg/b.stele":     {Data: []byte("let b = 2")},
+		"pkg/a.stele":     {Data: []byte("import \"x\"\nlet a = 1")},
+		"pkg/other.txt":   {Data: []byte("ignored")},
+		"pkg/sub/c.stele": {Data: []byte("let c = 3")},
+	}
+
+	fset := NewFileSet()
+	pkg, err := ParseFS(fset, fsys, "pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkg.Files) != 2 {
+		t.Fatalf("expected 2 files but got %v", len(pkg.Files))
+	}
+	if name := pkg.Files[0].Name; name != "pkg/a.stele" {
+		t.Fatalf("unexpected first file: %q", name)
+	}
+	if pos := pkg.Files[0].Decls[1].Pos(); pos.String() != "pkg/a.stele:2:1" {
+		t.Fatalf("unexpected position: %v", pos)
+	}
+	if fset.File("pkg/b.stele") != pkg.Files[1] {
+		t.Fatal("file missing from file set")
+	}
+}
+
+func TestParseFileError(t *testing.T) {
+	_, err := ParseFile(nil, "bad.stele", "let x = ")
+	if (err == nil) || !strings.HasPrefix(err.Error(), "(bad.stele") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	x, err := ParseExpr("a + b * 2 |> f()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe, ok := x.(*ast.Binary)
+	if !ok || (pipe.Op != scanner.PIPE) {
+		t.Fatalf("unexpected expression: %#v", x)
+	}
+
+	x, err = ParseExpr("-f()?.g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := x.(*ast.Unary).X.(*ast.Selector).X.(*ast.Try); !ok {
+		t.Fatalf("unexpected expression: %#v", x)
+	}
+
+	_, err = ParseExpr("a b")
+	if (err == nil) || errors.Is(err, ErrIncomplete) {
+		t.Fatalf("expected a complete error but got %v", err)
+	}
+}
+
+func TestParseStmt(t *testing.T) {
+	stmt, err := ParseStmt("let x = 3;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stmt.(*ast.Let); !ok {
+		t.Fatalf("unexpected statement: %#v", stmt)
+	}
+
+	stmt, err = ParseStmt("x, y = (y, x)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stmt.(*ast.Assign); !ok {
+		t.Fatalf("unexpected statement: %#v", stmt)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		src        string
+		incomplete bool
+	}{
+		{src: "if x {", incomplete: true},
+		{src: "let f = -> () {\n\tlet x = 3\n", incomplete: true},
+		{src: "x |>", incomplete: true},
+		{src: "x |>\n", incomplete: true},
+		{src: "f(a,\n", incomplete: true},
+		{src: "f(1, 2", incomplete: true},
+		{src: "f(a, b", incomplete: true},
+		{src: "(a", incomplete: true},
+		{src: "x[1", incomplete: true},
+		{src: "[1", incomplete: true},
+		{src: "let x = (a\n", incomplete: true},
+		{src: "let s = \"abc", incomplete: true},
+		{src: "let c = '", incomplete: true},
+		{src: "let x =", incomplete: true},
+		{src: "switch x {\n\t< 3 { 1 }\n", incomplete: true},
+		{src: "if x { 1 } }"},
+		{src: "let = 3"},
+		{src: "x )"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.src, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseStmt(test.src)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if errors.Is(err, ErrIncomplete) != test.incomplete {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
 }
@@ -0,0 +1,366 @@
+package stele
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Satisfies returns true if a value of type t may be used where a
+// value of type other is required.
+func (t Type) Satisfies(other Type) bool {
+	s := satisfier{seen: make(map[[2]string]struct{})}
+	return s.satisfies(t, other)
+}
+
+// Explain returns a list of the reasons that t does not satisfy
+// other. If t satisfies other, it returns nil.
+func (t Type) Explain(other Type) []string {
+	s := satisfier{seen: make(map[[2]string]struct{}), explain: true}
+	s.satisfies(t, other)
+	return s.reasons
+}
+
+// satisfier compares types structurally. If explain is true, it
+// records the reasons for any mismatch instead of stopping at the
+// first one.
+type satisfier struct {
+	seen    map[[2]string]struct{}
+	explain bool
+	reasons []string
+}
+
+func (s *satisfier) fail(format string, args ...any) {
+	if s.explain {
+		s.reasons = append(s.reasons, fmt.Sprintf(format, args...))
+	}
+}
+
+// nested compares t and other, prefixing any reasons recorded by the
+// comparison with prefix.
+func (s *satisfier) nested(t, other Type, prefix string) bool {
+	reasons := s.reasons
+	s.reasons = nil
+	ok := s.satisfies(t, other)
+	for _, r := range s.reasons {
+		reasons = append(reasons, prefix+r)
+	}
+	s.reasons = reasons
+	return ok
+}
+
+// quiet compares t and other without recording anything.
+func (s *satisfier) quiet(t, other Type) bool {
+	explain := s.explain
+	s.explain = false
+	ok := s.satisfies(t, other)
+	s.explain = explain
+	return ok
+}
+
+func (s *satisfier) satisfies(t, other Type) bool {
+	// Named types may refer to themselves through their features.
+	// Comparing a pair of them that is already being compared assumes
+	// that they match, which holds unless some other feature does not.
+	if (t.Name != "") && (other.Name != "") {
+		key := [2]string{t.Name, other.Name}
+		if _, ok := s.seen[key]; ok {
+			return true
+		}
+		s.seen[key] = struct{}{}
+		defer delete(s.seen, key)
+	}
+
+	// A self parameter on either side stands for the type of the value
+	// being checked, which is t.
+	tf, to := t.flatten(t)
+	of, oo := other.flatten(t)
+
+	if len(to) > 0 {
+		// Every member of a oneof must be usable as other, as there is
+		// no way of knowing statically which one a value is.
+		ok := true
+		for _, m := range to {
+			ok = s.nested(m, other, fmt.Sprintf("member %v: ", m)) && ok
+			if !ok && !s.explain {
+				return false
+			}
+		}
+		return ok
+	}
+
+	if len(oo) > 0 {
+		return s.oneof(t, oo)
+	}
+
+	features := make(map[featureKey]Feature, len(tf))
+	for _, f := range tf {
+		features[f.key()] = f
+	}
+
+	// The methods that a type with the wrong underlying type is missing
+	// follow from that, so it is not explained any further.
+	for _, want := range of {
+		if (want.Type == MemLayoutFeature) && !s.feature(features, want) {
+			return false
+		}
+	}
+
+	ok := true
+	for _, want := range of {
+		if want.Type == MemLayoutFeature {
+			continue
+		}
+		ok = s.feature(features, want) && ok
+		if !ok && !s.explain {
+			return false
+		}
+	}
+	return ok
+}
+
+// oneof checks that t satisfies exactly one of the members of a oneof
+// list.
+func (s *satisfier) oneof(t Type, members []Type) bool {
+	var matched []Type
+	for _, m := range members {
+		if s.quiet(t, m) {
+			matched = append(matched, m)
+		}
+	}
+
+	switch len(matched) {
+	case 1:
+		return true
+	case 0:
+		s.fail("does not satisfy any of %v", typeList(members))
+	default:
+		s.fail("satisfies more than one of %v", typeList(matched))
+	}
+	return false
+}
+
+func (s *satisfier) feature(features map[featureKey]Feature, want Feature) bool {
+	have, ok := features[want.key()]
+	if !ok {
+		s.fail("missing %v", want.describe())
+		return false
+	}
+
+	switch want.Type {
+	case LetFeature:
+		// Fields may be assigned through as well as read from, so their
+		// types must match in both directions.
+		if !s.quiet(have.Return, want.Return) || !s.quiet(want.Return, have.Return) {
+			s.fail("field %v has type %v, but %v is required", want.Name, have.Return, want.Return)
+			return false
+		}
+		return true
+
+	case FuncFeature:
+		ok := s.signature(have, want, fmt.Sprintf("method %v", want.Name))
+		if have.MutRecv && !want.MutRecv {
+			s.fail("method %v requires a mutable receiver", want.Name)
+			ok = false
+		}
+		if have.Mut && !want.Mut {
+			s.fail("method %v is mutable", want.Name)
+			ok = false
+		}
+		return ok
+
+	case MemLayoutFeature:
+		if (want.Name == "func") && (have.Name == "func") {
+			return s.funcLayout(have, want)
+		}
+
+		if !s.same(have.Args, want.Args) || !s.same([]Type{have.Return}, []Type{want.Return}) {
+			s.fail("underlying %v does not match %v", have.describeLayout(), want.describeLayout())
+			return false
+		}
+		return true
+
+	default:
+		s.fail("unknown feature %v", want.describe())
+		return false
+	}
+}
+
+// signature compares the arguments and return types of a pair of
+// functions. Arguments are compared in the opposite direction from
+// the return type, as the function is passed arguments but provides
+// its return value.
+func (s *satisfier) signature(have, want Feature, name string) bool {
+	if len(have.Args) != len(want.Args) {
+		s.fail("%v has %v arguments, but %v are required", name, len(have.Args), len(want.Args))
+		return false
+	}
+
+	ok := true
+	for i := range want.Args {
+		if !s.quiet(want.Args[i], have.Args[i]) {
+			s.fail("%v argument %v has type %v, which %v does not satisfy", name, i+1, have.Args[i], want.Args[i])
+			ok = false
+		}
+	}
+	return s.nested(have.Return, want.Return, name+" return: ") && ok
+}
+
+// funcLayout compares a pair of function types. A pure function may
+// be used where a mutable one is expected, but not the other way
+// around, as a pure caller could then cause side effects.
+func (s *satisfier) funcLayout(have, want Feature) bool {
+	if len(have.TypeParams) != len(want.TypeParams) {
+		s.fail("function has %v type parameters, but %v are required", len(have.TypeParams), len(want.TypeParams))
+		return false
+	}
+
+	ok := s.signature(have, want, "function")
+	if have.Mut && !want.Mut {
+		s.fail("function is mutable")
+		ok = false
+	}
+	return ok
+}
+
+// same returns true if the types in a and b satisfy each other
+// pairwise.
+func (s *satisfier) same(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !s.quiet(a[i], b[i]) || !s.quiet(b[i], a[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// flatten returns the features and oneof list of t with all embedded
+// types and instantiations expanded. If self is valid, t's self
+// parameter, and those of any types that it embeds, is bound to it.
+func (t Type) flatten(self Type) (features []Feature, oneof []Type) {
+	if self.Valid() || (t.Origin != nil) {
+		t = t.resolve(self)
+	}
+
+	oneof = t.Oneof
+	for _, f := range t.Features {
+		if f.Type != EmbedFeature {
+			features = append(features, f)
+			continue
+		}
+
+		ef, eo := f.Return.flatten(self)
+		features = append(features, ef...)
+		oneof = append(oneof[:len(oneof):len(oneof)], eo...)
+	}
+	return features, oneof
+}
+
+type featureKey struct {
+	t    FeatureType
+	name string
+}
+
+func (f Feature) key() featureKey {
+	return featureKey{t: f.Type, name: f.Name}
+}
+
+func (f Feature) describe() string {
+	switch f.Type {
+	case LetFeature:
+		return "field " + f.Name
+	case FuncFeature:
+		return "method " + f.Name
+	case MemLayoutFeature:
+		return "underlying " + f.describeLayout()
+	case EmbedFeature:
+		return "embedded " + f.Return.String()
+	default:
+		return f.Type.String() + " " + f.Name
+	}
+}
+
+func (f Feature) describeLayout() string {
+	switch f.Name {
+	case "func":
+		return f.describeFunc()
+	case "tuple":
+		return f.describeTuple()
+	}
+	if len(f.Args) == 0 {
+		return f.Name
+	}
+	return fmt.Sprintf("%v[%v]", f.Name, typeList(f.Args))
+}
+
+// String returns the name of t if it has one, or a description of its
+// features if it does not.
+func (t Type) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if len(t.Oneof) > 0 {
+		var buf strings.Builder
+		buf.WriteString("oneof {")
+		for i, m := range t.Oneof {
+			if i > 0 {
+				buf.WriteString(";")
+			}
+			buf.WriteString(" ")
+			buf.WriteString(m.String())
+		}
+		buf.WriteString(" }")
+		return buf.String()
+	}
+	if len(t.Features) == 0 {
+		return "type {}"
+	}
+	if (len(t.Features) == 1) && (t.Features[0].Type == MemLayoutFeature) {
+		return t.Features[0].describeLayout()
+	}
+
+	var buf strings.Builder
+	buf.WriteString("type {")
+	for i, f := range t.Features {
+		if i > 0 {
+			buf.WriteString(";")
+		}
+		buf.WriteString(" ")
+		switch f.Type {
+		case LetFeature:
+			fmt.Fprintf(&buf, "let %v %v", f.Name, f.Return)
+		case FuncFeature:
+			buf.WriteString("func ")
+			if f.MutRecv {
+				buf.WriteString("mut ")
+			}
+			fmt.Fprintf(&buf, "%v(%v)", f.Name, typeList(f.Args))
+			if f.Mut {
+				buf.WriteString(" mut")
+			}
+			if f.Return.Valid() {
+				fmt.Fprintf(&buf, " %v", f.Return)
+			}
+		case MemLayoutFeature:
+			buf.WriteString(f.describeLayout())
+		case EmbedFeature:
+			buf.WriteString(f.Return.String())
+		}
+	}
+	buf.WriteString(" }")
+	return buf.String()
+}
+
+func typeList(types []Type) string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.String())
+	}
+	return joinNames(names)
+}
+
+func joinNames(names []string) string {
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,122 @@
+package stele
+
+import (
+	"slices"
+	"testing"
+)
+
+func layout(name string) Type {
+	return Type{Name: name, Features: []Feature{{Type: MemLayoutFeature, Name: name}}}
+}
+
+func method(name string, mutRecv bool, ret Type, args ...Type) Feature {
+	return Feature{Type: FuncFeature, Name: name, Args: args, Return: ret, MutRecv: mutRecv}
+}
+
+func field(name string, t Type) Feature {
+	return Feature{Type: LetFeature, Name: name, Return: t}
+}
+
+func embed(t Type) Feature {
+	return Feature{Type: EmbedFeature, Return: t}
+}
+
+func TestSatisfies(t *testing.T) {
+	var (
+		intT    = layout("int")
+		stringT = layout("string")
+		anyT    = Type{Name: "any"}
+
+		reader = Type{Name: "reader", Features: []Feature{method("read", true, intT, stringT)}}
+		closer = Type{Name: "closer", Features: []Feature{method("close", false, anyT)}}
+		both   = Type{Name: "both", Features: []Feature{embed(reader), embed(closer)}}
+		file   = Type{Name: "file", Features: []Feature{
+			method("read", true, intT, stringT),
+			method("close", false, intT),
+			field("name", stringT),
+		}}
+		immutableFile = Type{Name: "immutableFile", Features: []Feature{
+			method("read", false, intT, stringT),
+		}}
+		mutableReader = Type{Name: "mutableReader", Features: []Feature{
+			method("read", true, intT, stringT),
+		}}
+		pureReader = Type{Name: "pureReader", Features: []Feature{
+			method("read", false, intT, stringT),
+		}}
+		named  = Type{Name: "named", Features: []Feature{field("name", stringT)}}
+		badArg = Type{Name: "badArg", Features: []Feature{method("read", true, intT, intT)}}
+
+		number  = Type{Name: "number", Oneof: []Type{intT, stringT}}
+		wrapped = Type{Name: "wrapped", Features: []Feature{embed(number)}}
+		anyOne  = Type{Name: "anyOne", Oneof: []Type{intT, anyT}}
+	)
+
+	tests := []struct {
+		name    string
+		from    Type
+		to      Type
+		reasons []string
+	}{
+		{name: "Any", from: intT, to: anyT},
+		{name: "Same", from: intT, to: intT},
+		{name: "Layout", from: intT, to: stringT, reasons: []string{"missing underlying string"}},
+		{name: "Embedded", from: file, to: both},
+		{name: "MissingEmbedded", from: reader, to: both, reasons: []string{"missing method close"}},
+		{name: "Field", from: file, to: named},
+		{name: "FieldType", from: Type{Name: "x", Features: []Feature{field("name", intT)}}, to: named, reasons: []string{
+			"field name has type int, but string is required",
+		}},
+		{name: "MutRecv", from: file, to: mutableReader},
+		{name: "ImmutableRecv", from: immutableFile, to: mutableReader},
+		{name: "NeedsMutRecv", from: mutableReader, to: pureReader, reasons: []string{
+			"method read requires a mutable receiver",
+		}},
+		{name: "Args", from: badArg, to: reader, reasons: []string{
+			"method read argument 1 has type int, which string does not satisfy",
+		}},
+		{name: "Oneof", from: intT, to: number},
+		{name: "OneofMissing", from: reader, to: number, reasons: []string{
+			"does not satisfy any of int, string",
+		}},
+		{name: "OneofAmbiguous", from: intT, to: anyOne, reasons: []string{
+			"satisfies more than one of int, any",
+		}},
+		{name: "EmbeddedOneof", from: stringT, to: wrapped},
+		{name: "FromOneof", from: number, to: anyT},
+		{name: "FromOneofMismatch", from: number, to: intT, reasons: []string{
+			"member string: missing underlying int",
+		}},
+		{name: "OneofToOneof", from: number, to: wrapped},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			reasons := test.from.Explain(test.to)
+			if !slices.Equal(reasons, test.reasons) {
+				t.Fatalf("unexpected reasons: %q", reasons)
+			}
+			if ok := test.from.Satisfies(test.to); ok != (len(test.reasons) == 0) {
+				t.Fatalf("Satisfies returned %v", ok)
+			}
+		})
+	}
+}
+
+func TestSatisfiesRecursive(t *testing.T) {
+	// node has a method that returns a node.
+	nodeFeatures := make([]Feature, 1)
+	node := Type{Name: "node", Features: nodeFeatures}
+	nodeFeatures[0] = method("next", false, node)
+
+	otherFeatures := make([]Feature, 1)
+	other := Type{Name: "other", Features: otherFeatures}
+	otherFeatures[0] = method("next", false, other)
+
+	if !node.Satisfies(other) {
+		t.Fatal(node.Explain(other))
+	}
+}
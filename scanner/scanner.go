@@ -5,26 +5,46 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"strconv"
 	"strings"
 	"unicode"
 )
 
+// Mode is a set of flags that modify the behavior of a Scanner.
+type Mode uint
+
+const (
+	// ScanComments causes comments to be returned as COMMENT tokens
+	// instead of being skipped.
+	ScanComments Mode = 1 << iota
+)
+
 type Scanner struct {
-	r   *bufio.Reader
-	err error
+	r    *bufio.Reader
+	mode Mode
+	err  error
+	eof  bool
 
-	line, col int
+	line, col   int
+	pline, pcol int
 
-	wasUnread   bool
 	buf         strings.Builder
 	tok         Token
+	last        Type
 	tline, tcol int
 }
 
 func New(r io.Reader) *Scanner {
+	return NewMode(r, 0)
+}
+
+// NewMode returns a Scanner that reads from r with the behavior
+// modified by mode.
+func NewMode(r io.Reader, mode Mode) *Scanner {
 	return &Scanner{
-		r: bufio.NewReader(r),
+		r:    bufio.NewReader(r),
+		mode: mode,
 	}
 }
 
@@ -35,16 +55,40 @@ func (s *Scanner) Scan() (more bool) {
 
 	s.tok = Token{}
 	s.buf.Reset()
+
+	if s.eof {
+		// A final semicolon is inserted at the end of the input in the
+		// same way as at the end of a line.
+		if !insertSemi(s.last) {
+			return false
+		}
+		s.startToken()
+		s.endToken(SEMI, "\n")
+		return true
+	}
+
 	state := s.whitespace
+	for state != nil {
+		state = s.step(state, false)
+	}
 
+	return (s.err == nil) && (s.tok.Type != INVALID)
+}
+
+// step runs a single state, handling errors that it throws. If the
+// state hits the end of the input, it is run again with eof set to
+// true so that it can finish whatever it was doing.
+func (s *Scanner) step(state state, eof bool) (next state) {
 	defer func() {
 		switch err := recover().(type) {
 		case stateErr:
-			s.err = fmt.Errorf("(%v:%v) %w", s.line, s.col, err.err)
-			if errors.Is(s.err, io.EOF) {
-				state(true)
-				more = true
+			if !eof && errors.Is(err.err, io.EOF) {
+				s.eof = true
+				next = s.step(state, true)
+				return
 			}
+			s.err = fmt.Errorf("(%v:%v) %w", s.line+1, s.col, err.err)
+			next = nil
 		case nil:
 			return
 		default:
@@ -52,11 +96,7 @@ func (s *Scanner) Scan() (more bool) {
 		}
 	}()
 
-	for state != nil {
-		state = state(false)
-	}
-
-	return s.err == nil
+	return state(eof)
 }
 
 func (s *Scanner) throw(err error) {
@@ -68,38 +108,31 @@ func (s *Scanner) Tok() Token {
 }
 
 func (s *Scanner) Err() error {
-	if errors.Is(s.err, io.EOF) {
-		return nil
-	}
 	return s.err
 }
 
-func (s *Scanner) read() (c rune) {
-	if !s.wasUnread {
-		s.col++
-	}
-
-	defer func() {
-		if !s.wasUnread && (c == '\n') {
-			s.line++
-			s.col = 0
-		}
-		s.wasUnread = false
-	}()
-
+func (s *Scanner) read() rune {
 	c, _, err := s.r.ReadRune()
 	if err != nil {
 		s.throw(err)
 	}
+
+	s.pline, s.pcol = s.line, s.col
+	if c == '\n' {
+		s.line++
+		s.col = 0
+		return c
+	}
+	s.col++
 	return c
 }
 
 func (s *Scanner) unread() {
-	s.wasUnread = true
 	err := s.r.UnreadRune()
 	if err != nil {
 		s.throw(err)
 	}
+	s.line, s.col = s.pline, s.pcol
 }
 
 func (s *Scanner) readEscapeSeq() rune {
@@ -126,19 +159,24 @@ func (s *Scanner) readEscapeSeq() rune {
 
 func (s *Scanner) whitespace(eof bool) state {
 	if eof {
+		if insertSemi(s.last) {
+			s.startToken()
+			s.endToken(SEMI, "\n")
+		}
 		return nil
 	}
 
+	line, col := s.line, s.col
 	c := s.read()
 	switch {
-	//case c == '\n':
-	//	if preventSemi(s.Tok()) {
-	//		return s.whitespace
-	//	}
+	case c == '\n':
+		if !insertSemi(s.last) {
+			return s.whitespace
+		}
 
-	//	s.startToken()
-	//	s.endToken(SEMI, ";")
-	//	return nil
+		s.tline, s.tcol = line+1, col+1
+		s.endToken(SEMI, "\n")
+		return nil
 
 	case unicode.IsSpace(c):
 		return s.whitespace
@@ -163,6 +201,12 @@ func (s *Scanner) whitespace(eof bool) state {
 		s.startToken()
 		return s.char
 
+	case c == '#':
+		s.buf.Reset()
+		s.buf.WriteRune(c)
+		s.startToken()
+		return s.singleLineComment
+
 	default:
 		s.buf.Reset()
 		s.buf.WriteRune(c)
@@ -200,7 +244,7 @@ func (s *Scanner) ident(eof bool) state {
 
 func (s *Scanner) string(eof bool) state {
 	if eof {
-		s.throw(errors.New("unterminated string literal"))
+		s.throw(fmt.Errorf("unterminated string literal: %w", io.ErrUnexpectedEOF))
 		return nil
 	}
 
@@ -220,11 +264,7 @@ func (s *Scanner) string(eof bool) state {
 
 func (s *Scanner) int(eof bool) state {
 	if eof {
-		v, err := strconv.ParseInt(s.buf.String(), 0, 64)
-		if err != nil {
-			s.throw(err)
-		}
-		s.endToken(INT, v)
+		s.endToken(INT, s.parseInt())
 		return nil
 	}
 
@@ -240,22 +280,46 @@ func (s *Scanner) int(eof bool) state {
 
 	default:
 		s.unread()
-		v, err := strconv.ParseInt(s.buf.String(), 0, 64)
-		if err != nil {
-			s.throw(err)
-		}
-		s.endToken(INT, v)
+		s.endToken(INT, s.parseInt())
 		return nil
 	}
 }
 
+// parseInt parses the buffered integer literal. Literals that are too
+// large for an int64 are returned as a *big.Int.
+func (s *Scanner) parseInt() any {
+	str := s.buf.String()
+	v, err := strconv.ParseInt(str, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if b, ok := new(big.Int).SetString(str, 0); ok {
+			return b
+		}
+	}
+	if err != nil {
+		s.throw(err)
+	}
+	return v
+}
+
+// parseFloat parses the buffered floating point literal. Literals that
+// can not be represented by a float64 are returned as a *big.Float.
+func (s *Scanner) parseFloat() any {
+	str := s.buf.String()
+	v, err := strconv.ParseFloat(str, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if b, ok := new(big.Float).SetString(str); ok {
+			return b
+		}
+	}
+	if err != nil {
+		s.throw(err)
+	}
+	return v
+}
+
 func (s *Scanner) float(eof bool) state {
 	if eof {
-		v, err := strconv.ParseFloat(s.buf.String(), 64)
-		if err != nil {
-			s.throw(err)
-		}
-		s.endToken(FLOAT, v)
+		s.endToken(FLOAT, s.parseFloat())
 		return nil
 	}
 
@@ -267,18 +331,14 @@ func (s *Scanner) float(eof bool) state {
 
 	default:
 		s.unread()
-		v, err := strconv.ParseFloat(s.buf.String(), 64)
-		if err != nil {
-			s.throw(err)
-		}
-		s.endToken(FLOAT, v)
+		s.endToken(FLOAT, s.parseFloat())
 		return nil
 	}
 }
 
 func (s *Scanner) char(eof bool) state {
 	if eof {
-		s.throw(errors.New("unterimnated char literal"))
+		s.throw(fmt.Errorf("unterminated char literal: %w", io.ErrUnexpectedEOF))
 		return nil
 	}
 
@@ -306,13 +366,8 @@ func (s *Scanner) symbol(eof bool) state {
 		return nil
 	}
 
-	str := s.buf.String()
-	if str == "#" {
-		return s.singleLineComment
-	}
-
 	s.buf.WriteRune(s.read())
-	str = s.buf.String()
+	str := s.buf.String()
 
 	if t, ok := symbols[str]; ok {
 		s.endToken(t, str)
@@ -329,18 +384,28 @@ func (s *Scanner) symbol(eof bool) state {
 }
 
 func (s *Scanner) singleLineComment(eof bool) state {
-	if eof || (s.read() == '\n') {
-		if s.read() != '#' {
-			s.unread()
-			return s.whitespace
+	if !eof {
+		c := s.read()
+		if c != '\n' {
+			s.buf.WriteRune(c)
+			return s.singleLineComment
 		}
 
-		return s.singleLineComment
+		// Leave the newline for whitespace so that it can insert a
+		// semicolon if necessary.
+		s.unread()
 	}
 
-	// TODO: Yield comments?
+	if s.mode&ScanComments == 0 {
+		s.buf.Reset()
+		if eof {
+			return s.whitespace(true)
+		}
+		return s.whitespace
+	}
 
-	return s.singleLineComment
+	s.endToken(COMMENT, s.buf.String())
+	return nil
 }
 
 func (s *Scanner) startToken() {
@@ -355,6 +420,9 @@ func (s *Scanner) endToken(t Type, v any) {
 		Type: t,
 		Val:  v,
 	}
+	if t != COMMENT {
+		s.last = t
+	}
 }
 
 type state func(eof bool) state
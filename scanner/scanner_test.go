@@ -1,6 +1,8 @@
 package scanner
 
 import (
+	"math/big"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -28,6 +30,7 @@ func TestBasic(t *testing.T) {
 	}
 
 	for _, test := range tests {
+		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -42,3 +45,99 @@ func TestBasic(t *testing.T) {
 		})
 	}
 }
+
+func scanAll(t *testing.T, s *Scanner) []Type {
+	var types []Type
+	for s.Scan() {
+		types = append(types, s.Tok().Type)
+	}
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+	return types
+}
+
+func TestSemicolons(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		types []Type
+	}{
+		{name: "AfterIdent", input: "a\nb", types: []Type{IDENT, SEMI, IDENT, SEMI}},
+		{name: "AfterOperator", input: "a +\nb", types: []Type{IDENT, PLUS, IDENT, SEMI}},
+		{name: "AfterBrace", input: "{\n}\n", types: []Type{LBRACE, RBRACE, SEMI}},
+		{name: "Explicit", input: "return;", types: []Type{RETURN, SEMI}},
+		{name: "AfterComment", input: "a # comment\nb", types: []Type{IDENT, SEMI, IDENT, SEMI}},
+		{name: "Mut", input: "func f() mut\n", types: []Type{FUNC, IDENT, LPAREN, RPAREN, MUT, SEMI}},
+		{name: "TrailingWhitespace", input: "a  \n\n  ", types: []Type{IDENT, SEMI}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			types := scanAll(t, New(strings.NewReader(test.input)))
+			if !slices.Equal(types, test.types) {
+				t.Fatalf("token types don't match\n\tgot: %v\n\texpected: %v", types, test.types)
+			}
+		})
+	}
+}
+
+func TestComments(t *testing.T) {
+	const src = "# first\na # second\n"
+
+	s := NewMode(strings.NewReader(src), ScanComments)
+	var toks []Token
+	for s.Scan() {
+		toks = append(toks, s.Tok())
+	}
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+
+	expected := []Token{
+		{1, 1, COMMENT, "# first"},
+		{2, 1, IDENT, "a"},
+		{2, 3, COMMENT, "# second"},
+		{2, 11, SEMI, "\n"},
+	}
+	if !slices.Equal(toks, expected) {
+		t.Fatalf("tokens don't match\n\tgot: %+v\n\texpected: %+v", toks, expected)
+	}
+}
+
+func TestIsIdentifier(t *testing.T) {
+	tests := map[string]bool{
+		"io":     true,
+		"_x2":    true,
+		"héllo":  true,
+		"":       false,
+		"2x":     false,
+		"a-b":    false,
+		"func":   false,
+		"v!":     false,
+		"a.b":    false,
+		"oneof":  false,
+		"oneofs": true,
+	}
+
+	for s, ok := range tests {
+		if IsIdentifier(s) != ok {
+			t.Errorf("IsIdentifier(%q) != %v", s, ok)
+		}
+	}
+}
+
+func TestBigLiterals(t *testing.T) {
+	s := New(strings.NewReader("123456789012345678901234567890"))
+	s.Scan()
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+	v, ok := s.Tok().Val.(*big.Int)
+	if !ok || (v.String() != "123456789012345678901234567890") {
+		t.Fatalf("unexpected value: %#v", s.Tok().Val)
+	}
+}
@@ -3,21 +3,27 @@ package scanner
 import (
 	"fmt"
 	"slices"
+	"unicode"
 )
 
 //go:generate go run golang.org/x/tools/cmd/stringer -type Type
 
 var (
 	keywords = map[string]Type{
-		"func":   FUNC,
-		"import": IMPORT,
-		"let":    LET,
-		"type":   TYPE,
-		"if":     IF,
-		"else":   ELSE,
-		"switch": SWITCH,
-		"as":     AS,
-		"return": RETURN,
+		"func":     FUNC,
+		"import":   IMPORT,
+		"let":      LET,
+		"type":     TYPE,
+		"if":       IF,
+		"else":     ELSE,
+		"switch":   SWITCH,
+		"as":       AS,
+		"return":   RETURN,
+		"mut":      MUT,
+		"oneof":    ONEOF,
+		"for":      FOR,
+		"break":    BREAK,
+		"continue": CONTINUE,
 	}
 
 	symbols = map[string]Type{
@@ -54,11 +60,33 @@ var (
 		",":  COMMA,
 		"<<": LSHIFT,
 		">>": RSHIFT,
+		"%":  MOD,
+		"%=": MODASSIGN,
+		"->": ARROW,
+		"?":  QUESTION,
 	}
 )
 
+// text is the reverse of keywords and symbols.
+var text = func() map[Type]string {
+	m := make(map[Type]string, len(keywords)+len(symbols))
+	for str, t := range keywords {
+		m[t] = str
+	}
+	for str, t := range symbols {
+		m[t] = str
+	}
+	return m
+}()
+
 type Type int
 
+// Text returns the source text of a keyword or symbol token type. For
+// other token types, it returns the empty string.
+func (t Type) Text() string {
+	return text[t]
+}
+
 // Token types.
 const (
 	INVALID Type = iota
@@ -73,6 +101,11 @@ const (
 	SWITCH
 	AS
 	RETURN
+	MUT
+	ONEOF
+	FOR
+	BREAK
+	CONTINUE
 
 	// Symbols
 	LPAREN      // (
@@ -108,12 +141,17 @@ const (
 	COMMA       // ,
 	LSHIFT      // <<
 	RSHIFT      // >>
+	MOD         // %
+	MODASSIGN   // %=
+	ARROW       // ->
+	QUESTION    // ?
 
 	// Other
 	IDENT
 	STRING
 	INT
 	FLOAT
+	COMMENT
 )
 
 func keywordOrIdent(s string) Type {
@@ -123,16 +161,44 @@ func keywordOrIdent(s string) Type {
 	return IDENT
 }
 
-func preventSemi(tok Token) bool {
+// IsKeyword returns true if s is a keyword.
+func IsKeyword(s string) bool {
+	_, ok := keywords[s]
+	return ok
+}
+
+// IsIdentifier returns true if s is a valid identifier that is not a
+// keyword. The immutability suffix, !, is not considered to be part of
+// an identifier.
+func IsIdentifier(s string) bool {
+	if (s == "") || IsKeyword(s) {
+		return false
+	}
+	for i, c := range s {
+		if !unicode.IsLetter(c) && (c != '_') && ((i == 0) || !unicode.IsNumber(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// insertSemi returns true if a newline directly following a token of
+// type t should cause a semicolon to be inserted.
+func insertSemi(t Type) bool {
 	return slices.Contains([]Type{
-		SEMI,
-		DOT,
-		PIPE,
-		COMMA,
-		LPAREN,
-		LBRACE,
-		LBRACKET,
-	}, tok.Type)
+		IDENT,
+		STRING,
+		INT,
+		FLOAT,
+		RPAREN,
+		RBRACKET,
+		RBRACE,
+		RETURN,
+		BREAK,
+		CONTINUE,
+		MUT,
+		QUESTION,
+	}, t)
 }
 
 type Token struct {
@@ -141,6 +207,37 @@ type Token struct {
 	Val       any
 }
 
+// Pos returns the position of the start of the token.
+func (t Token) Pos() Pos {
+	return Pos{Line: t.Line, Col: t.Col}
+}
+
 func (t Token) String() string {
 	return fmt.Sprintf("%v (%v:%v)", t.Type, t.Line, t.Col)
 }
+
+// Pos is a position in a source file. Both the line and the column
+// start at 1. File is the name of the file, if it is known. The zero
+// value represents an unknown position.
+type Pos struct {
+	File      string
+	Line, Col int
+}
+
+// IsValid returns true if p represents a known position.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	if !p.IsValid() {
+		if p.File != "" {
+			return p.File
+		}
+		return "-"
+	}
+	if p.File != "" {
+		return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Col)
+	}
+	return fmt.Sprintf("%v:%v", p.Line, p.Col)
+}
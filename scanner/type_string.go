@@ -18,52 +18,63 @@ func _() {
 	_ = x[SWITCH-7]
 	_ = x[AS-8]
 	_ = x[RETURN-9]
-	_ = x[LPAREN-10]
-	_ = x[RPAREN-11]
-	_ = x[LBRACE-12]
-	_ = x[RBRACE-13]
-	_ = x[LBRACKET-14]
-	_ = x[RBRACKET-15]
-	_ = x[SEMI-16]
-	_ = x[PLUS-17]
-	_ = x[MINUS-18]
-	_ = x[MULT-19]
-	_ = x[DIV-20]
-	_ = x[PLUSASSIGN-21]
-	_ = x[MINUSASSIGN-22]
-	_ = x[MULTASSIGN-23]
-	_ = x[DIVASSIGN-24]
-	_ = x[BITNOT-25]
-	_ = x[BITOR-26]
-	_ = x[BITAND-27]
-	_ = x[NOT-28]
-	_ = x[OR-29]
-	_ = x[AND-30]
-	_ = x[EQUAL-31]
-	_ = x[NOTEQUAL-32]
-	_ = x[LT-33]
-	_ = x[GT-34]
-	_ = x[LE-35]
-	_ = x[GE-36]
-	_ = x[ASSIGN-37]
-	_ = x[DOT-38]
-	_ = x[PIPE-39]
-	_ = x[COMMA-40]
-	_ = x[LSHIFT-41]
-	_ = x[RSHIFT-42]
-	_ = x[IDENT-43]
-	_ = x[STRING-44]
-	_ = x[INT-45]
-	_ = x[FLOAT-46]
+	_ = x[MUT-10]
+	_ = x[ONEOF-11]
+	_ = x[FOR-12]
+	_ = x[BREAK-13]
+	_ = x[CONTINUE-14]
+	_ = x[LPAREN-15]
+	_ = x[RPAREN-16]
+	_ = x[LBRACE-17]
+	_ = x[RBRACE-18]
+	_ = x[LBRACKET-19]
+	_ = x[RBRACKET-20]
+	_ = x[SEMI-21]
+	_ = x[PLUS-22]
+	_ = x[MINUS-23]
+	_ = x[MULT-24]
+	_ = x[DIV-25]
+	_ = x[PLUSASSIGN-26]
+	_ = x[MINUSASSIGN-27]
+	_ = x[MULTASSIGN-28]
+	_ = x[DIVASSIGN-29]
+	_ = x[BITNOT-30]
+	_ = x[BITOR-31]
+	_ = x[BITAND-32]
+	_ = x[NOT-33]
+	_ = x[OR-34]
+	_ = x[AND-35]
+	_ = x[EQUAL-36]
+	_ = x[NOTEQUAL-37]
+	_ = x[LT-38]
+	_ = x[GT-39]
+	_ = x[LE-40]
+	_ = x[GE-41]
+	_ = x[ASSIGN-42]
+	_ = x[DOT-43]
+	_ = x[PIPE-44]
+	_ = x[COMMA-45]
+	_ = x[LSHIFT-46]
+	_ = x[RSHIFT-47]
+	_ = x[MOD-48]
+	_ = x[MODASSIGN-49]
+	_ = x[ARROW-50]
+	_ = x[QUESTION-51]
+	_ = x[IDENT-52]
+	_ = x[STRING-53]
+	_ = x[INT-54]
+	_ = x[FLOAT-55]
+	_ = x[COMMENT-56]
 }
 
-const _Type_name = "INVALIDFUNCIMPORTLETTYPEIFELSESWITCHASRETURNLPARENRPARENLBRACERBRACELBRACKETRBRACKETSEMIPLUSMINUSMULTDIVPLUSASSIGNMINUSASSIGNMULTASSIGNDIVASSIGNBITNOTBITORBITANDNOTORANDEQUALNOTEQUALLTGTLEGEASSIGNDOTPIPECOMMALSHIFTRSHIFTIDENTSTRINGINTFLOAT"
+const _Type_name = "INVALIDFUNCIMPORTLETTYPEIFELSESWITCHASRETURNMUTONEOFFORBREAKCONTINUELPARENRPARENLBRACERBRACELBRACKETRBRACKETSEMIPLUSMINUSMULTDIVPLUSASSIGNMINUSASSIGNMULTASSIGNDIVASSIGNBITNOTBITORBITANDNOTORANDEQUALNOTEQUALLTGTLEGEASSIGNDOTPIPECOMMALSHIFTRSHIFTMODMODASSIGNARROWQUESTIONIDENTSTRINGINTFLOATCOMMENT"
 
-var _Type_index = [...]uint8{0, 7, 11, 17, 20, 24, 26, 30, 36, 38, 44, 50, 56, 62, 68, 76, 84, 88, 92, 97, 101, 104, 114, 125, 135, 144, 150, 155, 161, 164, 166, 169, 174, 182, 184, 186, 188, 190, 196, 199, 203, 208, 214, 220, 225, 231, 234, 239}
+var _Type_index = [...]uint16{0, 7, 11, 17, 20, 24, 26, 30, 36, 38, 44, 47, 52, 55, 60, 68, 74, 80, 86, 92, 100, 108, 112, 116, 121, 125, 128, 138, 149, 159, 168, 174, 179, 185, 188, 190, 193, 198, 206, 208, 210, 212, 214, 220, 223, 227, 232, 238, 244, 247, 256, 261, 269, 274, 280, 283, 288, 295}
 
 func (i Type) String() string {
-	if i < 0 || i >= Type(len(_Type_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Type_index)-1 {
 		return "Type(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _Type_name[_Type_index[i]:_Type_index[i+1]]
+	return _Type_name[_Type_index[idx]:_Type_index[idx+1]]
 }
@@ -1,75 +1,238 @@
 package stele
 
+import (
+	"fmt"
+	"slices"
+
+	"deedles.dev/stele/scanner"
+)
+
 // Scope represents the declarations available for a given piece of
 // code. It is a compile-time structure. The run-time equivalent is
 // Frame, which tracks the actual values of declarations.
 //
-// A zero-value scope is a direct child of the scope retunred by
-// [RootScope].
+// A scope is a stack of blocks, each of which is a flat list of
+// declarations. Every declaration that is visible from a scope can be
+// resolved to a depth, which is the number of blocks outwards that it
+// is in, and a slot, which is its index in that block. A Frame is laid
+// out the same way, so that looking up a value at run time takes
+// constant time.
+//
+// Scopes are values. Adding to a scope returns a new one and leaves
+// the original as it was, so a scope can be saved and returned to
+// later, such as when leaving a block. A scope may not be added to
+// from more than one goroutine at a time, however.
+//
+// A zero-value scope is an empty block directly inside of the one
+// returned by [RootScope].
 type Scope struct {
-	parent *Scope
-	ids    func() []string
-	get    func(string) Declaration
+	b *block
+
+	// n is the number of declarations in b that are visible from the
+	// scope. Declarations that have been added to b since then belong
+	// to other scopes that share it.
+	n int
+}
+
+type block struct {
+	parent Scope
+	level  int
+
+	// outer is the scopes that the block is inside of, indexed by
+	// level, so that any of them can be found without walking up
+	// through the ones in between.
+	outer []Scope
+
+	// boundary is true if the block was started with Block, rather
+	// than being created implicitly by adding to the root scope.
+	boundary bool
+
+	decls []Declaration
+	pos   []scanner.Pos
+
+	// index maps IDs to the slots of the declarations in the block
+	// with that ID, in the order in which they were added. There is
+	// only more than one if an ID was added more than once without
+	// using Declare.
+	index map[string][]int
 }
 
-var (
-	// fakeScope is a signal value to help make the zero value useful.
-	fakeScope Scope
+// rootBlock holds the predeclared identifiers in order of ID.
+var rootBlock = func() *block {
+	ids := make([]string, 0, len(predeclared))
+	for id := range predeclared {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
 
-	// TODO: Add predeclared identifiers.
-	rootScope = Scope{
-		parent: &fakeScope,
-		get:    func(string) Declaration { return nil },
+	b := block{index: make(map[string][]int, len(ids))}
+	for _, id := range ids {
+		b.add(predeclared[id], scanner.Pos{})
 	}
-)
+	return &b
+}()
 
 // RootScope returns the base scope that all scopes are the child of.
 // It is not usually necessary to call this directly, as the
 // zero-value of a Scope is considered to be an empty child scope of
 // the one returned by this function.
 func RootScope() Scope {
-	return rootScope
+	return Scope{b: rootBlock, n: len(rootBlock.decls)}
 }
 
-// Parent returns the parent of the current Scope, or nil if the
-// current scope is the root scope.
+// Parent returns the scope that the innermost block of the current
+// Scope was started in, or nil if the current scope is the root
+// scope.
 func (s Scope) Parent() *Scope {
-	switch s.parent {
+	switch s.b {
 	case nil:
-		return &rootScope
-	case &fakeScope:
+		return &Scope{b: rootBlock, n: len(rootBlock.decls)}
+	case rootBlock:
 		return nil
 	default:
-		return s.parent
+		return &s.b.parent
 	}
 }
 
-// Add returns a new child scope continaing d.
-func (s Scope) Add(d Declaration) Scope {
-	return Scope{
-		parent: &s,
-		get: func(id string) Declaration {
-			if id == d.ID() {
-				return d
-			}
-			return nil
-		},
+// Depth returns the number of blocks that s is inside of. The root
+// scope has a depth of zero.
+func (s Scope) Depth() int {
+	if s.b == nil {
+		return 1
 	}
+	return s.b.level
+}
+
+// Len returns the number of declarations in the innermost block of s.
+// This is the number of slots that a Frame for the block needs.
+func (s Scope) Len() int {
+	return s.n
+}
+
+// Add returns a new scope containing d as well as everything in s. d
+// is added to the innermost block of s, shadowing anything already in
+// it with the same ID.
+func (s Scope) Add(d Declaration) Scope {
+	return s.add(d, scanner.Pos{})
 }
 
-// AddAll returns a new child scope containing all of the Declarations
-// in d.
+// AddAll returns a new scope containing all of the Declarations in d
+// as well as everything in s. It is the same as calling Add with each
+// of them in order.
 func (s Scope) AddAll(d []Declaration) Scope {
-	m := make(map[string]Declaration, len(d))
 	for _, d := range d {
-		m[d.ID()] = d
+		s = s.add(d, scanner.Pos{})
+	}
+	return s
+}
+
+func (s Scope) add(d Declaration, pos scanner.Pos) Scope {
+	switch {
+	case (s.b == nil) || (s.b == rootBlock):
+		// The root block is shared by everything, so it is never added
+		// to.
+		s = RootScope().start(false)
+
+	case s.n < len(s.b.decls):
+		// Something else has already added to the block since s was
+		// created, so s gets a copy of its own.
+		s.b = s.b.fork(s.n)
+	}
+
+	s.b.add(d, pos)
+	s.n++
+	return s
+}
+
+// start returns a new scope with an empty block inside of s.
+func (s Scope) start(boundary bool) Scope {
+	if s.b == nil {
+		s = RootScope().start(false)
+	}
+
+	return Scope{b: &block{
+		parent:   s,
+		level:    s.b.level + 1,
+		outer:    append(s.b.outer[:s.b.level:s.b.level], s),
+		boundary: boundary,
+		index:    make(map[string][]int),
+	}}
+}
+
+func (b *block) add(d Declaration, pos scanner.Pos) {
+	id := d.ID()
+	b.index[id] = append(b.index[id], len(b.decls))
+	b.decls = append(b.decls, d)
+	b.pos = append(b.pos, pos)
+}
+
+// fork returns a copy of the first n declarations of b.
+func (b *block) fork(n int) *block {
+	f := block{
+		parent:   b.parent,
+		level:    b.level,
+		outer:    b.outer,
+		boundary: b.boundary,
+		index:    make(map[string][]int, n),
+	}
+	for i := 0; i < n; i++ {
+		f.add(b.decls[i], b.pos[i])
+	}
+	return &f
+}
+
+// lookup returns the slot of the most recent declaration in the first
+// n of b with the given ID.
+func (b *block) lookup(id string, n int) (int, bool) {
+	slots := b.index[id]
+	for i := len(slots) - 1; i >= 0; i-- {
+		if slots[i] < n {
+			return slots[i], true
+		}
+	}
+	return 0, false
+}
+
+// Block returns a new, empty child scope that starts a block.
+// Declarations in a block may shadow those outside of it, but may not
+// redeclare those in it.
+func (s Scope) Block() Scope {
+	return s.start(true)
+}
+
+// Declare is like Add, but it checks that d does not redeclare
+// anything in the same block as s first. If it does, it returns s and
+// a *RedeclaredError. pos is the position at which d is declared.
+func (s Scope) Declare(d Declaration, pos scanner.Pos) (Scope, error) {
+	id := d.ID()
+	for cur := s; (cur.b != nil) && (cur.b != rootBlock); cur = cur.b.parent {
+		if slot, ok := cur.b.lookup(id, cur.n); ok {
+			return s, &RedeclaredError{ID: id, Pos: pos, Prev: cur.b.pos[slot]}
+		}
+		if cur.b.boundary {
+			break
+		}
 	}
 
-	return Scope{
-		parent: &s,
-		get: func(id string) Declaration {
-			return m[id]
-		},
+	return s.add(d, pos), nil
+}
+
+// Resolve returns the depth and slot of the declaration that id refers
+// to in s. The depth is the number of blocks outwards from the
+// innermost block of s that the declaration is in.
+func (s Scope) Resolve(id string) (depth, slot int, ok bool) {
+	cur := s
+	if cur.b == nil {
+		cur, depth = RootScope(), 1
+	}
+	for {
+		if slot, ok := cur.b.lookup(id, cur.n); ok {
+			return depth, slot, true
+		}
+		if cur.b == rootBlock {
+			return 0, 0, false
+		}
+		cur, depth = cur.b.parent, depth+1
 	}
 }
 
@@ -77,18 +240,106 @@ func (s Scope) AddAll(d []Declaration) Scope {
 // encountered Declaration with the given ID. If no such Declaration
 // exists, it returns nil.
 func (s Scope) Get(id string) Declaration {
-	if s.get != nil {
-		d := s.get(id)
-		if d != nil {
-			return d
+	depth, slot, ok := s.Resolve(id)
+	if !ok {
+		return nil
+	}
+	return s.At(depth, slot)
+}
+
+// At returns the declaration at the given depth and slot, as returned
+// by Resolve.
+func (s Scope) At(depth, slot int) Declaration {
+	if s.b == nil {
+		s, depth = RootScope(), depth-1
+	}
+	if depth > 0 {
+		s = s.b.outer[s.b.level-depth]
+	}
+	return s.b.decls[slot]
+}
+
+// IDs returns the sorted IDs of all of the declarations that are
+// visible from s, including the predeclared ones.
+func (s Scope) IDs() []string {
+	var ids []string
+	s.All()(func(d Declaration) bool {
+		ids = append(ids, d.ID())
+		return true
+	})
+	slices.Sort(ids)
+	return ids
+}
+
+// All returns an iterator over the declarations that are visible from
+// s, starting with the innermost. Declarations that are shadowed by
+// another are skipped.
+func (s Scope) All() func(yield func(Declaration) bool) {
+	return func(yield func(Declaration) bool) {
+		seen := make(map[string]struct{})
+		for cur := &s; cur != nil; cur = cur.Parent() {
+			if !cur.each(seen, yield) {
+				return
+			}
 		}
 	}
+}
 
-	p := s.Parent()
-	if p == nil {
-		return nil
+// Local returns an iterator over the declarations in the innermost
+// block of s, starting with the most recent. Predeclared identifiers
+// are never local.
+func (s Scope) Local() func(yield func(Declaration) bool) {
+	return func(yield func(Declaration) bool) {
+		seen := make(map[string]struct{})
+		for cur := s; (cur.b != nil) && (cur.b != rootBlock); cur = cur.b.parent {
+			if !cur.each(seen, yield) || cur.b.boundary {
+				return
+			}
+		}
+	}
+}
+
+// each calls yield with each of the declarations in the innermost
+// block of s whose IDs are not in seen, most recent first, adding them
+// to it as it goes. It returns false if yield does.
+func (s *Scope) each(seen map[string]struct{}, yield func(Declaration) bool) bool {
+	if s.b == nil {
+		return true
+	}
+	for i := s.n - 1; i >= 0; i-- {
+		d := s.b.decls[i]
+		if _, ok := seen[d.ID()]; ok {
+			continue
+		}
+		seen[d.ID()] = struct{}{}
+		if !yield(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// Predeclared returns true if looking up id in s finds one of the
+// predeclared identifiers in the root scope, rather than a declaration
+// that shadows it.
+func (s Scope) Predeclared(id string) bool {
+	depth, _, ok := s.Resolve(id)
+	return ok && (depth == s.Depth())
+}
+
+// RedeclaredError is returned by [Scope.Declare] when a declaration
+// has the same ID as another one in the same block. Prev is the
+// position of the other declaration, if it is known.
+type RedeclaredError struct {
+	ID        string
+	Pos, Prev scanner.Pos
+}
+
+func (err *RedeclaredError) Error() string {
+	if !err.Prev.IsValid() {
+		return fmt.Sprintf("%v redeclared in this block", err.ID)
 	}
-	return p.Get(id)
+	return fmt.Sprintf("%v redeclared in this block; previous declaration at %v", err.ID, err.Prev)
 }
 
 // A Declaration is something declared in a scope in a Stele program.
@@ -0,0 +1,170 @@
+package stele
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"deedles.dev/stele/scanner"
+)
+
+func TestScopeDeclare(t *testing.T) {
+	pos := func(line int) scanner.Pos { return scanner.Pos{Line: line, Col: 1} }
+
+	s, err := Scope{}.Declare(Let{Name: "a", T: Bool}, pos(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = s.Declare(TypeDecl{Name: "bool", T: Unit}, pos(2))
+	if err != nil {
+		t.Fatalf("could not shadow predeclared identifier: %v", err)
+	}
+
+	_, err = s.Declare(Func{Name: "a", T: Unit}, pos(3))
+	var rerr *RedeclaredError
+	if !errors.As(err, &rerr) || (rerr.ID != "a") || (rerr.Pos != pos(3)) || (rerr.Prev != pos(1)) {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if err.Error() != "a redeclared in this block; previous declaration at 1:1" {
+		t.Fatalf("unexpected message: %v", err)
+	}
+
+	sub, err := s.Block().Declare(Let{Name: "a!", T: Unit}, pos(4))
+	if err != nil {
+		t.Fatalf("could not shadow in block: %v", err)
+	}
+	if _, err := sub.Declare(Let{Name: "a"}, pos(5)); err == nil {
+		t.Fatal("redeclared a in block")
+	}
+	if d := sub.Get("a"); d.Mutable() {
+		t.Fatalf("unexpected declaration for a: %#v", d)
+	}
+}
+
+func TestScopeAll(t *testing.T) {
+	s := Scope{}.AddAll([]Declaration{
+		Let{Name: "b", T: Bool},
+		Let{Name: "a", T: Bool},
+	})
+	s = s.Block().Add(Let{Name: "a!", T: Unit}).Add(Let{Name: "int", T: Unit})
+
+	var local []string
+	s.Local()(func(d Declaration) bool {
+		local = append(local, d.(Let).Name)
+		return true
+	})
+	if !slices.Equal(local, []string{"int", "a!"}) {
+		t.Fatalf("unexpected local declarations: %v", local)
+	}
+
+	var all []Declaration
+	s.All()(func(d Declaration) bool {
+		all = append(all, d)
+		return len(all) < 3
+	})
+	if (len(all) != 3) || (all[1].ID() != "a") || all[1].Mutable() || (all[2].ID() != "b") {
+		t.Fatalf("unexpected declarations: %#v", all)
+	}
+
+	ids := s.IDs()
+	if len(ids) != len(predeclared)+2 {
+		t.Fatalf("unexpected IDs: %v", ids)
+	}
+	if !slices.IsSorted(ids) || !slices.Contains(ids, "a") || !slices.Contains(ids, "opt") {
+		t.Fatalf("unexpected IDs: %v", ids)
+	}
+}
+
+func TestScopeResolve(t *testing.T) {
+	base := Scope{}.Add(Let{Name: "a", T: Bool}).Add(Let{Name: "b", T: Bool})
+	inner := base.Block().Add(Let{Name: "c", T: Unit}).Block().Add(Let{Name: "a", T: Unit})
+
+	tests := []struct {
+		id          string
+		depth, slot int
+		predeclared bool
+		undefined   bool
+	}{
+		{id: "a", depth: 0, slot: 0},
+		{id: "c", depth: 1, slot: 0},
+		{id: "b", depth: 2, slot: 1},
+		{id: "int", depth: 3, slot: slices.Index(RootScope().IDs(), "int"), predeclared: true},
+		{id: "missing", undefined: true},
+	}
+	for _, test := range tests {
+		depth, slot, ok := inner.Resolve(test.id)
+		if ok == test.undefined {
+			t.Errorf("unexpected result resolving %v: %v", test.id, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if (depth != test.depth) || (slot != test.slot) {
+			t.Errorf("%v resolved to (%v, %v)", test.id, depth, slot)
+		}
+		if inner.At(depth, slot).Type().String() != inner.Get(test.id).Type().String() {
+			t.Errorf("%v is not at (%v, %v)", test.id, depth, slot)
+		}
+		if inner.Predeclared(test.id) != test.predeclared {
+			t.Errorf("unexpected predeclared for %v", test.id)
+		}
+	}
+}
+
+func TestScopeFork(t *testing.T) {
+	base := Scope{}.Add(Let{Name: "a", T: Bool})
+	left := base.Add(Let{Name: "b", T: Bool})
+	right := base.Add(Let{Name: "c", T: Bool})
+	shadow := left.Add(Let{Name: "a", T: Unit})
+
+	if (left.Get("c") != nil) || (right.Get("b") != nil) || (base.Get("b") != nil) {
+		t.Fatal("additions leaked between scopes")
+	}
+	if (left.Get("a").Type().Name != "bool") || (shadow.Get("a").Type().Name != "unit") {
+		t.Fatal("unexpected shadowing")
+	}
+	if (left.Len() != 2) || (right.Len() != 2) || (shadow.Len() != 3) {
+		t.Fatalf("unexpected lengths: %v, %v, %v", left.Len(), right.Len(), shadow.Len())
+	}
+}
+
+func benchmarkScope(b *testing.B, blocks, perBlock int) {
+	s := Scope{}.Add(Let{Name: "target", T: Bool})
+	for i := 0; i < blocks; i++ {
+		s = s.Block()
+		for j := 0; j < perBlock; j++ {
+			s = s.Add(Let{Name: fmt.Sprintf("v%v_%v", i, j), T: Bool})
+		}
+	}
+
+	b.Run("Get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if s.Get("target") == nil {
+				b.Fatal("target not found")
+			}
+		}
+	})
+
+	depth, slot, _ := s.Resolve("target")
+	b.Run("At", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if s.At(depth, slot) == nil {
+				b.Fatal("target not found")
+			}
+		}
+	})
+}
+
+func BenchmarkScopeFlat(b *testing.B) {
+	benchmarkScope(b, 1, 1000)
+}
+
+func BenchmarkScopeNested(b *testing.B) {
+	benchmarkScope(b, 100, 10)
+}
+
+func BenchmarkScopeDeep(b *testing.B) {
+	benchmarkScope(b, 1000, 1)
+}
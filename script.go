@@ -1,5 +1,81 @@
 package stele
 
+import "fmt"
+
+// Script is the checked, runnable form of a single package.
 type Script struct {
+	// Scope contains all of the top-level declarations of the script.
 	Scope Scope
+
+	// Decls is the top-level declarations of the script in the order
+	// in which they were declared.
+	Decls []Declaration
+
+	// Descs is the table that the types of the script were interned in
+	// when it was checked.
+	Descs *Descs
+}
+
+// Exports returns the declarations of the script that are visible to
+// scripts that import it.
+func (s Script) Exports() []Declaration {
+	var exports []Declaration
+	for _, d := range s.Decls {
+		if d.Exported() {
+			exports = append(exports, d)
+		}
+	}
+	return exports
+}
+
+// Run runs the top-level code of s in state, assigning values to its
+// variables in the order in which they were declared. Its functions
+// and methods are defined first, so that that code may call them. If
+// the code fails, the returned error is a *RuntimeError.
+//
+// From then on, state interns types in the table of s.
+func (s Script) Run(state *State) (err error) {
+	defer catch(&err)
+
+	if s.Descs != nil {
+		state.Descs = s.Descs
+	}
+
+	for _, d := range s.Decls {
+		f, ok := d.(Func)
+		if !ok {
+			continue
+		}
+		if f.Recv == nil {
+			state.Globals[f.Name] = ValueOf(f.Desc, Function(Closure{Func: &f}))
+			continue
+		}
+
+		for _, recv := range f.RecvTypes() {
+			if state.methods[recv] == nil {
+				state.methods[recv] = make(map[string]*Func)
+			}
+			state.methods[recv][f.Name] = &f
+		}
+	}
+
+	for _, d := range s.Decls {
+		if let, ok := d.(Let); ok && (let.Assign != nil) {
+			let.Assign.Eval(state)
+		}
+	}
+	return nil
+}
+
+// Call calls the top-level function name of s, which must have been
+// run in state, with args. If the call fails, the returned error is a
+// *RuntimeError.
+func (s Script) Call(state *State, name string, args ...Value) (r Value, err error) {
+	defer catch(&err)
+
+	f, ok := state.Globals[name].Interface().(Function)
+	if !ok {
+		return Value{}, fmt.Errorf("%v is not a function", name)
+	}
+	return f.Call(state, args), nil
 }
@@ -0,0 +1,231 @@
+package stele
+
+import (
+	"fmt"
+
+	"deedles.dev/stele/scanner"
+)
+
+// State is the state of a running script.
+type State struct {
+	// Globals holds the values of top-level declarations and
+	// predeclared identifiers by ID.
+	Globals map[string]Value
+
+	// Frame is the frame of the innermost block that is running. It is
+	// nil if no block is.
+	Frame *Frame
+
+	// Stack is the call stack, with the most recent call last. It is
+	// empty while top-level code is running.
+	Stack []Caller
+
+	// Pos is the position of the statement that is running.
+	Pos scanner.Pos
+
+	// Descs is the table that the types of values are interned in. It
+	// is that of the script that is running.
+	Descs *Descs
+
+	// methods holds the methods declared by running scripts, keyed by
+	// the name of their receiver type and then by their own.
+	methods map[string]map[string]*Func
+}
+
+// NewState returns a State in which nothing but the predeclared
+// identifiers have values.
+func NewState() *State {
+	state := State{
+		Globals: make(map[string]Value),
+		Descs:   NewDescs(),
+		methods: make(map[string]map[string]*Func),
+	}
+	for _, d := range predeclared {
+		if let, ok := d.(Let); ok && (let.Assign != nil) {
+			let.Assign.Eval(&state)
+		}
+	}
+	return &state
+}
+
+// MaxCallDepth is the most function calls that may be running at once.
+// Calling a function when that many already are is a run-time error,
+// which keeps infinite recursion in a script from crashing the program
+// that is running it.
+const MaxCallDepth = 10000
+
+// A Caller is an entry in a State's call stack. It records what is
+// needed to return to the code that called a function.
+type Caller struct {
+	// Func is the name of the function that was called.
+	Func string
+
+	// Frame and Pos are the frame and position of the call.
+	Frame *Frame
+	Pos   scanner.Pos
+}
+
+// Call enters a call of the function named name. frame becomes the
+// current frame until the matching call to Return. It panics with a
+// *RuntimeError if that would be more than MaxCallDepth calls.
+func (s *State) Call(name string, frame *Frame) {
+	if len(s.Stack) >= MaxCallDepth {
+		s.panicf("stack overflow: more than %v calls", MaxCallDepth)
+	}
+	s.Stack = append(s.Stack, Caller{Func: name, Frame: s.Frame, Pos: s.Pos})
+	s.Frame = frame
+}
+
+// Return leaves the most recent function call, returning to the frame
+// and position that it was called from.
+func (s *State) Return() {
+	c := s.Stack[len(s.Stack)-1]
+	s.Stack = s.Stack[:len(s.Stack)-1]
+	s.Frame, s.Pos = c.Frame, c.Pos
+}
+
+// RuntimeError is an error that occurs while a script is running,
+// such as an out of range index. Pos is the position of the statement
+// that caused it.
+type RuntimeError struct {
+	Pos scanner.Pos
+	Msg string
+}
+
+func (err *RuntimeError) Error() string {
+	return fmt.Sprintf("(%v) %v", err.Pos, err.Msg)
+}
+
+// panicf stops the running script with a RuntimeError at the current
+// position.
+func (s *State) panicf(format string, args ...any) {
+	panic(&RuntimeError{Pos: s.Pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// catch recovers a RuntimeError into *err. Any other panic continues.
+func catch(err *error) {
+	switch r := recover().(type) {
+	case nil:
+	case *RuntimeError:
+		*err = r
+	default:
+		panic(r)
+	}
+}
+
+// desc returns d, which the checker resolves t to. Expressions that
+// were put together some other way might not have it, in which case t
+// is interned in s.Descs instead.
+func (s *State) desc(d *TypeDesc, t Type) *TypeDesc {
+	if d != nil {
+		return d
+	}
+	return s.Descs.Intern(t)
+}
+
+// Get returns the value of the variable with the given ID that is
+// kept at ref.
+func (s *State) Get(id string, ref Ref) Value {
+	if ref.Local {
+		return *s.Frame.At(ref.Depth, ref.Slot)
+	}
+
+	v, ok := s.Globals[id]
+	if !ok {
+		s.panicf("%v has not been initialized", id)
+	}
+	return v
+}
+
+// Set sets the value of the variable with the given ID that is kept at
+// ref.
+func (s *State) Set(id string, ref Ref, v Value) {
+	if ref.Local {
+		*s.Frame.At(ref.Depth, ref.Slot) = v
+		return
+	}
+	s.Globals[id] = v
+}
+
+// Ref is where the value of a variable is kept at run time. Variables
+// that are declared in a function or block are local, and are kept in
+// a slot of the frame of that block, Depth frames out from the current
+// one. Others, such as top-level variables, are globals.
+type Ref struct {
+	Local       bool
+	Depth, Slot int
+}
+
+// Frame holds the values of the variables declared in a block while it
+// is running. Its slots are laid out in the same order as the block's
+// declarations in its Scope.
+type Frame struct {
+	Slots []Value
+
+	// outer is the frames that the frame is inside of, outermost
+	// first, so that any of them can be found in constant time.
+	outer []*Frame
+}
+
+// NewFrame returns a frame with n slots for a block inside of the one
+// that outer is the frame of. outer may be nil.
+func NewFrame(outer *Frame, n int) *Frame {
+	f := Frame{Slots: make([]Value, n)}
+	if outer != nil {
+		f.outer = append(outer.outer[:len(outer.outer):len(outer.outer)], outer)
+	}
+	return &f
+}
+
+// Outer returns the frame that f is inside of, or nil if there is
+// none.
+func (f *Frame) Outer() *Frame {
+	if len(f.outer) == 0 {
+		return nil
+	}
+	return f.outer[len(f.outer)-1]
+}
+
+// At returns the slot that is depth frames out from f.
+func (f *Frame) At(depth, slot int) *Value {
+	if depth > 0 {
+		f = f.outer[len(f.outer)-depth]
+	}
+	return &f.Slots[slot]
+}
+
+// callMethod calls the method name of recv with args. Methods declared
+// by scripts are found by the name of recv's type, or of the newest
+// type in its chain that has one, and the methods of the predeclared
+// types by how recv is represented.
+func (s *State) callMethod(recv Value, name string, args ...Value) Value {
+	if !recv.Valid() {
+		s.panicf("cannot call method %v of an invalid value", name)
+	}
+	for r, ok := recv, true; ok; r, ok = r.Prev() {
+		if f, ok := s.methods[r.desc.String()][name]; ok {
+			return Closure{Func: f}.call(s, &r, args)
+		}
+	}
+	if r, ok := s.CallBuiltin(recv, name, args...); ok {
+		return r
+	}
+	s.panicf("%v has no method %v", recv.desc, name)
+	panic("unreachable")
+}
+
+// boundMethod is the value of a method selected from a value without
+// being called. If typ is not empty, the method is the one attached to
+// the type of that name.
+type boundMethod struct {
+	recv Value
+	name string
+	typ  string
+}
+
+func (m boundMethod) Call(state *State, args []Value) Value {
+	if m.typ != "" {
+		return Closure{Func: state.methods[m.typ][m.name]}.call(state, &m.recv, args)
+	}
+	return state.callMethod(m.recv, m.name, args...)
+}
@@ -0,0 +1,82 @@
+package stele
+
+import (
+	"go/constant"
+	"strings"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	outer := NewFrame(nil, 2)
+	middle := NewFrame(outer, 1)
+	inner := NewFrame(middle, 3)
+
+	*outer.At(0, 1) = MakeBool(boolDesc, true)
+	if v := inner.At(2, 1); v.Interface() != true {
+		t.Fatalf("unexpected value: %#v", v)
+	}
+	if (inner.Outer() != middle) || (middle.Outer() != outer) || (outer.Outer() != nil) {
+		t.Fatal("unexpected outer frames")
+	}
+}
+
+func TestBlockEval(t *testing.T) {
+	intConst := func(v int64) Const { return Const{Val: constant.MakeInt64(v), T: Int} }
+
+	// let a int = 1
+	// {
+	//	let b int = 2
+	//	let (c, d) = (b, a)
+	//	a = c
+	//	d
+	// }
+	block := Block{
+		Stmts: []Stmt{
+			&Assign{ID: "b", Val: intConst(2), Ref: Ref{Local: true}},
+			&Assign{
+				IDs: []string{"c", "d"},
+				Val: Tuple{
+					Elems: []Expr{
+						Ident{ID: "b", T: Int, Ref: Ref{Local: true}},
+						Ident{ID: "a", T: Int},
+					},
+					T: TupleType(Int, Int),
+				},
+				Refs: []Ref{{Local: true, Slot: 1}, {Local: true, Slot: 2}},
+			},
+			&Assign{ID: "a", Val: Ident{ID: "c", T: Int, Ref: Ref{Local: true, Slot: 1}}},
+			Ident{ID: "d", T: Int, Ref: Ref{Local: true, Slot: 2}},
+		},
+		Slots: 3,
+		T:     Int,
+	}
+
+	state := NewState()
+	(&Assign{ID: "a", Val: intConst(1)}).Eval(state)
+	v := block.Eval(state)
+	if v.Interface() != int64(1) {
+		t.Fatalf("unexpected result: %#v", v)
+	}
+	if a := state.Globals["a"]; a.Interface() != int64(2) {
+		t.Fatalf("unexpected value of a: %#v", a)
+	}
+	if state.Frame != nil {
+		t.Fatal("frame was not popped")
+	}
+	if tr := state.Globals["true"]; tr.Interface() != true {
+		t.Fatalf("unexpected value of true: %#v", tr)
+	}
+}
+
+func TestCallMethodInvalid(t *testing.T) {
+	state := NewState()
+
+	var err error
+	func() {
+		defer catch(&err)
+		state.callMethod(Value{}, "add", MakeInt(intDesc, 1))
+	}()
+	if (err == nil) || !strings.HasSuffix(err.Error(), "cannot call method add of an invalid value") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,14 +1,50 @@
 package stele
 
-type State struct{}
-
+// Type is a set of functionality. A value may be used as a given
+// type if its own type has all of that type's features, regardless of
+// what either type is named.
 type Type struct {
 	Name     string
 	Features []Feature
+
+	// Oneof is the list of types that a value of a oneof type may
+	// satisfy. A oneof type is satisfied by anything that satisfies
+	// exactly one of them. Oneof is empty for other types.
+	Oneof []Type
+
+	// TypeParams is the type parameters of a generic type. If there
+	// are any, the first is always the unconstrained self parameter,
+	// which stands for the type of whatever value is being used as t.
+	TypeParams []TypeParam
+
+	// Origin is the generic type that t is an instantiation of, and
+	// Args is the type arguments that it was instantiated with. The
+	// features of an instantiation are only worked out when they are
+	// needed, which allows generic types to refer to themselves.
+	Origin *Type
+	Args   []Type
+
+	// Param is true if t is a reference to the type parameter named
+	// Name. Its features are those of the parameter's constraint.
+	Param bool
 }
 
+var (
+	// Any is the type that is satisfied by every type.
+	Any = Type{Name: "any"}
+
+	// Unit is the type of the unit value. It is the type of
+	// functions that do not return anything.
+	Unit = layoutType("unit")
+
+	// Bool is the type of true and false.
+	Bool = builtinType("bool", nil, operator("eq", self, self), operator("not", self))
+)
+
+// Valid returns true if t is a type at all. The zero Type is not
+// valid, and represents the lack of a known type.
 func (t Type) Valid() bool {
-	return t.Name != ""
+	return (t.Name != "") || (len(t.Features) > 0) || (len(t.Oneof) > 0) || (t.Origin != nil)
 }
 
 //go:generate go run golang.org/x/tools/cmd/stringer -type FeatureType
@@ -20,6 +56,11 @@ const (
 	LetFeature
 	FuncFeature
 	MemLayoutFeature
+
+	// EmbedFeature is the embedding of another type. The embedded type
+	// is the Feature's Return, and its features and oneof list are
+	// treated as though they belonged to the embedding type.
+	EmbedFeature
 )
 
 type Feature struct {
@@ -28,13 +69,15 @@ type Feature struct {
 
 	Args   []Type
 	Return Type
-}
 
-type Value struct {
-	Type Type
-	Val  any
-}
+	// MutRecv is true if a method may only be called on a mutable
+	// receiver.
+	MutRecv bool
+
+	// Mut is true if a function or method is mutable, meaning that it
+	// may have side effects. Only mutable functions may call it.
+	Mut bool
 
-func (v Value) Valid() bool {
-	return v.Val != nil
+	// TypeParams is the type parameters of a generic function.
+	TypeParams []TypeParam
 }
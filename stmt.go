@@ -1,5 +1,7 @@
 package stele
 
+import "deedles.dev/stele/scanner"
+
 // A Stmt is an executable piece of code.
 type Stmt interface {
 	// Eval evaluates the Stmt in the context of the given State and
@@ -20,26 +22,152 @@ type Expr interface {
 	Eval(*State) Value
 }
 
-// A Block represents a series of statements.
+// A Block represents a series of statements. If the last statement
+// is an expression, its value is the value of the Block and T is its
+// type. Otherwise, T is Unit.
+//
+// Pos holds the position of each statement. Slots is the number of
+// variables declared in the block, which is the size of the Frame
+// that it runs in.
 type Block struct {
 	Stmts []Stmt
+	Pos   []scanner.Pos
+	Slots int
+	T     Type
+}
+
+func (b Block) Type() Type {
+	return b.T
 }
 
 func (b Block) Eval(state *State) Value {
-	for _, stmt := range b.Stmts {
-		stmt.Eval(state)
+	outer := state.Frame
+	state.Frame = NewFrame(outer, b.Slots)
+	defer func() { state.Frame = outer }()
+
+	for i, stmt := range b.Stmts {
+		if i < len(b.Pos) {
+			state.Pos = b.Pos[i]
+		}
+		v := stmt.Eval(state)
+		if _, ok := stmt.(Expr); ok && (i == len(b.Stmts)-1) {
+			return v
+		}
 	}
-	return Value{}
+	return UnitValue
 }
 
 // An Assign is an assignment statement. It evaluates an expression
-// and assigns it to a variable.
+// and assigns it to a variable. If IDs is not empty, the expression is
+// a tuple that is destructured, with each of its elements assigned to
+// the variable in IDs at the same index, and ID is not used. If Recv
+// is not empty, the expression is instead assigned to the field ID of
+// the variable Recv. If Struct is not nil, it is instead assigned to
+// the field ID of the struct that Struct evaluates to, which is how a
+// field of a field is assigned to.
+//
+// Ref is where the variable that is assigned to, or Recv, is kept, and
+// Refs is where each of the variables in IDs is.
 type Assign struct {
-	Recv string
-	ID   string
-	Val  Expr
+	Recv   string
+	Struct Expr
+	ID     string
+	IDs    []string
+	Val    Expr
+	Ref    Ref
+	Refs   []Ref
 }
 
 func (a Assign) Eval(state *State) Value {
-	panic("Not implemented.")
+	if a.Struct != nil {
+		s := a.Struct.Eval(state).Interface().(*Struct)
+		s.SetField(a.ID, a.Val.Eval(state))
+		return Value{}
+	}
+
+	v := a.Val.Eval(state)
+	switch {
+	case len(a.IDs) > 0:
+		elems := v.Interface().([]Value)
+		for i, id := range a.IDs {
+			state.Set(id, a.Refs[i], elems[i])
+		}
+
+	case a.Recv != "":
+		recv := state.Get(a.Recv, a.Ref)
+		recv.Interface().(*Struct).SetField(a.ID, v)
+
+	default:
+		state.Set(a.ID, a.Ref, v)
+	}
+	return Value{}
+}
+
+// Return returns from the current function. Val is nil if no value
+// was given.
+type Return struct {
+	Val Expr
+}
+
+func (r Return) Eval(state *State) Value {
+	v := UnitValue
+	if r.Val != nil {
+		v = r.Val.Eval(state)
+	}
+	panic(returning{val: v})
+}
+
+// returning is panicked with by a Return to unwind to the function
+// call that it returns from.
+type returning struct {
+	val Value
+}
+
+// For is a for loop. Cond is nil if the loop only ends by breaking
+// out of it.
+type For struct {
+	Cond Expr
+	Body Block
+}
+
+func (f For) Eval(state *State) Value {
+	for (f.Cond == nil) || f.Cond.Eval(state).Bool() {
+		if f.iter(state) {
+			break
+		}
+	}
+	return Value{}
+}
+
+// iter runs a single iteration of the loop. It returns true if the
+// loop was broken out of.
+func (f For) iter(state *State) (brk bool) {
+	defer func() {
+		switch p := recover().(type) {
+		case nil:
+		case branching:
+			brk = p.tok == scanner.BREAK
+		default:
+			panic(p)
+		}
+	}()
+
+	f.Body.Eval(state)
+	return false
+}
+
+// Branch is a break or continue statement, depending on Tok. It
+// affects the innermost loop that it is in.
+type Branch struct {
+	Tok scanner.Type
+}
+
+func (b Branch) Eval(state *State) Value {
+	panic(branching{tok: b.Tok})
+}
+
+// branching is panicked with by a Branch to unwind to the loop that
+// it affects.
+type branching struct {
+	tok scanner.Type
 }
@@ -0,0 +1,63 @@
+package stele
+
+import "fmt"
+
+// TupleType returns the type of a tuple with elements of the given
+// types.
+func TupleType(elems ...Type) Type {
+	return Type{Features: []Feature{{
+		Type: MemLayoutFeature,
+		Name: "tuple",
+		Args: elems,
+	}}}
+}
+
+// Tuple returns the types of the elements of t if t is a tuple type or
+// embeds one.
+func (t Type) Tuple() ([]Type, bool) {
+	f, ok := t.Feature(MemLayoutFeature, "tuple")
+	if !ok {
+		return nil, false
+	}
+	return f.Args, true
+}
+
+func (f Feature) describeTuple() string {
+	return fmt.Sprintf("(%v)", typeList(f.Args))
+}
+
+// Tuple is a tuple literal. Desc is the description of T, which is
+// left nil until the types of the elements are known.
+type Tuple struct {
+	Elems []Expr
+	T     Type
+	Desc  *TypeDesc
+}
+
+func (t Tuple) Type() Type {
+	return t.T
+}
+
+func (t Tuple) Eval(state *State) Value {
+	elems := make([]Value, 0, len(t.Elems))
+	for _, e := range t.Elems {
+		elems = append(elems, e.Eval(state))
+	}
+	return ValueOf(state.desc(t.Desc, t.T), elems)
+}
+
+// TupleIndex is the selection of a single element of a tuple by a
+// constant index.
+type TupleIndex struct {
+	X     Expr
+	Index int
+	T     Type
+}
+
+func (i TupleIndex) Type() Type {
+	return i.T
+}
+
+func (i TupleIndex) Eval(state *State) Value {
+	return i.X.Eval(state).Interface().([]Value)[i.Index]
+}
@@ -0,0 +1,36 @@
+package stele
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTuple(t *testing.T) {
+	var (
+		intT    = layout("int")
+		stringT = layout("string")
+		pair    = TupleType(stringT, intT)
+		named   = Type{Name: "named", Features: []Feature{
+			embed(pair),
+			field("name", stringT),
+		}}
+	)
+
+	if s := pair.String(); s != "(string, int)" {
+		t.Fatalf("unexpected string: %v", s)
+	}
+
+	elems, ok := named.Tuple()
+	if !ok || !slices.EqualFunc(elems, []Type{stringT, intT}, func(a, b Type) bool { return a.Name == b.Name }) {
+		t.Fatalf("unexpected elements: %v", typeList(elems))
+	}
+	if !named.Satisfies(pair) {
+		t.Fatal("expected type with embedded tuple to satisfy tuple type")
+	}
+	if pair.Satisfies(TupleType(intT, stringT)) {
+		t.Fatal("expected tuples with elements in a different order not to match")
+	}
+	if _, ok := intT.Tuple(); ok {
+		t.Fatal("expected non-tuple type not to be a tuple")
+	}
+}
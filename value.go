@@ -0,0 +1,429 @@
+package stele
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Value is a run-time value. Values of the predeclared unit, bool and
+// numeric types, other than bigint and bigfloat, are held directly,
+// and everything else as a Go value, so that most arithmetic does not
+// allocate. The zero Value is not valid, and represents the lack of a
+// value, such as that of a variable that has not been initialized.
+//
+// Along with its type, a Value has the chain of types that it had
+// before it was assigned to variables of other types, which type
+// assertions search.
+type Value struct {
+	desc  *TypeDesc
+	kind  Kind
+	bits  uint64
+	ref   any
+	chain *link
+}
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type Kind
+
+// Kind is how a Value holds its value.
+type Kind uint8
+
+const (
+	InvalidKind Kind = iota
+	UnitKind
+	BoolKind
+	IntKind
+	UintKind
+	ByteKind
+	FloatKind
+
+	// RefKind is the kind of values that are held as Go values, such
+	// as strings, arrays and functions.
+	RefKind
+)
+
+// TypeDesc is the interned description of a type. Values refer to
+// their type through one so that they don't need to copy it, and so
+// that every value of a type in a program shares a single description.
+type TypeDesc struct {
+	t    Type
+	name string
+
+	// table is the Descs that d was interned in. It is nil for the
+	// predeclared types, which are shared by every table.
+	table *Descs
+}
+
+// Descs is a table of interned TypeDescs. Every program has its own,
+// which holds the descriptions of the types that it uses along with
+// what is cached about them, so that all of it is freed along with the
+// program. Types with the same name share a TypeDesc, which is the same
+// rule by which methods are found for the type of a value.
+type Descs struct {
+	m     sync.RWMutex
+	descs map[string]*TypeDesc
+
+	// satisfies caches whether the type of one TypeDesc satisfies the
+	// type of another.
+	satisfies map[[2]*TypeDesc]bool
+
+	// links holds the links of the chains of types that values have
+	// had, keyed by their contents.
+	links map[link]*link
+}
+
+// predeclaredDescs holds the TypeDescs of the predeclared types and
+// what is cached about them alone. It does not refer to anything from
+// any other table, so it does not keep any program from being freed.
+var predeclaredDescs = func() *Descs {
+	ds := NewDescs()
+	for _, d := range predeclared {
+		if d, ok := d.(TypeDecl); ok {
+			name := d.T.String()
+			ds.descs[name] = &TypeDesc{t: d.T, name: name}
+		}
+	}
+	return ds
+}()
+
+// NewDescs returns an empty table. The predeclared types are in every
+// table without having to be added to it.
+func NewDescs() *Descs {
+	return &Descs{
+		descs:     make(map[string]*TypeDesc),
+		satisfies: make(map[[2]*TypeDesc]bool),
+		links:     make(map[link]*link),
+	}
+}
+
+// Intern returns the TypeDesc of t.
+func (ds *Descs) Intern(t Type) *TypeDesc {
+	name := t.String()
+	if d, ok := predeclaredDescs.descs[name]; ok {
+		return d
+	}
+
+	ds.m.RLock()
+	d, ok := ds.descs[name]
+	ds.m.RUnlock()
+	if ok {
+		return d
+	}
+
+	ds.m.Lock()
+	defer ds.m.Unlock()
+	if d, ok := ds.descs[name]; ok {
+		return d
+	}
+	d = &TypeDesc{t: t, name: name, table: ds}
+	ds.descs[name] = d
+	return d
+}
+
+// tableOf returns the table that a cache entry involving d and o
+// belongs in, which is that of whichever is not predeclared.
+func tableOf(d, o *TypeDesc) *Descs {
+	switch {
+	case d.table != nil:
+		return d.table
+	case o.table != nil:
+		return o.table
+	}
+	return predeclaredDescs
+}
+
+// Type returns the type that d describes.
+func (d *TypeDesc) Type() Type {
+	return d.t
+}
+
+func (d *TypeDesc) String() string {
+	return d.name
+}
+
+// Satisfies returns true if the type that d describes satisfies the
+// type that o does.
+func (d *TypeDesc) Satisfies(o *TypeDesc) bool {
+	if d == o {
+		return true
+	}
+
+	table, key := tableOf(d, o), [2]*TypeDesc{d, o}
+	table.m.RLock()
+	ok, cached := table.satisfies[key]
+	table.m.RUnlock()
+	if cached {
+		return ok
+	}
+
+	ok = d.t.Satisfies(o.t)
+	table.m.Lock()
+	table.satisfies[key] = ok
+	table.m.Unlock()
+	return ok
+}
+
+// link is an entry in the chain of types that a value has had, newest
+// first. Links are interned, so that converting a value to a type
+// that another value has already been converted to from the same chain
+// does not allocate. A link is interned in the table of its own type
+// or, if that is predeclared, in that of the link before it.
+type link struct {
+	desc  *TypeDesc
+	prev  *link
+	table *Descs
+}
+
+func makeLink(desc *TypeDesc, prev *link) *link {
+	key := link{desc: desc, prev: prev, table: desc.table}
+	if (key.table == nil) && (prev != nil) {
+		key.table = prev.table
+	}
+	table := key.table
+	if table == nil {
+		table = predeclaredDescs
+	}
+
+	table.m.RLock()
+	l, ok := table.links[key]
+	table.m.RUnlock()
+	if ok {
+		return l
+	}
+
+	table.m.Lock()
+	defer table.m.Unlock()
+	if l, ok := table.links[key]; ok {
+		return l
+	}
+	l = &key
+	table.links[key] = l
+	return l
+}
+
+// without returns the chain l without any link to d.
+func (l *link) without(d *TypeDesc) *link {
+	switch {
+	case l == nil:
+		return nil
+	case l.desc == d:
+		return l.prev
+	}
+	prev := l.prev.without(d)
+	if prev == l.prev {
+		return l
+	}
+	return makeLink(l.desc, prev)
+}
+
+var (
+	unitDesc = predeclaredDesc(Unit)
+	boolDesc = predeclaredDesc(Bool)
+	intDesc  = predeclaredDesc(Int)
+	byteDesc = predeclaredDesc(Byte)
+
+	stringDesc = predeclaredDesc(String)
+	errorDesc  = predeclaredDesc(Error)
+)
+
+// predeclaredDesc returns the TypeDesc of the predeclared type t.
+func predeclaredDesc(t Type) *TypeDesc {
+	d, ok := predeclaredDescs.descs[t.String()]
+	if !ok {
+		panic(fmt.Errorf("%v is not predeclared", t))
+	}
+	return d
+}
+
+// UnitValue is the unit value. It is the result of functions that do
+// not return anything.
+var UnitValue = Value{desc: unitDesc, kind: UnitKind}
+
+// MakeBool returns a bool of type d.
+func MakeBool(d *TypeDesc, b bool) Value {
+	v := Value{desc: d, kind: BoolKind}
+	if b {
+		v.bits = 1
+	}
+	return v
+}
+
+// MakeInt returns an int of type d.
+func MakeInt(d *TypeDesc, i int64) Value {
+	return Value{desc: d, kind: IntKind, bits: uint64(i)}
+}
+
+// MakeUint returns a uint of type d.
+func MakeUint(d *TypeDesc, u uint64) Value {
+	return Value{desc: d, kind: UintKind, bits: u}
+}
+
+// MakeByte returns a byte of type d.
+func MakeByte(d *TypeDesc, b byte) Value {
+	return Value{desc: d, kind: ByteKind, bits: uint64(b)}
+}
+
+// MakeFloat returns a float of type d.
+func MakeFloat(d *TypeDesc, f float64) Value {
+	return Value{desc: d, kind: FloatKind, bits: math.Float64bits(f)}
+}
+
+// ValueOf returns a Value of type d that holds the Go value x. Values
+// of the kinds that are held directly are recognized by their Go type,
+// with struct{}{} being unit. Anything else, including nil, is held as
+// is.
+func ValueOf(d *TypeDesc, x any) Value {
+	switch x := x.(type) {
+	case struct{}:
+		return Value{desc: d, kind: UnitKind}
+	case bool:
+		return MakeBool(d, x)
+	case int64:
+		return MakeInt(d, x)
+	case uint64:
+		return MakeUint(d, x)
+	case byte:
+		return MakeByte(d, x)
+	case float64:
+		return MakeFloat(d, x)
+	}
+	return Value{desc: d, kind: RefKind, ref: x}
+}
+
+// Valid returns true if v is a value at all.
+func (v Value) Valid() bool {
+	return v.kind != InvalidKind
+}
+
+// Kind returns how v holds its value.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Desc returns the description of the type of v, or nil if v is not
+// valid.
+func (v Value) Desc() *TypeDesc {
+	return v.desc
+}
+
+// Type returns the type of v.
+func (v Value) Type() Type {
+	if v.desc == nil {
+		return Type{}
+	}
+	return v.desc.t
+}
+
+// Bool returns the value of a bool. It panics if v is not one.
+func (v Value) Bool() bool {
+	v.must(BoolKind)
+	return v.bits != 0
+}
+
+// Int returns the value of an int. It panics if v is not one.
+func (v Value) Int() int64 {
+	v.must(IntKind)
+	return int64(v.bits)
+}
+
+// Uint returns the value of a uint. It panics if v is not one.
+func (v Value) Uint() uint64 {
+	v.must(UintKind)
+	return v.bits
+}
+
+// Byte returns the value of a byte. It panics if v is not one.
+func (v Value) Byte() byte {
+	v.must(ByteKind)
+	return byte(v.bits)
+}
+
+// Float returns the value of a float. It panics if v is not one.
+func (v Value) Float() float64 {
+	v.must(FloatKind)
+	return math.Float64frombits(v.bits)
+}
+
+func (v Value) must(k Kind) {
+	if v.kind != k {
+		panic(fmt.Errorf("value of kind %v used as kind %v", v.kind, k))
+	}
+}
+
+// Interface returns v as a Go value. It is the inverse of ValueOf.
+// Values that are held directly are returned as the Go type that
+// ValueOf recognizes them by, and an invalid Value as nil.
+func (v Value) Interface() any {
+	switch v.kind {
+	case UnitKind:
+		return struct{}{}
+	case BoolKind:
+		return v.bits != 0
+	case IntKind:
+		return int64(v.bits)
+	case UintKind:
+		return v.bits
+	case ByteKind:
+		return byte(v.bits)
+	case FloatKind:
+		return math.Float64frombits(v.bits)
+	}
+	return v.ref
+}
+
+func (v Value) String() string {
+	return fmt.Sprint(v.Interface())
+}
+
+// Convert returns v as a value of type d, as it is when it is assigned
+// to a variable of that type. If v's type is not d, it is added to the
+// chain of types that v has had.
+//
+// A type only appears in the chain once, with any earlier appearance
+// being removed when it is added again, so a value that keeps being
+// assigned back and forth between variables does not keep growing. An
+// assertion can then not rewind to the earlier appearance.
+func (v Value) Convert(d *TypeDesc) Value {
+	if !v.Valid() || (v.desc == d) {
+		return v
+	}
+	v.chain = makeLink(v.desc, v.chain.without(d))
+	v.desc = d
+	return v
+}
+
+// Prev returns v as it was before it was last converted to a different
+// type. It returns false if v never was.
+func (v Value) Prev() (Value, bool) {
+	if v.chain == nil {
+		return Value{}, false
+	}
+	v.desc, v.chain = v.chain.desc, v.chain.prev
+	return v, true
+}
+
+// Assert searches v's type and then the chain of types that it has had,
+// newest first, for one that satisfies d. It returns v as it was when
+// it had that type, or false if there is no such type.
+func (v Value) Assert(d *TypeDesc) (Value, bool) {
+	for r, ok := v, v.Valid(); ok; r, ok = r.Prev() {
+		if r.desc.Satisfies(d) {
+			return r, true
+		}
+	}
+	return Value{}, false
+}
+
+// WithInt returns v, which must be an int, holding i instead. Like the
+// results of the methods of the predeclared types, it keeps v's type
+// and the chain of types that v has had.
+func (v Value) WithInt(i int64) Value {
+	return v.with(uint64(i))
+}
+
+// with returns a value of the same type and kind as v that holds bits.
+// It keeps the chain of types that v has had, so that the result of an
+// operation on a value can be asserted to the same types as it can.
+func (v Value) with(bits uint64) Value {
+	return Value{desc: v.desc, kind: v.kind, bits: bits, chain: v.chain}
+}
@@ -0,0 +1,140 @@
+package stele
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestValueOf(t *testing.T) {
+	tests := []struct {
+		name string
+		x    any
+		kind Kind
+	}{
+		{name: "Unit", x: struct{}{}, kind: UnitKind},
+		{name: "Bool", x: true, kind: BoolKind},
+		{name: "Int", x: int64(-3), kind: IntKind},
+		{name: "Uint", x: uint64(1 << 63), kind: UintKind},
+		{name: "Byte", x: byte('a'), kind: ByteKind},
+		{name: "Float", x: -0.5, kind: FloatKind},
+		{name: "String", x: "example", kind: RefKind},
+		{name: "BigInt", x: big.NewInt(3), kind: RefKind},
+		{name: "Nil", x: nil, kind: RefKind},
+	}
+
+	d := NewDescs().Intern(Any)
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := ValueOf(d, test.x)
+			if !v.Valid() || (v.Kind() != test.kind) || (v.Desc() != d) {
+				t.Fatalf("unexpected value: %#v", v)
+			}
+			if x := v.Interface(); x != test.x {
+				t.Fatalf("expected %#v but got %#v", test.x, x)
+			}
+		})
+	}
+}
+
+func TestValueValid(t *testing.T) {
+	var none Value
+	if none.Valid() || (none.Interface() != nil) || none.Type().Valid() {
+		t.Fatalf("zero value is valid: %#v", none)
+	}
+	if !UnitValue.Valid() || !UnitValue.Type().Satisfies(Unit) {
+		t.Fatalf("unexpected unit value: %#v", UnitValue)
+	}
+}
+
+func TestIntern(t *testing.T) {
+	ds := NewDescs()
+	if ds.Intern(Int) != ds.Intern(layoutType("int")) {
+		t.Fatal("types with the same name have different descriptions")
+	}
+	if ds.Intern(Int) == ds.Intern(Uint) {
+		t.Fatal("different types have the same description")
+	}
+	if d := ds.Intern(ArrayOf(Int)); d.String() != ArrayOf(Int).String() {
+		t.Fatalf("unexpected name: %v", d)
+	}
+
+	other := NewDescs()
+	if other.Intern(Int) != ds.Intern(Int) {
+		t.Fatal("tables have different descriptions of a predeclared type")
+	}
+	a := ds.Intern(Type{Name: "point", Features: []Feature{{Type: LetFeature, Name: "x", Return: Int}}})
+	b := other.Intern(Type{Name: "point", Features: []Feature{{Type: LetFeature, Name: "y", Return: Float}}})
+	if (a == b) || !a.Type().Satisfies(a.Type()) || b.Type().Satisfies(a.Type()) {
+		t.Fatal("types with the same name in different tables share a description")
+	}
+}
+
+func TestValueAllocs(t *testing.T) {
+	state := NewState()
+	x, y := MakeInt(intDesc, 1), MakeInt(intDesc, 2)
+	allocs := testing.AllocsPerRun(100, func() {
+		r, _ := state.CallBuiltin(x, "add", y)
+		r, _ = state.CallBuiltin(r, "lt", x)
+		if r.Bool() {
+			panic("unreachable")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("integer operations allocated %v times", allocs)
+	}
+}
+
+func TestValueChain(t *testing.T) {
+	ds := NewDescs()
+	a := ds.Intern(Type{Name: "a", Features: Int.Features})
+	b := ds.Intern(Type{Name: "b", Features: Int.Features})
+
+	v := MakeInt(intDesc, 3).Convert(a).Convert(ds.Intern(Any))
+	if v.Desc() != ds.Intern(Any) {
+		t.Fatalf("unexpected type: %v", v.Desc())
+	}
+
+	r, ok := v.Assert(a)
+	if !ok || (r.Desc() != a) || (r.Int() != 3) {
+		t.Fatalf("unexpected result of assertion: %#v", r)
+	}
+	if p, ok := r.Prev(); !ok || (p.Desc() != intDesc) {
+		t.Fatalf("assertion did not rewind the chain: %#v", p)
+	}
+	if _, ok := v.Assert(ds.Intern(String)); ok {
+		t.Fatal("assertion to a type that was never in the chain succeeded")
+	}
+
+	for i := 0; i < 1000; i++ {
+		v = v.Convert(a).Convert(b)
+	}
+	var chain []string
+	for r, ok := v.Prev(); ok; r, ok = r.Prev() {
+		chain = append(chain, r.Desc().String())
+	}
+	if want := []string{"a", "any", "int"}; !slices.Equal(chain, want) {
+		t.Fatalf("unexpected chain: %v", chain)
+	}
+
+	// After a -> c -> a, the entry for the first a is gone, so
+	// rewinding to c can not then get back to a.
+	c := ds.Intern(Type{Name: "c", Features: String.Features})
+	v = MakeInt(intDesc, 3).Convert(a).Convert(c).Convert(a)
+	if r, ok := v.Assert(a); !ok || (r != v) {
+		t.Fatalf("assertion to the current type did not return the value: %#v", r)
+	}
+	r, ok = v.Assert(c)
+	if !ok || (r.Desc() != c) {
+		t.Fatalf("unexpected result of assertion: %#v", r)
+	}
+	if p, ok := r.Prev(); !ok || (p.Desc() != intDesc) {
+		t.Fatalf("unexpected chain after assertion: %#v", p)
+	}
+	if r, ok := r.Assert(a); ok {
+		t.Fatalf("assertion rewound to a removed entry: %#v", r)
+	}
+}
@@ -0,0 +1,852 @@
+package vm
+
+import (
+	"fmt"
+	"slices"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/scanner"
+)
+
+// Program is a compiled script.
+type Program struct {
+	// Funcs is the script's functions, including methods and function
+	// literals. The first is the script's top-level code, which
+	// initializes its variables.
+	Funcs []*Func
+
+	// Consts, Names, Types and Shapes are the operands of instructions
+	// that are not integers, such as constant values and the names of
+	// fields and methods.
+	Consts []stele.Value
+	Names  []string
+	Types  []*stele.TypeDesc
+	Shapes []Shape
+
+	// Globals is the IDs of the script's top-level variables and
+	// functions, along with any predeclared identifiers that it uses.
+	Globals []string
+
+	// Methods holds the indices in Funcs of the script's methods, keyed
+	// by the name of their receiver type and then by their own.
+	Methods map[string]map[string]int
+
+	// Descs is the table that the program's types are interned in.
+	Descs *stele.Descs
+}
+
+// Shape is the type and field names of a struct literal.
+type Shape struct {
+	Fields []string
+	T      *stele.TypeDesc
+}
+
+// Func is a compiled function.
+type Func struct {
+	Name string
+	T    *stele.TypeDesc
+	Code []Instr
+
+	// Lines maps the function's instructions to the statements that
+	// they were compiled from, in order of PC.
+	Lines []Line
+
+	// Slots is the number of local slots that a call of the function
+	// needs. Its arguments are in the slots from Params on, and its
+	// receiver, if it is named, is in slot Recv. Otherwise, Recv is -1.
+	Slots  int
+	Params int
+	Recv   int
+
+	// Unit is true if the function returns unit, in which case the
+	// value of its body is discarded.
+	Unit bool
+
+	// Ret is the type that the function returns if it returns a result,
+	// in which case run-time errors that occur during a call of it are
+	// returned as values of that type. Otherwise, it is nil.
+	Ret *stele.TypeDesc
+
+	// Global is the index in the program's Globals of the function if
+	// it is a top-level function. Otherwise, it is -1.
+	Global int
+}
+
+// Pos returns the position of the statement that the instruction at
+// pc was compiled from.
+func (f *Func) Pos(pc int) scanner.Pos {
+	var pos scanner.Pos
+	for _, l := range f.Lines {
+		if l.PC > pc {
+			break
+		}
+		pos = l.Pos
+	}
+	return pos
+}
+
+// Compile compiles a checked script. It fails if the script contains
+// anything that can not be compiled yet.
+func Compile(script stele.Script) (prog *Program, err error) {
+	c := compiler{
+		prog:    &Program{Methods: make(map[string]map[string]int), Descs: script.Descs},
+		names:   make(map[string]int),
+		globals: make(map[string]int),
+		funcs:   make(map[string]int),
+	}
+	if c.prog.Descs == nil {
+		c.prog.Descs = stele.NewDescs()
+	}
+	c.state = &stele.State{Descs: c.prog.Descs}
+	defer func() {
+		if r := recover(); r != nil {
+			cerr, ok := r.(compileError)
+			if !ok {
+				panic(r)
+			}
+			prog, err = nil, cerr
+		}
+	}()
+
+	c.script(script)
+	return c.prog, nil
+}
+
+type compileError struct {
+	msg string
+}
+
+func (err compileError) Error() string {
+	return err.msg
+}
+
+type compiler struct {
+	prog *Program
+
+	// state evaluates constants, which interns their types in the
+	// program's table.
+	state *stele.State
+
+	names   map[string]int
+	globals map[string]int
+
+	// funcs holds the indices in prog.Funcs of the top-level functions
+	// by name.
+	funcs map[string]int
+
+	fn *funcState
+}
+
+// funcState is the state of the compilation of a single function.
+type funcState struct {
+	// outer is the function that the function is declared in, if it
+	// is a function literal.
+	outer *funcState
+	f     *Func
+
+	// blocks holds the blocks that the code being compiled is in,
+	// innermost last.
+	blocks []block
+
+	// envs holds the number of slots in each env that the function
+	// enters on top of that of the call itself, innermost last.
+	envs []int
+
+	// sp is the height of the stack, relative to the start of the call,
+	// after the code compiled so far.
+	sp    int
+	loops []*loop
+}
+
+// block is a block that is being compiled. Its slots start at first
+// in the env that was innermost when it started, which is env levels
+// deep.
+type block struct {
+	first int
+	env   int
+}
+
+// loop tracks the jumps to the end of a loop that is being compiled.
+// env is the number of envs that were entered when it started.
+type loop struct {
+	start  int
+	sp     int
+	env    int
+	breaks []int
+}
+
+func (c *compiler) errorf(format string, args ...any) {
+	panic(compileError{msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *compiler) script(script stele.Script) {
+	init := &Func{Name: "init", T: c.prog.Descs.Intern(stele.FuncType(nil, nil, stele.Unit)), Recv: -1, Unit: true, Global: -1}
+	c.prog.Funcs = append(c.prog.Funcs, init)
+
+	// Functions may be called before they are declared, so they all
+	// need to have an index before any of them are compiled.
+	var decls []*stele.Func
+	for _, d := range script.Decls {
+		f, ok := d.(stele.Func)
+		if !ok {
+			continue
+		}
+		decls = append(decls, &f)
+		i := c.declare(&f)
+		if f.Recv == nil {
+			c.funcs[f.Name] = i
+			c.prog.Funcs[i].Global = c.global(f.Name)
+			continue
+		}
+
+		for _, recv := range f.RecvTypes() {
+			if c.prog.Methods[recv] == nil {
+				c.prog.Methods[recv] = make(map[string]int)
+			}
+			c.prog.Methods[recv][f.Name] = i
+		}
+	}
+	for i, f := range decls {
+		c.function(f, c.prog.Funcs[i+1], nil)
+	}
+
+	c.fn = &funcState{f: init}
+	for _, d := range script.Decls {
+		if let, ok := d.(stele.Let); ok && (let.Assign != nil) {
+			c.assign(*let.Assign)
+		}
+	}
+	c.emit(OpUnit, 0, 0)
+	c.emit(OpReturn, 0, 0)
+}
+
+// declare adds an empty Func for f to the program and returns its
+// index.
+func (c *compiler) declare(f *stele.Func) int {
+	sig, _ := f.T.Func()
+	fn := &Func{
+		Name:   f.Name,
+		T:      c.prog.Descs.Intern(f.T),
+		Slots:  f.Slots,
+		Params: f.Slots - len(f.Params),
+		Recv:   -1,
+		Unit:   sig.Return.Satisfies(stele.Unit),
+		Global: -1,
+	}
+	if (f.Recv != nil) && (f.Recv.Name != "") {
+		fn.Recv = fn.Params - 1
+	}
+	if sig.Return.Fallible() {
+		fn.Ret = c.prog.Descs.Intern(sig.Return)
+	}
+	c.prog.Funcs = append(c.prog.Funcs, fn)
+	return len(c.prog.Funcs) - 1
+}
+
+// function compiles f into fn, which it was declared as, inside of the
+// function outer.
+func (c *compiler) function(f *stele.Func, fn *Func, outer *funcState) {
+	prev := c.fn
+	c.fn = &funcState{outer: outer, f: fn, blocks: []block{{}}}
+	defer func() { c.fn = prev }()
+
+	// Immutable parameters are copied in case they were passed from
+	// mutable variables.
+	copies := make([]int, 0, len(f.Immutable)+1)
+	if (fn.Recv >= 0) && !f.Recv.Mutable() {
+		copies = append(copies, fn.Recv)
+	}
+	for _, i := range f.Immutable {
+		copies = append(copies, fn.Params+i)
+	}
+	for _, slot := range copies {
+		c.emit(OpLoad, slot, 0)
+		c.emit(OpCopy, 0, 0)
+		c.emit(OpStore, slot, 0)
+	}
+
+	c.block(f.Body, true)
+	c.emit(OpReturn, 0, 0)
+}
+
+// emit adds an instruction to the function being compiled and returns
+// its PC.
+func (c *compiler) emit(op Op, a, b int) int {
+	fs := c.fn
+	fs.f.Code = append(fs.f.Code, Instr{Op: op, A: int32(a), B: int32(b)})
+	fs.sp += effect(op, a, b)
+	return len(fs.f.Code) - 1
+}
+
+// effect returns the change in the height of the stack that an
+// instruction causes.
+func effect(op Op, a, b int) int {
+	switch op {
+	case OpConst, OpUnit, OpZero, OpLoad, OpLoadOuter, OpLoadGlobal, OpClosure:
+		return 1
+	case OpPop:
+		return -a
+	case OpStore, OpStoreOuter, OpStoreGlobal, OpJumpFalse, OpAddInt, OpSubInt, OpMulInt, OpLtInt, OpLeInt, OpEqInt:
+		return -1
+	case OpMethod, OpCallMethod:
+		return -b
+	case OpCall:
+		return -a
+	case OpCallFunc:
+		return 1 - b
+	case OpReturn:
+		return -1
+	case OpSetField:
+		return -2
+	case OpTuple, OpArray:
+		return 1 - a
+	case OpStruct:
+		return 1 - b
+	case OpUnpack:
+		return a - 1
+	}
+	return 0
+}
+
+// patch makes the jump at pc jump to the next instruction.
+func (c *compiler) patch(pc int) {
+	c.fn.f.Code[pc].A = int32(len(c.fn.f.Code))
+}
+
+func (c *compiler) constant(v stele.Value) int {
+	c.prog.Consts = append(c.prog.Consts, v)
+	return len(c.prog.Consts) - 1
+}
+
+func (c *compiler) name(name string) int {
+	if i, ok := c.names[name]; ok {
+		return i
+	}
+	c.prog.Names = append(c.prog.Names, name)
+	c.names[name] = len(c.prog.Names) - 1
+	return c.names[name]
+}
+
+func (c *compiler) typ(t stele.Type) int {
+	c.prog.Types = append(c.prog.Types, c.prog.Descs.Intern(t))
+	return len(c.prog.Types) - 1
+}
+
+func (c *compiler) global(id string) int {
+	if i, ok := c.globals[id]; ok {
+		return i
+	}
+	c.prog.Globals = append(c.prog.Globals, id)
+	c.globals[id] = len(c.prog.Globals) - 1
+	return c.globals[id]
+}
+
+// local returns the number of functions out from the one being
+// compiled and the slot in that function of a local variable.
+func (c *compiler) local(ref stele.Ref) (int, int) {
+	depth := ref.Depth
+	for fs, n := c.fn, 0; fs != nil; fs = fs.outer {
+		if depth < len(fs.blocks) {
+			b := fs.blocks[len(fs.blocks)-1-depth]
+			return n + len(fs.envs) - b.env, b.first + ref.Slot
+		}
+		depth -= len(fs.blocks)
+
+		// Closures are created in the innermost env of the function
+		// that they are in, which is the outer env of their calls.
+		n += len(fs.envs) + 1
+	}
+	c.errorf("variable at depth %v is outside of every function", ref.Depth)
+	return 0, 0
+}
+
+func (c *compiler) load(id string, ref stele.Ref) {
+	if !ref.Local {
+		c.emit(OpLoadGlobal, c.global(id), 0)
+		return
+	}
+	n, slot := c.local(ref)
+	if n == 0 {
+		c.emit(OpLoad, slot, 0)
+		return
+	}
+	c.emit(OpLoadOuter, n, slot)
+}
+
+func (c *compiler) store(id string, ref stele.Ref) {
+	if !ref.Local {
+		c.emit(OpStoreGlobal, c.global(id), 0)
+		return
+	}
+	n, slot := c.local(ref)
+	if n == 0 {
+		c.emit(OpStore, slot, 0)
+		return
+	}
+	c.emit(OpStoreOuter, n, slot)
+}
+
+// block compiles a block. If value is true, the block's value is left
+// on the stack.
+//
+// Every block in a function gets slots of its own, so that closures
+// that are created in a block keep seeing its variables after it has
+// ended. A loop's body reuses its slots in each iteration, unless
+// closures may be created in it, in which case each iteration enters
+// an env of its own so that they each see their own variables.
+func (c *compiler) block(b stele.Block, value bool) {
+	fs := c.fn
+	slots := &fs.f.Slots
+	if len(fs.envs) > 0 {
+		slots = &fs.envs[len(fs.envs)-1]
+	}
+	fs.blocks = append(fs.blocks, block{first: *slots, env: len(fs.envs)})
+	*slots += b.Slots
+	defer func() { fs.blocks = fs.blocks[:len(fs.blocks)-1] }()
+
+	pushed := false
+	for i, stmt := range b.Stmts {
+		if i < len(b.Pos) {
+			fs.f.Lines = append(fs.f.Lines, Line{PC: len(fs.f.Code), Pos: b.Pos[i]})
+		}
+
+		x, ok := stmt.(stele.Expr)
+		if !ok {
+			c.stmt(stmt)
+			continue
+		}
+		c.expr(x)
+		if value && (i == len(b.Stmts)-1) {
+			pushed = true
+			continue
+		}
+		c.emit(OpPop, 1, 0)
+	}
+	if value && !pushed {
+		c.emit(OpUnit, 0, 0)
+	}
+}
+
+func (c *compiler) stmt(stmt stele.Stmt) {
+	switch stmt := stmt.(type) {
+	case *stele.Assign:
+		c.assign(*stmt)
+
+	case stele.Assign:
+		c.assign(stmt)
+
+	case stele.Return:
+		if stmt.Val == nil {
+			c.emit(OpUnit, 0, 0)
+		} else {
+			c.expr(stmt.Val)
+		}
+		c.emit(OpReturn, 0, 0)
+
+	case stele.For:
+		c.forStmt(stmt)
+
+	case stele.Branch:
+		fs := c.fn
+		l := fs.loops[len(fs.loops)-1]
+		sp := fs.sp
+		if fs.sp > l.sp {
+			c.emit(OpPop, fs.sp-l.sp, 0)
+		}
+		for i := l.env; i < len(fs.envs); i++ {
+			c.emit(OpLeave, 0, 0)
+		}
+		if stmt.Tok == scanner.BREAK {
+			l.breaks = append(l.breaks, c.emit(OpJump, 0, 0))
+		} else {
+			c.emit(OpJump, l.start, 0)
+		}
+		// The code after a branch is never reached, but it is compiled
+		// as though it is.
+		fs.sp = sp
+
+	default:
+		c.errorf("%T can not be compiled yet", stmt)
+	}
+}
+
+func (c *compiler) assign(a stele.Assign) {
+	switch {
+	case len(a.IDs) > 0:
+		c.expr(a.Val)
+		c.emit(OpUnpack, len(a.IDs), 0)
+		for i := len(a.IDs) - 1; i >= 0; i-- {
+			c.store(a.IDs[i], a.Refs[i])
+		}
+
+	case a.Recv != "":
+		c.load(a.Recv, a.Ref)
+		c.expr(a.Val)
+		c.emit(OpSetField, c.name(a.ID), 0)
+
+	case a.Struct != nil:
+		c.expr(a.Struct)
+		c.expr(a.Val)
+		c.emit(OpSetField, c.name(a.ID), 0)
+
+	default:
+		c.expr(a.Val)
+		c.store(a.ID, a.Ref)
+	}
+}
+
+func (c *compiler) forStmt(x stele.For) {
+	fs := c.fn
+	l := &loop{start: len(fs.f.Code), sp: fs.sp, env: len(fs.envs)}
+	fs.loops = append(fs.loops, l)
+	defer func() { fs.loops = fs.loops[:len(fs.loops)-1] }()
+
+	end := -1
+	if x.Cond != nil {
+		c.expr(x.Cond)
+		end = c.emit(OpJumpFalse, 0, 0)
+	}
+	if closes(x.Body) {
+		enter := c.emit(OpEnter, 0, 0)
+		fs.envs = append(fs.envs, 0)
+		c.block(x.Body, false)
+		fs.f.Code[enter].A = int32(fs.envs[len(fs.envs)-1])
+		fs.envs = fs.envs[:len(fs.envs)-1]
+		c.emit(OpLeave, 0, 0)
+	} else {
+		c.block(x.Body, false)
+	}
+	c.emit(OpJump, l.start, 0)
+
+	if end >= 0 {
+		c.patch(end)
+	}
+	for _, pc := range l.breaks {
+		c.patch(pc)
+	}
+}
+
+// closes returns true if x, a part of a function, contains a function
+// literal, which could capture the variables of the blocks that it is
+// in.
+func closes(x any) bool {
+	switch x := x.(type) {
+	case stele.FuncLit:
+		return true
+	case stele.Block:
+		return slices.ContainsFunc(x.Stmts, func(s stele.Stmt) bool { return closes(s) })
+	case *stele.Assign:
+		return closes(x.Val)
+	case stele.Assign:
+		return closes(x.Val)
+	case stele.Return:
+		return closes(x.Val)
+	case stele.For:
+		return closes(x.Cond) || closes(x.Body)
+	case stele.If:
+		return closes(x.Cond) || closes(x.Body) || closes(x.Else)
+	case stele.Switch:
+		return closes(x.Tag) || slices.ContainsFunc(x.Cases, func(c stele.Case) bool {
+			return closes(c.Value) || closes(c.Body)
+		})
+	case stele.Call:
+		return closes(x.Func) || slices.ContainsFunc(x.Args, func(e stele.Expr) bool { return closes(e) })
+	case stele.Tuple:
+		return slices.ContainsFunc(x.Elems, func(e stele.Expr) bool { return closes(e) })
+	case stele.ArrayLit:
+		return slices.ContainsFunc(x.Elems, func(e stele.Expr) bool { return closes(e) })
+	case stele.StructLit:
+		return slices.ContainsFunc(x.Fields, func(f stele.FieldInit) bool { return closes(f.Val) })
+	case stele.Binary:
+		return closes(x.X) || closes(x.Y)
+	case stele.Index:
+		return closes(x.X) || closes(x.Index)
+	case stele.Unary:
+		return closes(x.X)
+	case stele.Selector:
+		return closes(x.X)
+	case stele.TupleIndex:
+		return closes(x.X)
+	case stele.TypeAssert:
+		return closes(x.X)
+	case stele.Narrow:
+		return closes(x.X)
+	case stele.Try:
+		return closes(x.X)
+	case stele.Convert:
+		return closes(x.X)
+	case stele.Copy:
+		return closes(x.X)
+	}
+	return false
+}
+
+func (c *compiler) expr(x stele.Expr) {
+	switch x := x.(type) {
+	case stele.Const:
+		c.emit(OpConst, c.constant(x.Eval(c.state)), 0)
+
+	case stele.Zero:
+		c.emit(OpZero, c.typ(x.T), 0)
+
+	case stele.Ident:
+		c.load(x.ID, x.Ref)
+
+	case stele.Block:
+		c.block(x, true)
+
+	case stele.Tuple:
+		for _, e := range x.Elems {
+			c.expr(e)
+		}
+		c.emit(OpTuple, len(x.Elems), c.typ(x.T))
+
+	case stele.TupleIndex:
+		c.expr(x.X)
+		c.emit(OpTupleIndex, x.Index, 0)
+
+	case stele.Index:
+		c.expr(x.X)
+		c.expr(x.Index)
+		c.emit(OpMethod, c.name("get"), 1)
+
+	case stele.Call:
+		c.call(x)
+
+	case stele.Selector:
+		c.expr(x.X)
+		if x.Recv != "" {
+			c.emit(OpBind, c.prog.Methods[x.Recv][x.Name], c.typ(x.T))
+			break
+		}
+		c.emit(OpSelect, c.name(x.Name), c.typ(x.T))
+
+	case stele.If:
+		c.ifExpr(x)
+
+	case stele.Switch:
+		c.switchExpr(x)
+
+	case stele.TypeAssert:
+		c.expr(x.X)
+		c.emit(OpAssert, c.typ(x.Assert), 0)
+
+	case stele.Narrow:
+		c.expr(x.X)
+		c.emit(OpNarrow, c.typ(x.T), 0)
+
+	case stele.Copy:
+		c.expr(x.X)
+		c.emit(OpCopy, 0, 0)
+
+	case stele.Try:
+		// An error is returned, leaving the stack as it was for the
+		// code that narrows anything else.
+		c.expr(x.X)
+		try := c.emit(OpTry, 0, 0)
+		c.emit(OpConvert, c.typ(x.Ret), 0)
+		c.emit(OpReturn, 0, 0)
+		c.fn.sp++
+		c.patch(try)
+		c.emit(OpNarrow, c.typ(x.T), 0)
+
+	case stele.Convert:
+		c.expr(x.X)
+		c.emit(OpConvert, c.typ(x.T), 0)
+
+	case stele.Binary:
+		c.binary(x)
+
+	case stele.Unary:
+		c.expr(x.X)
+		if (x.Method == "not") && isLayout(x.X.Type(), "bool") {
+			c.emit(OpNot, 0, 0)
+			break
+		}
+		c.emit(OpMethod, c.name(x.Method), 0)
+
+	case stele.StructLit:
+		shape := Shape{Fields: make([]string, 0, len(x.Fields)), T: c.prog.Descs.Intern(x.T)}
+		for _, f := range x.Fields {
+			c.expr(f.Val)
+			shape.Fields = append(shape.Fields, f.Name)
+		}
+		c.prog.Shapes = append(c.prog.Shapes, shape)
+		c.emit(OpStruct, len(c.prog.Shapes)-1, len(shape.Fields))
+
+	case stele.ArrayLit:
+		for _, e := range x.Elems {
+			c.expr(e)
+		}
+		c.emit(OpArray, len(x.Elems), c.typ(x.T))
+
+	case stele.FuncLit:
+		i := c.declare(&x.Func)
+		c.function(&x.Func, c.prog.Funcs[i], c.fn)
+		c.emit(OpClosure, i, 0)
+
+	default:
+		c.errorf("%T can not be compiled yet", x)
+	}
+}
+
+func (c *compiler) call(call stele.Call) {
+	// Calls of top-level functions and of methods are resolved when
+	// they are compiled, unless a field of the same name as the method
+	// would be selected instead.
+	switch fun := call.Func.(type) {
+	case stele.Ident:
+		i, ok := c.funcs[fun.ID]
+		if !ok || fun.Ref.Local {
+			break
+		}
+		for _, arg := range call.Args {
+			c.expr(arg)
+		}
+		c.emit(OpCallFunc, i, len(call.Args))
+		return
+
+	case stele.Selector:
+		if _, ok := fun.X.Type().Feature(stele.LetFeature, fun.Name); ok && (fun.Recv == "") {
+			break
+		}
+		c.expr(fun.X)
+		for _, arg := range call.Args {
+			c.expr(arg)
+		}
+		if fun.Recv != "" {
+			c.emit(OpCallMethod, c.prog.Methods[fun.Recv][fun.Name], len(call.Args))
+			return
+		}
+		c.emit(OpMethod, c.name(fun.Name), len(call.Args))
+		return
+	}
+
+	c.expr(call.Func)
+	for _, arg := range call.Args {
+		c.expr(arg)
+	}
+	c.emit(OpCall, len(call.Args), 0)
+}
+
+func (c *compiler) ifExpr(x stele.If) {
+	c.expr(x.Cond)
+	next := c.emit(OpJumpFalse, 0, 0)
+	c.block(x.Body, true)
+	end := c.emit(OpJump, 0, 0)
+	c.fn.sp--
+
+	c.patch(next)
+	if x.Else != nil {
+		c.expr(x.Else)
+	} else {
+		c.emit(OpUnit, 0, 0)
+	}
+	c.patch(end)
+}
+
+func (c *compiler) switchExpr(x stele.Switch) {
+	fs := c.fn
+	tag := -1
+	if x.Tag != nil {
+		tag = fs.f.Slots
+		fs.f.Slots++
+		c.expr(x.Tag)
+		c.emit(OpStore, tag, 0)
+	}
+
+	var ends []int
+	for _, sc := range x.Cases {
+		next := -1
+		switch {
+		case sc.Else:
+		case sc.Assert.Valid():
+			c.emit(OpLoad, tag, 0)
+			c.emit(OpAssert, c.typ(sc.Assert), 0)
+			next = c.emit(OpJumpFalse, 0, 0)
+		case tag < 0:
+			c.expr(sc.Value)
+			next = c.emit(OpJumpFalse, 0, 0)
+		default:
+			op := sc.Op
+			if op == 0 {
+				op = scanner.EQUAL
+			}
+			name, swap, negate, _ := stele.OperatorMethod(op, false)
+			c.emit(OpLoad, tag, 0)
+			c.expr(sc.Value)
+			c.operate(name, swap, negate, x.Tag.Type())
+			next = c.emit(OpJumpFalse, 0, 0)
+		}
+
+		c.block(sc.Body, true)
+		ends = append(ends, c.emit(OpJump, 0, 0))
+		fs.sp--
+		if next >= 0 {
+			c.patch(next)
+		}
+	}
+
+	c.emit(OpUnit, 0, 0)
+	for _, pc := range ends {
+		c.patch(pc)
+	}
+}
+
+func (c *compiler) binary(x stele.Binary) {
+	c.expr(x.X)
+	switch x.Op {
+	case scanner.AND, scanner.OR:
+		op := OpJumpFalseOr
+		if x.Op == scanner.OR {
+			op = OpJumpTrueOr
+		}
+		end := c.emit(op, 0, 0)
+		c.fn.sp--
+		c.expr(x.Y)
+		c.patch(end)
+		return
+	}
+
+	c.expr(x.Y)
+	c.operate(x.Method, x.Swap, x.Negate, x.X.Type())
+}
+
+// intOps are the instructions that the methods of int are compiled to
+// when they are called on something that is statically known to be an
+// int.
+var intOps = map[string]Op{
+	"add": OpAddInt,
+	"sub": OpSubInt,
+	"mul": OpMulInt,
+	"lt":  OpLtInt,
+	"le":  OpLeInt,
+	"eq":  OpEqInt,
+}
+
+// operate compiles the call of the method name that an operator maps
+// to on the top two values of the stack, which are of type t.
+func (c *compiler) operate(name string, swap, negate bool, t stele.Type) {
+	if swap {
+		c.emit(OpSwap, 0, 0)
+	}
+	if op, ok := intOps[name]; ok && isLayout(t, "int") {
+		c.emit(op, 0, 0)
+	} else {
+		c.emit(OpMethod, c.name(name), 1)
+	}
+	if negate {
+		c.emit(OpNot, 0, 0)
+	}
+}
+
+// isLayout returns true if t is the predeclared type with the given
+// memory layout, rather than something that just embeds it, which may
+// have methods of its own.
+func isLayout(t stele.Type, layout string) bool {
+	if (t.Name != layout) || (len(t.Features) == 0) {
+		return false
+	}
+	f := t.Features[0]
+	return (f.Type == stele.MemLayoutFeature) && (f.Name == layout)
+}
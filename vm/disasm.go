@@ -0,0 +1,75 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Disassemble writes a human-readable listing of the instructions of
+// each of prog's functions to w. Operands that refer to something other
+// than a number, such as a constant or a jump target, are annotated
+// with what they refer to.
+func (prog *Program) Disassemble(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for i, fn := range prog.Funcs {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		name := fn.Name
+		if name == "" {
+			name = "func literal"
+		}
+		fmt.Fprintf(tw, "%v: %v %v (%v slots)\n", i, name, fn.T, fn.Slots)
+
+		lines := fn.Lines
+		for pc, in := range fn.Code {
+			for (len(lines) > 0) && (lines[0].PC == pc) {
+				fmt.Fprintf(tw, "  (%v)\n", lines[0].Pos)
+				lines = lines[1:]
+			}
+			op := strings.ToLower(in.Op.String())
+			if operands := prog.operands(in); operands != "" {
+				op += "\t" + operands
+			}
+			fmt.Fprintf(tw, "  %04d  %v\n", pc, op)
+		}
+	}
+	return tw.Flush()
+}
+
+// operands returns the operands of in as they are shown by
+// Disassemble.
+func (prog *Program) operands(in Instr) string {
+	a, b := int(in.A), int(in.B)
+	switch in.Op {
+	case OpConst:
+		return fmt.Sprintf("%v\t; %v", a, prog.Consts[a])
+	case OpZero, OpAssert, OpNarrow, OpConvert:
+		return fmt.Sprintf("%v\t; %v", a, prog.Types[a])
+	case OpPop, OpEnter, OpLoad, OpStore, OpJump, OpJumpFalse, OpJumpFalseOr, OpJumpTrueOr, OpTry, OpCall, OpTupleIndex, OpUnpack:
+		return fmt.Sprint(a)
+	case OpLoadOuter, OpStoreOuter:
+		return fmt.Sprintf("%v %v", a, b)
+	case OpLoadGlobal, OpStoreGlobal:
+		return fmt.Sprintf("%v\t; %v", a, prog.Globals[a])
+	case OpMethod:
+		return fmt.Sprintf("%v %v\t; %v", a, b, prog.Names[a])
+	case OpCallFunc, OpCallMethod:
+		return fmt.Sprintf("%v %v\t; %v", a, b, prog.Funcs[a].Name)
+	case OpClosure:
+		return fmt.Sprint(a)
+	case OpSelect:
+		return fmt.Sprintf("%v %v\t; %v", a, b, prog.Names[a])
+	case OpBind:
+		return fmt.Sprintf("%v %v\t; %v", a, b, prog.Funcs[a].Name)
+	case OpSetField:
+		return fmt.Sprintf("%v\t; %v", a, prog.Names[a])
+	case OpTuple, OpArray:
+		return fmt.Sprintf("%v %v\t; %v", a, b, prog.Types[b])
+	case OpStruct:
+		return fmt.Sprintf("%v %v\t; %v", a, b, prog.Shapes[a].T)
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+// Package vm compiles checked scripts into bytecode and runs them.
+//
+// The bytecode is for a stack machine. Each function call has a flat
+// array of local slots that holds the variables of every block in the
+// function, so variables are found by a single index instead of by
+// walking the frames of each block, and instructions are dispatched by
+// a single switch instead of through the [stele.Expr] interface.
+package vm
+
+import "deedles.dev/stele/scanner"
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type Op -trimprefix Op
+
+// Op is the operation of an instruction. The comment on each
+// describes its operands, A and B, and its effect on the stack.
+type Op uint8
+
+const (
+	OpInvalid Op = iota
+
+	OpConst // push Consts[A]
+	OpUnit  // push unit
+	OpZero  // push the zero value of Types[A]
+	OpPop   // pop A values
+
+	OpLoad        // push slot A
+	OpStore       // pop into slot A
+	OpLoadOuter   // push slot B of the function A levels out
+	OpStoreOuter  // pop into slot B of the function A levels out
+	OpLoadGlobal  // push global A
+	OpStoreGlobal // pop into global A
+
+	OpJump        // jump to A
+	OpJumpFalse   // pop a bool and jump to A if it is false
+	OpJumpFalseOr // jump to A if the top of the stack is false, otherwise pop it
+	OpJumpTrueOr  // jump to A if the top of the stack is true, otherwise pop it
+	OpTry         // assert the top of the stack to error if it is one, otherwise jump to A
+
+	OpAddInt // pop two ints and push their sum
+	OpSubInt // pop two ints and push their difference
+	OpMulInt // pop two ints and push their product
+	OpLtInt  // pop two ints and push whether the first is less
+	OpLeInt  // pop two ints and push whether the first is less or equal
+	OpEqInt  // pop two ints and push whether they are equal
+	OpNot    // pop a bool and push its negation
+	OpSwap   // swap the top two values
+
+	OpMethod     // call method Names[A] with B arguments on the value below them
+	OpCall       // call the function below the top A values with them
+	OpCallFunc   // call Funcs[A] with the top B values
+	OpCallMethod // call Funcs[A] with B arguments on the value below them
+	OpClosure    // push a closure of Funcs[A] in the current env
+	OpEnter      // enter a new env with A slots inside of the current one
+	OpLeave      // leave the current env for its outer one
+	OpReturn     // return the top value from the function
+
+	OpSelect     // pop a value and push its field or method Names[A]
+	OpBind       // pop a value and push Funcs[A] bound to it as a Types[B]
+	OpSetField   // pop a value, then set field Names[A] of the value below it to it
+	OpTuple      // pop A values and push a tuple of Types[B]
+	OpTupleIndex // pop a tuple and push its element A
+	OpUnpack     // pop a tuple and push its A elements
+	OpArray      // pop A values and push an array of Types[B]
+	OpStruct     // pop the values of the B fields of Shapes[A] and push a struct
+	OpAssert     // pop a value and push whether it can be asserted to Types[A]
+	OpNarrow     // pop a value and push it asserted to Types[A]
+	OpConvert    // pop a value and push it converted to Types[A]
+	OpCopy       // pop a value and push a copy of it
+)
+
+// Instr is a single instruction.
+type Instr struct {
+	Op   Op
+	A, B int32
+}
+
+// Line records that the instructions of a function from PC onwards
+// were compiled from the statement at Pos.
+type Line struct {
+	PC  int
+	Pos scanner.Pos
+}
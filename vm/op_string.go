@@ -0,0 +1,67 @@
+// Code generated by "stringer -type Op -trimprefix Op"; DO NOT EDIT.
+
+package vm
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[OpInvalid-0]
+	_ = x[OpConst-1]
+	_ = x[OpUnit-2]
+	_ = x[OpZero-3]
+	_ = x[OpPop-4]
+	_ = x[OpLoad-5]
+	_ = x[OpStore-6]
+	_ = x[OpLoadOuter-7]
+	_ = x[OpStoreOuter-8]
+	_ = x[OpLoadGlobal-9]
+	_ = x[OpStoreGlobal-10]
+	_ = x[OpJump-11]
+	_ = x[OpJumpFalse-12]
+	_ = x[OpJumpFalseOr-13]
+	_ = x[OpJumpTrueOr-14]
+	_ = x[OpTry-15]
+	_ = x[OpAddInt-16]
+	_ = x[OpSubInt-17]
+	_ = x[OpMulInt-18]
+	_ = x[OpLtInt-19]
+	_ = x[OpLeInt-20]
+	_ = x[OpEqInt-21]
+	_ = x[OpNot-22]
+	_ = x[OpSwap-23]
+	_ = x[OpMethod-24]
+	_ = x[OpCall-25]
+	_ = x[OpCallFunc-26]
+	_ = x[OpCallMethod-27]
+	_ = x[OpClosure-28]
+	_ = x[OpEnter-29]
+	_ = x[OpLeave-30]
+	_ = x[OpReturn-31]
+	_ = x[OpSelect-32]
+	_ = x[OpBind-33]
+	_ = x[OpSetField-34]
+	_ = x[OpTuple-35]
+	_ = x[OpTupleIndex-36]
+	_ = x[OpUnpack-37]
+	_ = x[OpArray-38]
+	_ = x[OpStruct-39]
+	_ = x[OpAssert-40]
+	_ = x[OpNarrow-41]
+	_ = x[OpConvert-42]
+	_ = x[OpCopy-43]
+}
+
+const _Op_name = "InvalidConstUnitZeroPopLoadStoreLoadOuterStoreOuterLoadGlobalStoreGlobalJumpJumpFalseJumpFalseOrJumpTrueOrTryAddIntSubIntMulIntLtIntLeIntEqIntNotSwapMethodCallCallFuncCallMethodClosureEnterLeaveReturnSelectBindSetFieldTupleTupleIndexUnpackArrayStructAssertNarrowConvertCopy"
+
+var _Op_index = [...]uint16{0, 7, 12, 16, 20, 23, 27, 32, 41, 51, 61, 72, 76, 85, 96, 106, 109, 115, 121, 127, 132, 137, 142, 145, 149, 155, 159, 167, 177, 184, 189, 194, 200, 206, 210, 218, 223, 233, 239, 244, 250, 256, 262, 269, 273}
+
+func (i Op) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Op_index)-1 {
+		return "Op(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Op_name[_Op_index[idx]:_Op_index[idx+1]]
+}
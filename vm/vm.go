@@ -0,0 +1,505 @@
+package vm
+
+import (
+	"fmt"
+
+	"deedles.dev/stele"
+)
+
+// VM runs a compiled program.
+type VM struct {
+	prog    *Program
+	globals []stele.Value
+	stack   []stele.Value
+	frames  []frame
+
+	// state is used to call the methods of the predeclared types and
+	// functions that were not compiled by the VM.
+	state *stele.State
+
+	// boolDesc is the type of the results of comparisons, and
+	// errorDesc is the type of run-time errors that are returned as
+	// values.
+	boolDesc  *stele.TypeDesc
+	errorDesc *stele.TypeDesc
+}
+
+// frame is the state of a single function call.
+type frame struct {
+	fn  *Func
+	pc  int
+	env *env
+
+	// base is the height of the stack when the call started.
+	base int
+}
+
+// env holds the local slots of a function call. Closures keep the env
+// of the call that they were created in, which is the outer env of
+// their own calls.
+type env struct {
+	slots []stele.Value
+	outer *env
+}
+
+// New returns a VM that is ready to run prog. Its top-level functions
+// are defined, but the rest of its globals are not initialized until
+// Run is called.
+func New(prog *Program) *VM {
+	vm := VM{
+		prog:    prog,
+		globals: make([]stele.Value, len(prog.Globals)),
+		state:   stele.NewState(),
+
+		boolDesc:  prog.Descs.Intern(stele.Bool),
+		errorDesc: prog.Descs.Intern(stele.Error),
+	}
+	vm.state.Descs = prog.Descs
+	for i, id := range prog.Globals {
+		vm.globals[i] = vm.state.Globals[id]
+	}
+	for i, fn := range prog.Funcs {
+		if fn.Global >= 0 {
+			vm.globals[fn.Global] = stele.ValueOf(fn.T, stele.Function(&closure{vm: &vm, fn: i}))
+		}
+	}
+	return &vm
+}
+
+// Run runs the program's top-level code, initializing its variables.
+// If the code fails, the returned error is a *stele.RuntimeError.
+func (vm *VM) Run() error {
+	_, err := vm.protect(0, nil)
+	return err
+}
+
+// Call calls the program's top-level function name with args. If the
+// call fails, the returned error is a *stele.RuntimeError.
+func (vm *VM) Call(name string, args ...stele.Value) (stele.Value, error) {
+	for i, fn := range vm.prog.Funcs {
+		if (fn.Global >= 0) && (vm.prog.Globals[fn.Global] == name) {
+			return vm.protect(i, args)
+		}
+	}
+	return stele.Value{}, fmt.Errorf("%v is not a function", name)
+}
+
+// protect calls the function at index fn in the program's Funcs,
+// recovering from any runtime error that it causes.
+func (vm *VM) protect(fn int, args []stele.Value) (r stele.Value, err error) {
+	frames, stack := len(vm.frames), len(vm.stack)
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		rerr, ok := p.(*stele.RuntimeError)
+		if !ok {
+			panic(p)
+		}
+
+		// The error is normally given its position by exec, but one
+		// raised outside of it is reported at the statement that the
+		// innermost call was running.
+		if !rerr.Pos.IsValid() && (len(vm.frames) > frames) {
+			f := vm.frames[len(vm.frames)-1]
+			rerr.Pos = f.fn.Pos(f.pc - 1)
+		}
+		vm.frames, vm.stack = vm.frames[:frames], vm.stack[:stack]
+		err = rerr
+	}()
+
+	return vm.call(fn, nil, nil, args), nil
+}
+
+// call calls a function and runs it until it returns.
+func (vm *VM) call(fn int, outer *env, recv *stele.Value, args []stele.Value) stele.Value {
+	vm.enter(vm.prog.Funcs[fn], outer, recv, args, len(vm.stack))
+	vm.run(len(vm.frames) - 1)
+	r := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return r
+}
+
+// enter pushes a frame for a call of fn. base is the height that the
+// stack returns to when the call returns. As with the tree-walking
+// interpreter, there may be at most stele.MaxCallDepth frames.
+func (vm *VM) enter(fn *Func, outer *env, recv *stele.Value, args []stele.Value, base int) {
+	if len(vm.frames) >= stele.MaxCallDepth {
+		vm.panicf("stack overflow: more than %v calls", stele.MaxCallDepth)
+	}
+	e := env{slots: make([]stele.Value, fn.Slots), outer: outer}
+	copy(e.slots[fn.Params:], args)
+	if (recv != nil) && (fn.Recv >= 0) {
+		e.slots[fn.Recv] = *recv
+	}
+	vm.frames = append(vm.frames, frame{fn: fn, env: &e, base: base})
+}
+
+func (vm *VM) panicf(format string, args ...any) {
+	panic(&stele.RuntimeError{Msg: fmt.Sprintf(format, args...)})
+}
+
+func (vm *VM) push(v stele.Value) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *VM) pop() stele.Value {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+func (vm *VM) top() *stele.Value {
+	return &vm.stack[len(vm.stack)-1]
+}
+
+// run runs the VM until the number of frames drops to stop, leaving
+// the value returned by the last call on the stack.
+func (vm *VM) run(stop int) {
+	for !vm.exec(stop) {
+	}
+}
+
+// exec runs the VM as run does. If a run-time error occurs during a
+// call of a function that returns a result, that call returns the error
+// instead, and exec returns false if the VM should then keep running.
+func (vm *VM) exec(stop int) (done bool) {
+	fr := &vm.frames[len(vm.frames)-1]
+	code, slots := fr.fn.Code, fr.env.slots
+	pc, cur := fr.pc, len(vm.frames)-1
+
+	// Calls and returns save and restore the PC, but an error needs
+	// to know where it happened, too. Only the frame that is running
+	// here is saved, as the error may have come through a call made by
+	// a Function, whose frames are above it and have already been
+	// saved by the exec that ran them.
+	defer func() {
+		if done {
+			return
+		}
+		p := recover()
+		if p == nil {
+			return
+		}
+		if rerr, ok := p.(*stele.RuntimeError); ok && !rerr.Pos.IsValid() {
+			rerr.Pos = vm.frames[cur].fn.Pos(pc - 1)
+		}
+		if len(vm.frames) <= stop {
+			panic(p)
+		}
+		vm.frames[cur].pc = pc
+		done = vm.catch(p, stop)
+	}()
+
+	// load restores the state of the current frame after a call or a
+	// return.
+	load := func() {
+		cur = len(vm.frames) - 1
+		fr = &vm.frames[cur]
+		code, slots, pc = fr.fn.Code, fr.env.slots, fr.pc
+	}
+
+	for {
+		in := code[pc]
+		pc++
+
+		switch in.Op {
+		case OpConst:
+			vm.push(vm.prog.Consts[in.A])
+		case OpUnit:
+			vm.push(stele.UnitValue)
+		case OpZero:
+			vm.push(vm.prog.Descs.Zero(vm.prog.Types[in.A].Type()))
+		case OpPop:
+			vm.stack = vm.stack[:len(vm.stack)-int(in.A)]
+
+		case OpLoad:
+			vm.push(slots[in.A])
+		case OpStore:
+			slots[in.A] = vm.pop()
+		case OpLoadOuter:
+			vm.push(fr.env.at(int(in.A)).slots[in.B])
+		case OpStoreOuter:
+			fr.env.at(int(in.A)).slots[in.B] = vm.pop()
+		case OpLoadGlobal:
+			v := vm.globals[in.A]
+			if !v.Valid() {
+				vm.panicf("%v has not been initialized", vm.prog.Globals[in.A])
+			}
+			vm.push(v)
+		case OpStoreGlobal:
+			vm.globals[in.A] = vm.pop()
+
+		case OpJump:
+			pc = int(in.A)
+		case OpJumpFalse:
+			if !vm.pop().Bool() {
+				pc = int(in.A)
+			}
+		case OpJumpFalseOr:
+			if !vm.top().Bool() {
+				pc = int(in.A)
+				break
+			}
+			vm.pop()
+		case OpJumpTrueOr:
+			if vm.top().Bool() {
+				pc = int(in.A)
+				break
+			}
+			vm.pop()
+		case OpTry:
+			x := vm.top()
+			err, ok := x.Assert(vm.errorDesc)
+			if !ok {
+				pc = int(in.A)
+				break
+			}
+			*x = err
+
+		case OpAddInt:
+			y := vm.pop()
+			x := vm.top()
+			*x = x.WithInt(x.Int() + y.Int())
+		case OpSubInt:
+			y := vm.pop()
+			x := vm.top()
+			*x = x.WithInt(x.Int() - y.Int())
+		case OpMulInt:
+			y := vm.pop()
+			x := vm.top()
+			*x = x.WithInt(x.Int() * y.Int())
+		case OpLtInt:
+			y := vm.pop()
+			x := vm.top()
+			*x = stele.MakeBool(vm.boolDesc, x.Int() < y.Int())
+		case OpLeInt:
+			y := vm.pop()
+			x := vm.top()
+			*x = stele.MakeBool(vm.boolDesc, x.Int() <= y.Int())
+		case OpEqInt:
+			y := vm.pop()
+			x := vm.top()
+			*x = stele.MakeBool(vm.boolDesc, x.Int() == y.Int())
+		case OpNot:
+			x := vm.top()
+			*x = stele.MakeBool(x.Desc(), !x.Bool())
+		case OpSwap:
+			n := len(vm.stack)
+			vm.stack[n-1], vm.stack[n-2] = vm.stack[n-2], vm.stack[n-1]
+
+		case OpMethod:
+			n := len(vm.stack) - int(in.B)
+			recv, args := vm.stack[n-1], vm.stack[n:]
+			name := vm.prog.Names[in.A]
+			if m, recv, ok := vm.method(recv, name); ok {
+				fr.pc = pc
+				vm.enter(vm.prog.Funcs[m], nil, &recv, args, n-1)
+				vm.stack = vm.stack[:n-1]
+				load()
+				break
+			}
+			r, ok := vm.state.CallBuiltin(recv, name, args...)
+			if !ok {
+				vm.panicf("%v has no method %v", recv.Desc(), name)
+			}
+			vm.stack = append(vm.stack[:n-1], r)
+
+		case OpCall:
+			n := len(vm.stack) - int(in.A)
+			f, args := vm.stack[n-1], vm.stack[n:]
+			if c, ok := f.Interface().(*closure); ok && (c.vm == vm) {
+				fr.pc = pc
+				vm.enter(vm.prog.Funcs[c.fn], c.env, nil, args, n-1)
+				vm.stack = vm.stack[:n-1]
+				load()
+				break
+			}
+			r := f.Interface().(stele.Function).Call(vm.state, append([]stele.Value(nil), args...))
+			vm.stack = append(vm.stack[:n-1], r)
+
+		case OpCallFunc:
+			n := len(vm.stack) - int(in.B)
+			fr.pc = pc
+			vm.enter(vm.prog.Funcs[in.A], nil, nil, vm.stack[n:], n)
+			vm.stack = vm.stack[:n]
+			load()
+
+		case OpCallMethod:
+			n := len(vm.stack) - int(in.B)
+			fr.pc = pc
+			vm.enter(vm.prog.Funcs[in.A], nil, &vm.stack[n-1], vm.stack[n:], n-1)
+			vm.stack = vm.stack[:n-1]
+			load()
+
+		case OpClosure:
+			fn := vm.prog.Funcs[in.A]
+			vm.push(stele.ValueOf(fn.T, stele.Function(&closure{vm: vm, fn: int(in.A), env: fr.env})))
+		case OpEnter:
+			fr.env = &env{slots: make([]stele.Value, in.A), outer: fr.env}
+			slots = fr.env.slots
+		case OpLeave:
+			fr.env = fr.env.outer
+			slots = fr.env.slots
+
+		case OpReturn:
+			r := vm.pop()
+			if fr.fn.Unit {
+				r = stele.UnitValue
+			}
+			vm.stack = append(vm.stack[:fr.base], r)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) <= stop {
+				return true
+			}
+			load()
+
+		case OpSelect:
+			x := vm.top()
+			if fields, ok := x.Interface().(*stele.Struct); ok {
+				if v, ok := fields.Field(vm.prog.Names[in.A]); ok {
+					*x = v
+					break
+				}
+			}
+			m := boundMethod{vm: vm, recv: *x, name: vm.prog.Names[in.A]}
+			*x = stele.ValueOf(vm.prog.Types[in.B], stele.Function(m))
+		case OpBind:
+			x := vm.top()
+			m := boundMethod{vm: vm, recv: *x, fn: int(in.A)}
+			*x = stele.ValueOf(vm.prog.Types[in.B], stele.Function(m))
+		case OpSetField:
+			v := vm.pop()
+			recv := vm.pop()
+			recv.Interface().(*stele.Struct).SetField(vm.prog.Names[in.A], v)
+		case OpTuple:
+			n := len(vm.stack) - int(in.A)
+			elems := append([]stele.Value(nil), vm.stack[n:]...)
+			vm.stack = append(vm.stack[:n], stele.ValueOf(vm.prog.Types[in.B], elems))
+		case OpTupleIndex:
+			x := vm.top()
+			*x = x.Interface().([]stele.Value)[in.A]
+		case OpUnpack:
+			elems := vm.pop().Interface().([]stele.Value)
+			vm.stack = append(vm.stack, elems[:in.A]...)
+		case OpArray:
+			n := len(vm.stack) - int(in.A)
+			elems := append([]stele.Value(nil), vm.stack[n:]...)
+			vm.stack = append(vm.stack[:n], stele.ValueOf(vm.prog.Types[in.B], &stele.Slice{Elems: elems}))
+		case OpStruct:
+			shape := vm.prog.Shapes[in.A]
+			n := len(vm.stack) - len(shape.Fields)
+			fields := make(map[string]stele.Value, len(shape.Fields))
+			for i, name := range shape.Fields {
+				fields[name] = vm.stack[n+i]
+			}
+			vm.stack = append(vm.stack[:n], stele.ValueOf(shape.T, stele.NewStruct(fields)))
+		case OpAssert:
+			x := vm.top()
+			_, ok := x.Assert(vm.prog.Types[in.A])
+			*x = stele.MakeBool(vm.boolDesc, ok)
+		case OpNarrow:
+			x := vm.top()
+			r, ok := x.Assert(vm.prog.Types[in.A])
+			if !ok {
+				vm.panicf("%v can not be asserted to %v", x.Desc(), vm.prog.Types[in.A])
+			}
+			*x = r
+		case OpConvert:
+			x := vm.top()
+			*x = x.Convert(vm.prog.Types[in.A])
+		case OpCopy:
+			x := vm.top()
+			*x = x.Copy()
+
+		default:
+			panic(fmt.Errorf("invalid instruction %v at %v in %v", in.Op, pc-1, fr.fn.Name))
+		}
+	}
+}
+
+// catch handles p, which was recovered from a panic while running
+// calls above stop. If it is a run-time error in a call of a function
+// that returns a result, the call returns the error, and catch
+// returns true if that leaves no calls above stop. Otherwise, it panics
+// with p again.
+func (vm *VM) catch(p any, stop int) bool {
+	rerr, ok := p.(*stele.RuntimeError)
+	if !ok {
+		panic(p)
+	}
+	i := len(vm.frames) - 1
+	for (i >= stop) && (vm.frames[i].fn.Ret == nil) {
+		i--
+	}
+	if i < stop {
+		panic(p)
+	}
+
+	call := vm.frames[i]
+	vm.stack = append(vm.stack[:call.base], stele.ValueOf(vm.errorDesc, rerr).Convert(call.fn.Ret))
+	vm.frames = vm.frames[:i]
+	return len(vm.frames) <= stop
+}
+
+// at returns the env n functions out from e.
+func (e *env) at(n int) *env {
+	for i := 0; i < n; i++ {
+		e = e.outer
+	}
+	return e
+}
+
+// method returns the index of the method name that the program
+// declares for the type of recv, or for the newest type in its chain
+// that has one, along with recv as it was when it had that type.
+func (vm *VM) method(recv stele.Value, name string) (int, stele.Value, bool) {
+	if !recv.Valid() {
+		vm.panicf("cannot call method %v of an invalid value", name)
+	}
+	if len(vm.prog.Methods) == 0 {
+		return 0, recv, false
+	}
+	for r, ok := recv, true; ok; r, ok = r.Prev() {
+		if m, ok := vm.prog.Methods[r.Desc().String()][name]; ok {
+			return m, r, true
+		}
+	}
+	return 0, recv, false
+}
+
+// closure is the value of a function that was compiled by the VM.
+type closure struct {
+	vm  *VM
+	fn  int
+	env *env
+}
+
+func (c *closure) Call(state *stele.State, args []stele.Value) stele.Value {
+	return c.vm.call(c.fn, c.env, nil, args)
+}
+
+// boundMethod is the value of a method selected from a value without
+// being called. If name is empty, the method is Funcs[fn], which was
+// found from the static type of the value.
+type boundMethod struct {
+	vm   *VM
+	recv stele.Value
+	name string
+	fn   int
+}
+
+func (m boundMethod) Call(state *stele.State, args []stele.Value) stele.Value {
+	if m.name == "" {
+		return m.vm.call(m.fn, nil, &m.recv, args)
+	}
+	if i, recv, ok := m.vm.method(m.recv, m.name); ok {
+		return m.vm.call(i, nil, &recv, args)
+	}
+	r, ok := m.vm.state.CallBuiltin(m.recv, m.name, args...)
+	if !ok {
+		m.vm.panicf("%v has no method %v", m.recv.Desc(), m.name)
+	}
+	return r
+}
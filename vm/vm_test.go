@@ -0,0 +1,739 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"deedles.dev/stele"
+	"deedles.dev/stele/check"
+	"deedles.dev/stele/parser"
+)
+
+func load(t testing.TB, src string) (stele.Script, *Program) {
+	t.Helper()
+
+	file, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := check.File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := Compile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script, prog
+}
+
+func TestVM(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want any
+	}{
+		{
+			name: "Arithmetic",
+			src:  `func main() int { (7 + 3) * 2 - 10 / 3 % 2 }`,
+			want: int64(19),
+		},
+		{
+			name: "BigInt",
+			src:  `func main() bigint { let x bigint = 1 << 70; x + 1 }`,
+			want: "1180591620717411303425",
+		},
+		{
+			name: "Logic",
+			src:  `func main() bool { (1 < 2) && (2 >= 2) && !(3 <= 2) && ((1 == 2) || (1 != 2)) }`,
+			want: true,
+		},
+		{
+			name: "Globals",
+			src: `let a int = 2
+let b, c int = (a * 3, 4)
+func main() int { a + b + c }`,
+			want: int64(12),
+		},
+		{
+			name: "Switch",
+			src: `func classify(n int) string {
+	switch n {
+		< 0 { "negative" }
+		0 { "zero" }
+		else { "positive" }
+	}
+}
+func main() string { classify(-2) + classify(0) + classify(5) }`,
+			want: "negativezeropositive",
+		},
+		{
+			name: "Loop",
+			src: `func main() int {
+	let sum int = 0
+	let i int = 0
+	for {
+		i += 1
+		if i > 10 { break }
+		if i % 2 == 0 { continue }
+		sum += i
+	}
+	sum
+}`,
+			want: int64(25),
+		},
+		{
+			name: "Return",
+			src: `func first(n int) int {
+	let i int = 0
+	for {
+		if i * i > n { return i }
+		i += 1
+	}
+	0
+}
+func main() int { first(20) }`,
+			want: int64(5),
+		},
+		{
+			name: "Closure",
+			src: `func apply(x int, f -> (int) int) int { f(x) }
+func main() int {
+	let n int = 10
+	let add = -> (x int) int { x + n }
+	3 |> apply(add)
+}`,
+			want: int64(13),
+		},
+		{
+			name: "Struct",
+			src: `type point {
+	let x, y int
+	func sum() int
+}
+func (p point) sum() int { p.x + p.y }
+func main() int {
+	let p = &point{x = 3}
+	p.y = 4
+	let s = p.sum
+	s() + (p.x, p.y)[0]
+}`,
+			want: int64(10),
+		},
+		{
+			name: "Array",
+			src: `func main() int {
+	let a array[int] = [1, 2, 3]
+	a[1] += 10
+	a.append(4)
+	a[1] + a.len()
+}`,
+			want: int64(16),
+		},
+		{
+			name: "Assert",
+			src: `type named {
+	func name() string
+}
+
+type point {
+	let x, y int
+	func name() string
+}
+
+func (p point) name() string { "point" }
+
+func describe(v! any) string {
+	if v.(named) { v.name() } else { "unknown" }
+}
+
+let none int = 0
+
+func size(v! any) int {
+	switch v {
+		.(int) { v + 1 }
+		.(string) { v.len() }
+		else { none }
+	}
+}
+
+func main() string {
+	let p! point = &point{x = 1}
+	let n! named = p
+	let a! any = n
+	let s int = size(41) + size("abc") + size(p)
+	describe(a) + describe(3) + if s == 45 { "45" } else { "?" }
+}`,
+			want: "pointunknown45",
+		},
+		{
+			name: "MethodScope",
+			src: `type example int
+type other int
+
+func (e example) double() int { e + e }
+func [T int] (v T) double() int { v * 3 }
+
+func main() int {
+	let v int = 3
+	let o other = 4
+	let f = example(v).double
+	f() * 100 + o.double() + other(v).double()
+}`,
+			want: int64(621),
+		},
+		{
+			name: "CopyOnAssign",
+			src: `type point {
+	let x, y int
+}
+
+func bump(p point) { p.x = p.x + 1 }
+
+func alias(a array[int], b! array[int]) mut int {
+	a[0] = 9
+	b[0]
+}
+
+func main() mut int {
+	let frozen! = &point{x = 1}
+	let m = frozen
+	m.x = 10
+	bump(m)
+	bump(frozen)
+
+	let a! = [frozen]
+	let b = a
+	let e = b[0]
+	e.x = 100
+
+	let arr array[int] = [1, 2]
+	let fixed! = arr
+	arr[0] = 5
+	arr.append(3)
+	let before = alias(arr, arr)
+
+	frozen.x + m.x * 10 + a[0].x * 1000 + b[0].x * 10000 + fixed[0] * 10000000 + fixed.len() * 100000000 + before * 1000000000 + arr[0] * 10000000000
+}`,
+			want: int64(95211001111),
+		},
+		{
+			name: "Closures",
+			src: `func counter() -> () mut int {
+	let n int = 0
+	-> () mut int { n += 1; n }
+}
+
+func apply(f -> (int) int, x int) int { f(x) }
+
+func main() mut int {
+	let base int = 10
+	let add = -> (x int) int { x + base }
+	let c = counter()
+	c()
+	c()
+	let d = counter()
+	d()
+	base = 100
+
+	let fs array[-> () int]
+	let i int = 0
+	for i < 3 {
+		let x int = i
+		fs.append(-> () int { x + i })
+		i += 1
+	}
+
+	c() * 10000 + d() * 1000 + apply(add, 1) + fs[0]() * 100 + fs[1]() * 10 + fs[2]()
+}`,
+			want: int64(32446),
+		},
+		{
+			name: "LoopClosures",
+			src: `func main() mut int {
+	let fs array[-> () int]
+	let i int = 0
+	for i < 6 {
+		let x int = i * 2
+		i += 1
+		if i == 2 { continue }
+		let inc = -> () mut int { x += 1; x }
+		inc()
+		fs.append(-> () int { x })
+		if i == 5 { break }
+	}
+
+	let gs array[-> () int]
+	let j int = 0
+	for j < 2 {
+		let k int = 0
+		for k < 2 {
+			let y int = j * 2 + k
+			gs.append(-> () int { y })
+			k += 1
+		}
+		j += 1
+	}
+
+	i * 100000000 + fs[0]() * 10000000 + fs[1]() * 1000000 + fs[2]() * 100000 + fs[3]() * 10000 +
+		gs[0]() * 1000 + gs[1]() * 100 + gs[2]() * 10 + gs[3]()
+}`,
+			want: int64(515790123),
+		},
+		{
+			name: "RecursiveZero",
+			src: `type tree {
+	let v int
+	let children array[tree]
+	let parent -> () tree
+}
+
+type [T, E any] box {
+	let val E
+}
+
+func main() mut int {
+	let t tree
+	let c array[tree] = t.children
+	c.append(&tree{v = 2})
+	let p tree = t.parent()
+	let b box[box[int]]
+	(b.val.val + 3) * 1000 + c.len() * 10 + c[0].v * 100 + t.v + p.v + p.children.len() + 1
+}`,
+			want: int64(3211),
+		},
+		{
+			name: "GenericLocal",
+			src: `func [T numeric] sum(a array[T]) T {
+	let s T = a[0]
+	let i int = 1
+	for i < a.len() {
+		s = s + a[i]
+		i += 1
+	}
+	s
+}
+
+func main() mut float {
+	let a array[float]
+	a.append(1.5)
+	a.append(2.25)
+	let b array[int]
+	b.append(3)
+	b.append(4)
+	let n float = if sum(b) == 7 { 7.0 } else { 0.0 }
+	sum(a) * 100.0 + n
+}`,
+			want: 382.0,
+		},
+		{
+			name: "NumericConstraints",
+			src: `func double(v numeric) numeric { v * 2 }
+func [T numeric] triple(v T) T { v * 3 }
+func [T anyint] half(v T) T { v / 2 }
+
+func main() float {
+	let f float = 1.5
+	let r! = double(f)
+	let d float = if r.(float) { r } else { 0.0 }
+	let n bigint = triple(bigint(7))
+	let i int = 9
+	d * 100.0 + triple(f) + if n == 21 { 1000.0 } else { 0.0 } + if half(i) == 4 { 10000.0 } else { 0.0 }
+}`,
+			want: 11304.5,
+		},
+		{
+			name: "CompoundIndex",
+			src: `type inner {
+	let x int
+}
+
+type outer {
+	let in inner
+	let a array[int]
+}
+
+let calls int = 0
+
+func idx() mut int {
+	calls += 1
+	1
+}
+
+func arr() mut array[int] {
+	calls += 10
+	[1, 2, 3]
+}
+
+func main() mut int {
+	let o outer
+	o.a = [1, 2, 3]
+	o.a[idx()] += 5
+	arr()[idx()] *= 2
+	o.in.x = 5
+	o.in.x += 2
+	o.a[1] * 1000 + calls * 10 + o.in.x
+}`,
+			want: int64(7127),
+		},
+		{
+			name: "StackOverflow",
+			src: `func down(n int) result[int] {
+	let r int = down(n + 1)?
+	r
+}
+
+func main() string {
+	let r! result[int] = down(0)
+	switch r {
+		.(int) { "no error" }
+		.(error) { r.error() }
+	}
+}`,
+			want: "stack overflow: more than 10000 calls",
+		},
+		{
+			name: "NaN",
+			src: `func bit(b bool, n int) int { if b { n } else { 0 } }
+
+func main() int {
+	let zero float = 0.0
+	let one float = 1.0
+	let nan float = zero / zero
+	let i int = 3
+	bit(nan >= one, 1) + bit(nan <= one, 2) + bit(nan < one, 4) + bit(nan > one, 8) + bit(nan == nan, 16) + bit(nan != nan, 32) + bit(one <= one, 64) + bit(one >= zero, 128) + bit(i <= 3, 256) + bit(i >= 4, 512)
+}`,
+			want: int64(480),
+		},
+		{
+			name: "Errors",
+			src: `type failure {
+	let msg string
+	func error() string
+}
+
+func (f failure) error() string { f.msg }
+
+func get(a array[int], i int) result[int] { a[i] }
+
+func at(a array[int], i int) int { a[i] }
+
+func check(n int) result[int] {
+	if n > 2 {
+		return &failure{msg = "too big"}
+	}
+	n
+}
+
+func sum(a array[int]) result[int] {
+	let total int = get(a, 0)? + at(a, 1)
+	check(total)? * 10
+}
+
+func describe(r! result[int]) string {
+	switch r {
+		.(int) { "ok" }
+		.(failure) { "failure: " + r.error() }
+		.(error) { r.error() }
+	}
+}
+
+func main() string {
+	let a array[int] = [1, 2]
+	let b array[int] = [1]
+	let c array[int] = [1, 1]
+	describe(sum(a)) + ";" + describe(sum(b)) + ";" + describe(sum(c))
+}`,
+			want: "failure: too big;index 1 out of range with length 1;ok",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			script, prog := load(t, test.src)
+
+			vm := New(prog)
+			if err := vm.Run(); err != nil {
+				t.Fatal(err)
+			}
+			r, err := vm.Call("main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := r.Interface()
+			if s, ok := got.(fmt.Stringer); ok {
+				got = s.String()
+			}
+			if got != test.want {
+				t.Fatalf("unexpected result: %#v", r.Interface())
+			}
+
+			// The VM should agree with the tree-walking interpreter.
+			state := stele.NewState()
+			if err := script.Run(state); err != nil {
+				t.Fatal(err)
+			}
+			want, err := script.Call(state, "main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want.String() != r.String() {
+				t.Fatalf("interpreter returned %v but VM returned %v", want, r)
+			}
+		})
+	}
+}
+
+func TestRot13(t *testing.T) {
+	src, err := os.ReadFile("../testdata/rot13.stele")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, prog := load(t, string(src))
+	vm := New(prog)
+	if err := vm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := vm.Call("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []byte
+	for _, v := range r.Interface().(*stele.Slice).Elems {
+		out = append(out, v.Byte())
+	}
+	if string(out) != "Guvf vf na rknzcyr." {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	_, prog := load(t, `func get(a array[int], i int) int {
+	let n int = 1
+	a[i] + n
+}
+func main() int {
+	let a array[int] = [1]
+	get(a, 0) + get(a, 3)
+}`)
+
+	vm := New(prog)
+	_, err := vm.Call("main")
+	var rerr *stele.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a runtime error but got %v", err)
+	}
+	if (rerr.Pos.Line != 3) || !strings.Contains(rerr.Msg, "out of range") {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+	if (len(vm.frames) != 0) || (len(vm.stack) != 0) {
+		t.Fatalf("VM was not reset: %v frames, %v values", len(vm.frames), len(vm.stack))
+	}
+}
+
+func TestBoundMethodError(t *testing.T) {
+	script, prog := load(t, `type list {
+	let elems array[int]
+	func get(int) int
+}
+func (l list) get(i int) int {
+	let e int = l.elems[i]
+	e + 1
+}
+func main() int {
+	let l = &list{elems = [1]}
+	let get = l.get
+	get(0) + get(3)
+}`)
+
+	vm := New(prog)
+	_, err := vm.Call("main")
+	var rerr *stele.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a runtime error but got %v", err)
+	}
+	if (len(vm.frames) != 0) || (len(vm.stack) != 0) {
+		t.Fatalf("VM was not reset: %v frames, %v values", len(vm.frames), len(vm.stack))
+	}
+
+	// The error should be reported where the method failed, as the
+	// tree-walking interpreter reports it, not where it was called.
+	state := stele.NewState()
+	if err := script.Run(state); err != nil {
+		t.Fatal(err)
+	}
+	_, want := script.Call(state, "main")
+	if (want == nil) || (err.Error() != want.Error()) {
+		t.Fatalf("interpreter failed with %v but VM failed with %v", want, err)
+	}
+	if rerr.Pos.Line != 6 {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	_, prog := load(t, `func down(n int) int {
+	down(n + 1) + 1
+}
+func main() int { down(0) }`)
+
+	vm := New(prog)
+	_, err := vm.Call("main")
+	var rerr *stele.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a runtime error but got %v", err)
+	}
+	if (rerr.Pos.Line != 2) || !strings.Contains(rerr.Msg, "stack overflow") {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+	if (len(vm.frames) != 0) || (len(vm.stack) != 0) {
+		t.Fatalf("VM was not reset: %v frames, %v values", len(vm.frames), len(vm.stack))
+	}
+}
+
+func TestMethodInvalid(t *testing.T) {
+	_, prog := load(t, `func main() int { 1 }`)
+	vm := New(prog)
+
+	var rerr *stele.RuntimeError
+	func() {
+		defer func() { rerr, _ = recover().(*stele.RuntimeError) }()
+		vm.method(stele.Value{}, "add")
+	}()
+	if (rerr == nil) || (rerr.Msg != "cannot call method add of an invalid value") {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+}
+
+func TestErrorResult(t *testing.T) {
+	_, prog := load(t, `func get(a array[int], i int) int { a[i] }
+func main() result[int] {
+	let a array[int] = [1]
+	let n int = 0
+	for n < 3 {
+		n += get(a, n)
+	}
+	n
+}`)
+
+	vm := New(prog)
+	r, err := vm.Call("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rerr, ok := r.Interface().(*stele.RuntimeError)
+	if !ok {
+		t.Fatalf("expected an error but got %v", r)
+	}
+	if (rerr.Pos.Line != 1) || !strings.Contains(rerr.Msg, "out of range") {
+		t.Fatalf("unexpected error: %v", rerr)
+	}
+	if (len(vm.frames) != 0) || (len(vm.stack) != 0) {
+		t.Fatalf("VM was not reset: %v frames, %v values", len(vm.frames), len(vm.stack))
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	_, prog := load(t, `func double(n int) int { n * 2 }`)
+
+	var buf strings.Builder
+	if err := prog.Disassemble(&buf); err != nil {
+		t.Fatal(err)
+	}
+	const want = `0: init -> () unit (0 slots)
+  0000  unit
+  0001  return
+
+1: double -> (int) int (1 slots)
+  (1:26)
+  0000  load   0
+  0001  const  0  ; 2
+  0002  mulint
+  0003  return
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("unexpected listing:\n%v", buf.String())
+	}
+}
+
+const benchFib = `func fib(n int) int {
+	if n < 2 { n } else { fib(n - 1) + fib(n - 2) }
+}
+func main() int { fib(15) }`
+
+// benchIndirect is benchFib with the recursive calls made through a
+// variable of a function type.
+const benchIndirect = `let next -> (int) int = fib
+func fib(n int) int {
+	if n < 2 { n } else { next(n - 1) + next(n - 2) }
+}
+func main() int { fib(15) }`
+
+const benchLoop = `func main() int {
+	let a array[int]
+	let i int = 0
+	for i < 1000 {
+		a.append(i * i)
+		i += 1
+	}
+
+	let sum int = 0
+	i = 0
+	for i < a.len() {
+		if a[i] % 3 == 0 { sum += a[i] }
+		i += 1
+	}
+	sum
+}`
+
+func BenchmarkFib(b *testing.B) {
+	benchmark(b, benchFib)
+}
+
+func BenchmarkIndirect(b *testing.B) {
+	benchmark(b, benchIndirect)
+}
+
+func BenchmarkLoop(b *testing.B) {
+	benchmark(b, benchLoop)
+}
+
+// benchmark compares the VM to the tree-walking interpreter by calling
+// the main function of src with each of them.
+func benchmark(b *testing.B, src string) {
+	script, prog := load(b, src)
+
+	b.Run("Tree", func(b *testing.B) {
+		state := stele.NewState()
+		if err := script.Run(state); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := script.Call(state, "main"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("VM", func(b *testing.B) {
+		vm := New(prog)
+		if err := vm.Run(); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := vm.Call("main"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
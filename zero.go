@@ -0,0 +1,190 @@
+package stele
+
+import (
+	"math/big"
+	"slices"
+)
+
+// Zero returns the zero value of t, with its type interned in ds. This
+// is the value that a variable of type t has if it is declared without
+// one. If t does not have a zero value, as reported by HasZero, the
+// returned Value is not valid.
+func (ds *Descs) Zero(t Type) Value {
+	return ds.zero(t, nil)
+}
+
+// zero is Zero. visiting is the names of the struct types whose zero
+// values contain that of t.
+func (ds *Descs) zero(t Type, visiting []string) Value {
+	if t.Param || (len(t.Members()) > 0) {
+		return Value{}
+	}
+
+	features := t.FeatureSet()
+	if len(features) == 0 {
+		// A type without any requirements, such as any, can hold
+		// anything, so its zero value is the simplest one there is.
+		return UnitValue
+	}
+
+	for _, f := range features {
+		if f.Type != MemLayoutFeature {
+			continue
+		}
+
+		d := ds.Intern(t)
+		switch f.Name {
+		case "unit":
+			return Value{desc: d, kind: UnitKind}
+		case "bool":
+			return MakeBool(d, false)
+		case "int":
+			return MakeInt(d, 0)
+		case "uint":
+			return MakeUint(d, 0)
+		case "byte":
+			return MakeByte(d, 0)
+		case "float":
+			return MakeFloat(d, 0)
+		case "bigint":
+			return ValueOf(d, new(big.Int))
+		case "bigfloat":
+			return ValueOf(d, new(big.Float))
+		case "string":
+			return ValueOf(d, "")
+		case "array":
+			return ValueOf(d, new(Slice))
+		case "func":
+			return ValueOf(d, Function(zeroFunc{ret: f.Return}))
+		case "tuple":
+			elems := make([]Value, 0, len(f.Args))
+			for _, e := range f.Args {
+				z := ds.zero(e, visiting)
+				if !z.Valid() {
+					return Value{}
+				}
+				elems = append(elems, z)
+			}
+			return ValueOf(d, elems)
+		}
+	}
+
+	// Without a memory layout, a type with fields is a struct.
+	name := t.String()
+	if slices.Contains(visiting, name) {
+		return Value{}
+	}
+	visiting = append(visiting, name)
+
+	fields := make(map[string]Value)
+	for _, f := range features {
+		if f.Type != LetFeature {
+			continue
+		}
+		z := ds.zero(f.Return, visiting)
+		if !z.Valid() {
+			return Value{}
+		}
+		fields[f.Name] = z
+	}
+	if len(fields) > 0 {
+		return ValueOf(ds.Intern(t), NewStruct(fields))
+	}
+	return Value{}
+}
+
+// HasZero returns true if t has a zero value, which a variable of type
+// t must have to be declared without one. Types that only have
+// methods, oneof types, type parameters and struct types that contain
+// themselves do not, although a struct type may contain itself through an array or
+// the result of a function, as the zero values of those do not need it
+// until they are used.
+func HasZero(t Type) bool {
+	return hasZero(t, make(map[string]bool))
+}
+
+// hasZero is HasZero. visiting is the names of the struct types that
+// contain t, which t can not contain in turn. Instantiations of a
+// generic type are all the same type as far as visiting is concerned,
+// as one that contains another always contains yet another one.
+func hasZero(t Type, visiting map[string]bool) bool {
+	// The zero value of a type parameter would depend on what it is
+	// instantiated with, which is not known until run time.
+	if t.Param || (len(t.Members()) > 0) {
+		return false
+	}
+
+	name := t.String()
+	if t.Origin != nil {
+		// All that matters about a type argument is whether it has a
+		// zero value, so it is replaced by a type that does or does
+		// not. That is worked out first, as an argument may be an
+		// instantiation of the same type.
+		args := make([]Type, 0, len(t.Args))
+		for _, a := range t.Args {
+			if hasZero(a, visiting) {
+				args = append(args, Unit)
+				continue
+			}
+			args = append(args, Error)
+		}
+		t.Args, name = args, t.Origin.Name
+	}
+
+	features := t.FeatureSet()
+	if len(features) == 0 {
+		return true
+	}
+
+	for _, f := range features {
+		if f.Type != MemLayoutFeature {
+			continue
+		}
+
+		switch f.Name {
+		case "unit", "bool", "int", "uint", "byte", "float", "bigint", "bigfloat", "string", "array":
+			return true
+		case "func":
+			return visiting[f.Return.String()] || hasZero(f.Return, visiting)
+		case "tuple":
+			for _, e := range f.Args {
+				if !hasZero(e, visiting) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	if visiting[name] {
+		return false
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	var fields bool
+	for _, f := range features {
+		if f.Type != LetFeature {
+			continue
+		}
+		if !hasZero(f.Return, visiting) {
+			return false
+		}
+		fields = true
+	}
+	return fields
+}
+
+// Zero is the zero value of a type, such as that of a variable that is
+// declared without a value.
+type Zero struct {
+	T Type
+}
+
+func (z Zero) Type() Type {
+	return z.T
+}
+
+func (z Zero) Eval(state *State) Value {
+	return state.Descs.Zero(z.T)
+}
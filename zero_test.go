@@ -0,0 +1,38 @@
+package stele
+
+import "testing"
+
+func TestZeroRecursive(t *testing.T) {
+	// node's next field has node's own type, so that the features of
+	// the one are the features of the other.
+	node := Type{Name: "node", Features: []Feature{
+		{Type: LetFeature, Name: "v", Return: Int},
+		{Type: LetFeature, Name: "next"},
+	}}
+	node.Features[1].Return = node
+
+	tree := Type{Name: "tree", Features: []Feature{
+		{Type: LetFeature, Name: "v", Return: Int},
+		{Type: LetFeature, Name: "children"},
+	}}
+	tree.Features[1].Return, _ = Array.Instantiate(tree)
+
+	if HasZero(node) {
+		t.Fatal("type that contains itself has a zero value")
+	}
+	if z := NewDescs().Zero(node); z.Valid() {
+		t.Fatalf("unexpected zero value: %#v", z)
+	}
+
+	if !HasZero(tree) {
+		t.Fatal("type that contains an array of itself has no zero value")
+	}
+	z := NewDescs().Zero(tree)
+	s, ok := z.Interface().(*Struct)
+	if !ok {
+		t.Fatalf("unexpected zero value: %#v", z)
+	}
+	if v, ok := s.Field("v"); !ok || (v.Int() != 0) {
+		t.Fatalf("unexpected value of field v: %#v", v)
+	}
+}